@@ -3,6 +3,6 @@ module github.com/Rha02/block-beard
 go 1.17
 
 require (
-	github.com/btcsuite/btcutil v1.0.2 // indirect
-	golang.org/x/crypto v0.6.0 // indirect
+	github.com/btcsuite/btcutil v1.0.2
+	golang.org/x/crypto v0.6.0
 )
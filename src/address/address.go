@@ -0,0 +1,117 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/btcsuite/btcutil/base58"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// HashLength is the length of the public key hash carried by an address.
+	HashLength = ripemd160.Size
+	// ChecksumLength is the length of the checksum appended to an address.
+	ChecksumLength = 4
+)
+
+var (
+	ErrInvalidFormat = errors.New("address: invalid base58 encoding")
+	ErrInvalidLength = errors.New("address: invalid length")
+	ErrChecksum      = errors.New("address: checksum mismatch")
+	ErrVersion       = errors.New("address: unexpected version byte")
+)
+
+// Network is a struct for the version bytes used by the addresses of a network.
+// PubKeyHash addresses belong to a single key and ScriptHash addresses to a multisig policy.
+type Network struct {
+	Name       string
+	PubKeyHash byte
+	ScriptHash byte
+}
+
+var (
+	MainNet = &Network{Name: "mainnet", PubKeyHash: 0x00, ScriptHash: 0x05}
+	TestNet = &Network{Name: "testnet", PubKeyHash: 0x6f, ScriptHash: 0xc4}
+)
+
+// ParseNetwork() returns the network with the given name.
+func ParseNetwork(name string) (*Network, error) {
+	for _, n := range []*Network{MainNet, TestNet} {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("address: unknown network %q", name)
+}
+
+// Hash160() returns the RIPEMD160 hash of the SHA256 hash of the data.
+func Hash160(data []byte) []byte {
+	digest := sha256.Sum256(data)
+
+	h := ripemd160.New()
+	h.Write(digest[:])
+	return h.Sum(nil)
+}
+
+// Checksum() returns the first four bytes of the double SHA256 hash of the data.
+func Checksum(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:ChecksumLength]
+}
+
+// Encode() returns the Base58Check encoding of a version byte and a hash.
+func Encode(version byte, hash []byte) string {
+	payload := append([]byte{version}, hash...)
+	return base58.Encode(append(payload, Checksum(payload)...))
+}
+
+// Decode() takes a Base58Check address and returns its version byte and hash.
+func Decode(addr string) (byte, []byte, error) {
+	decoded := base58.Decode(addr)
+	if len(decoded) == 0 {
+		return 0, nil, ErrInvalidFormat
+	}
+	if len(decoded) != 1+HashLength+ChecksumLength {
+		return 0, nil, ErrInvalidLength
+	}
+
+	payload := decoded[:len(decoded)-ChecksumLength]
+	if !bytes.Equal(Checksum(payload), decoded[len(decoded)-ChecksumLength:]) {
+		return 0, nil, ErrChecksum
+	}
+
+	return payload[0], payload[1:], nil
+}
+
+// Validate() returns an error if the address is not a well-formed address of the network.
+func Validate(addr string, network *Network) error {
+	version, _, err := Decode(addr)
+	if err != nil {
+		return err
+	}
+	if version != network.PubKeyHash && version != network.ScriptHash {
+		return ErrVersion
+	}
+	return nil
+}
+
+// IsScriptHash() reports whether the address is a script hash address of the network.
+func IsScriptHash(addr string, network *Network) bool {
+	version, _, err := Decode(addr)
+	return err == nil && version == network.ScriptHash
+}
+
+// FromScript() derives the script hash address of a script, such as an encoded multisig policy, on the network.
+func FromScript(script []byte, network *Network) string {
+	return Encode(network.ScriptHash, Hash160(script))
+}
+
+// FromPublicKey() derives the address of a public key on the network from its raw key material.
+func FromPublicKey(publicKey keys.Verifier, network *Network) string {
+	return Encode(network.PubKeyHash, Hash160(publicKey.Raw()))
+}
@@ -0,0 +1,116 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// The public key hash of the address derivation example of the Bitcoin wiki, and its addresses.
+const exampleHash = "010966776006953d5567439e5e39f86a0d273bee"
+
+var exampleAddresses = []struct {
+	network *Network
+	script  bool
+	address string
+}{
+	{MainNet, false, "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM"},
+	{MainNet, true, "31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG"},
+	{TestNet, false, "mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j"},
+	{TestNet, true, "2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb"},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	hash, _ := hex.DecodeString(exampleHash)
+	for _, tt := range exampleAddresses {
+		version := tt.network.PubKeyHash
+		if tt.script {
+			version = tt.network.ScriptHash
+		}
+		if got := Encode(version, hash); got != tt.address {
+			t.Errorf("Encode(%#x, %s) = %s, want %s", version, exampleHash, got, tt.address)
+		}
+
+		gotVersion, gotHash, err := Decode(tt.address)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", tt.address, err)
+		}
+		if gotVersion != version || !bytes.Equal(gotHash, hash) {
+			t.Errorf("Decode(%s) = %#x, %x, want %#x, %s", tt.address, gotVersion, gotHash, version, exampleHash)
+		}
+		if IsScriptHash(tt.address, tt.network) != tt.script {
+			t.Errorf("IsScriptHash(%s) = %v", tt.address, !tt.script)
+		}
+	}
+
+	// leading zero bytes are kept through the encoding
+	zero := make([]byte, HashLength)
+	if _, got, err := Decode(Encode(MainNet.PubKeyHash, zero)); err != nil || !bytes.Equal(got, zero) {
+		t.Errorf("Decode() of the zero hash = %x, %v", got, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	hash, _ := hex.DecodeString(exampleHash)
+	valid := exampleAddresses[0].address
+	// the last character is part of the checksum, so changing it breaks the checksum alone
+	last := valid[len(valid)-1]
+	mistyped := valid[:len(valid)-1] + string(last+1)
+
+	for _, tt := range []struct {
+		name, address string
+		want          error
+	}{
+		{"empty", "", ErrInvalidFormat},
+		{"a character outside the alphabet", "0" + valid[1:], ErrInvalidFormat},
+		{"an ambiguous character", valid[:5] + "l" + valid[6:], ErrInvalidFormat},
+		{"a mistyped checksum", mistyped, ErrChecksum},
+		{"a short hash", Encode(MainNet.PubKeyHash, hash[:19]), ErrInvalidLength},
+		{"a long hash", Encode(MainNet.PubKeyHash, append(hash, 0)), ErrInvalidLength},
+		{"a missing checksum byte", Encode(MainNet.PubKeyHash, hash)[:len(valid)-1], ErrInvalidLength},
+	} {
+		if _, _, err := Decode(tt.address); !errors.Is(err, tt.want) {
+			t.Errorf("Decode() of %s %q = %v, want %v", tt.name, tt.address, err, tt.want)
+		}
+	}
+
+	swapped := []byte(valid)
+	swapped[5], swapped[6] = swapped[6], swapped[5]
+	if _, _, err := Decode(string(swapped)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Decode() of an address with swapped characters = %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range exampleAddresses {
+		for _, network := range []*Network{MainNet, TestNet} {
+			err := Validate(tt.address, network)
+			if network == tt.network && err != nil {
+				t.Errorf("Validate(%s, %s) = %v", tt.address, network.Name, err)
+			}
+			if network != tt.network && !errors.Is(err, ErrVersion) {
+				t.Errorf("Validate(%s, %s) = %v, want %v", tt.address, network.Name, err, ErrVersion)
+			}
+		}
+	}
+
+	hash, _ := hex.DecodeString(exampleHash)
+	if err := Validate(Encode(0x42, hash), MainNet); !errors.Is(err, ErrVersion) {
+		t.Errorf("Validate() of an unknown version byte = %v, want %v", err, ErrVersion)
+	}
+	if err := Validate("", MainNet); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Validate() of the empty address = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+	for _, n := range []*Network{MainNet, TestNet} {
+		if got, err := ParseNetwork(n.Name); err != nil || got != n {
+			t.Errorf("ParseNetwork(%q) = %v, %v", n.Name, got, err)
+		}
+	}
+	if _, err := ParseNetwork("regtest"); err == nil {
+		t.Error("ParseNetwork() of an unknown network succeeded")
+	}
+}
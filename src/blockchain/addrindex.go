@@ -0,0 +1,300 @@
+package blockchain
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	HISTORY_PAGE_DEFAULT_LIMIT = 20
+	HISTORY_PAGE_MAX_LIMIT     = 100
+)
+
+// addressEntry is what the address index knows about one address.
+type addressEntry struct {
+	received float32
+	sent     float32
+	// nonce is the number of transactions the address has sent.
+	nonce uint64
+	// history holds the transactions involving the address in chain order.
+	history []TxLocation
+	// credits holds the blocks crediting the address in chain order.
+	credits []credit
+}
+
+// credit is the total an address had received by the end of the block at the height.
+type credit struct {
+	height   int
+	received float32
+}
+
+// AddressIndex keeps the totals and the confirmed transactions of every address.
+// It is updated as blocks connect and disconnect, so queries never scan the chain.
+type AddressIndex struct {
+	mux     sync.RWMutex
+	entries map[string]*addressEntry
+}
+
+// AddressSummary is the confirmed activity of an address.
+type AddressSummary struct {
+	Address          string  `json:"address"`
+	Balance          float32 `json:"balance"`
+	Received         float32 `json:"received"`
+	Sent             float32 `json:"sent"`
+	TransactionCount int     `json:"transaction_count"`
+	// Nonce is the number of transactions the address has sent, which its next one carries
+	Nonce uint64 `json:"nonce"`
+	// Tokens holds the confirmed balances of the address in the tokens it holds, by symbol
+	Tokens map[string]float32 `json:"tokens,omitempty"`
+}
+
+// AddressHistory is a page of the transactions of an address, newest first;
+// NextCursor is empty on the last page.
+type AddressHistory struct {
+	Address      string             `json:"address"`
+	Transactions []*TransactionView `json:"transactions"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
+}
+
+func NewAddressIndex() *AddressIndex {
+	return &AddressIndex{entries: make(map[string]*addressEntry)}
+}
+
+func (idx *AddressIndex) entry(bcAddress string) *addressEntry {
+	e, ok := idx.entries[bcAddress]
+	if !ok {
+		e = &addressEntry{}
+		idx.entries[bcAddress] = e
+	}
+	return e
+}
+
+// connect() adds the transactions of the block at the height.
+func (idx *AddressIndex) connect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	for i, t := range block.transactions {
+		loc := TxLocation{height, i}
+		sender := idx.entry(t.senderAddress)
+		sender.sent += t.Cost()
+		sender.history = append(sender.history, loc)
+		if t.senderAddress != MINING_SENDER {
+			sender.nonce++
+		}
+
+		recipient := idx.entry(t.recipientAddress)
+		recipient.received += t.Value()
+		if t.recipientAddress != t.senderAddress {
+			recipient.history = append(recipient.history, loc)
+		}
+		if t.Value() > 0 {
+			recipient.credits = addCredit(recipient.credits, height, recipient.received)
+		}
+	}
+}
+
+// disconnect() removes the transactions of the block at the height, which must be the last one connected.
+func (idx *AddressIndex) disconnect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	for i := len(block.transactions) - 1; i >= 0; i-- {
+		t := block.transactions[i]
+		sender := idx.entry(t.senderAddress)
+		sender.sent -= t.Cost()
+		sender.history = dropHeight(sender.history, height)
+		if t.senderAddress != MINING_SENDER {
+			sender.nonce--
+		}
+
+		recipient := idx.entry(t.recipientAddress)
+		recipient.received -= t.Value()
+		recipient.history = dropHeight(recipient.history, height)
+		if n := len(recipient.credits); n > 0 && recipient.credits[n-1].height == height {
+			recipient.credits = recipient.credits[:n-1]
+		}
+	}
+
+	for _, t := range block.transactions {
+		for _, a := range []string{t.senderAddress, t.recipientAddress} {
+			if e := idx.entries[a]; e != nil && len(e.history) == 0 {
+				delete(idx.entries, a)
+			}
+		}
+	}
+}
+
+// dropHeight() removes the trailing locations at the height.
+func dropHeight(history []TxLocation, height int) []TxLocation {
+	for len(history) > 0 && history[len(history)-1].Height == height {
+		history = history[:len(history)-1]
+	}
+	return history
+}
+
+// rebuild() indexes the chain from scratch.
+func (idx *AddressIndex) rebuild(chain []*Block) {
+	idx.mux.Lock()
+	idx.entries = make(map[string]*addressEntry)
+	idx.mux.Unlock()
+	for height, block := range chain {
+		idx.connect(block, height)
+	}
+}
+
+// ledger counts what addresses receive and send as transactions are applied in chain order, the
+// way the address index does, so it agrees with the index on what each address can spend. An
+// address starts from the totals returned by start, or from nothing if start is nil.
+type ledger struct {
+	start    func(bcAddress string) (received, sent float32)
+	received map[string]float32
+	sent     map[string]float32
+}
+
+func newLedger(start func(bcAddress string) (received, sent float32)) *ledger {
+	return &ledger{start, make(map[string]float32), make(map[string]float32)}
+}
+
+func (l *ledger) load(bcAddress string) {
+	if _, ok := l.received[bcAddress]; ok {
+		return
+	}
+	l.received[bcAddress], l.sent[bcAddress] = 0, 0
+	if l.start != nil {
+		l.received[bcAddress], l.sent[bcAddress] = l.start(bcAddress)
+	}
+}
+
+// apply() applies the transaction unless its sender cannot afford it, and returns whether it was
+// applied. Mining rewards create their coins, so they are always applied.
+func (l *ledger) apply(t *Transaction) bool {
+	l.load(t.senderAddress)
+	l.load(t.recipientAddress)
+	if t.senderAddress != MINING_SENDER && l.received[t.senderAddress]-l.sent[t.senderAddress] < t.Cost() {
+		return false
+	}
+	l.sent[t.senderAddress] += t.Cost()
+	l.received[t.recipientAddress] += t.Value()
+	return true
+}
+
+// Summary() returns the totals of the address.
+func (idx *AddressIndex) Summary(bcAddress string) *AddressSummary {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	s := &AddressSummary{Address: bcAddress}
+	if e, ok := idx.entries[bcAddress]; ok {
+		s.Balance = e.received - e.sent
+		s.Received = e.received
+		s.Sent = e.sent
+		s.TransactionCount = len(e.history)
+		s.Nonce = e.nonce
+	}
+	return s
+}
+
+// addCredit() records that the address had received the total by the end of the block at the height.
+func addCredit(credits []credit, height int, received float32) []credit {
+	if n := len(credits); n > 0 && credits[n-1].height == height {
+		credits[n-1].received = received
+		return credits
+	}
+	return append(credits, credit{height, received})
+}
+
+// spentAge() returns the number of blocks between the height and the block crediting the funds
+// that bring what the address has sent to the total. Funds are spent in the order they were
+// received, so whatever is sent to an address later does not make the funds it already holds any
+// younger. Funds the credits do not cover are as old as the last credit, and an address without
+// credits has no age.
+func spentAge(credits []credit, sent float32, height int) int {
+	if len(credits) == 0 {
+		return 0
+	}
+	i := sort.Search(len(credits), func(i int) bool { return credits[i].received >= sent })
+	if i == len(credits) {
+		i--
+	}
+	return height - credits[i].height
+}
+
+// age() returns the age at the height of the funds the address spends once it has sent pending
+// more than it has confirmed.
+func (idx *AddressIndex) age(bcAddress string, pending float32, height int) int {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	e, ok := idx.entries[bcAddress]
+	if !ok {
+		return 0
+	}
+	return spentAge(e.credits, e.sent+pending, height)
+}
+
+// locations() returns at most limit locations of the address, newest first, starting at the cursor,
+// and the cursor of the next page.
+func (idx *AddressIndex) locations(bcAddress, cursor string, limit int) ([]TxLocation, string, error) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+
+	var history []TxLocation
+	if e, ok := idx.entries[bcAddress]; ok {
+		history = e.history
+	}
+
+	// the cursor is the number of older transactions left to list, so it stays valid as blocks are added
+	end := len(history)
+	if cursor != "" {
+		var err error
+		end, err = strconv.Atoi(cursor)
+		if err != nil || end < 0 || end > len(history) {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	var res []TxLocation
+	i := end - 1
+	for ; i >= 0 && len(res) < limit; i-- {
+		res = append(res, history[i])
+	}
+	next := ""
+	if i >= 0 {
+		next = strconv.Itoa(i + 1)
+	}
+	return res, next, nil
+}
+
+// AddressSummary() returns the confirmed totals of the address and its token balances.
+func (bc *Blockchain) AddressSummary(bcAddress string) *AddressSummary {
+	s := bc.addrIndex.Summary(bcAddress)
+	if holdings := bc.tokens.Holdings(bcAddress); len(holdings) > 0 {
+		s.Tokens = holdings
+	}
+	return s
+}
+
+// AddressHistory() returns a page of at most limit confirmed transactions of the address, newest first.
+func (bc *Blockchain) AddressHistory(bcAddress, cursor string, limit int) (*AddressHistory, error) {
+	if limit <= 0 {
+		limit = HISTORY_PAGE_DEFAULT_LIMIT
+	}
+	if limit > HISTORY_PAGE_MAX_LIMIT {
+		limit = HISTORY_PAGE_MAX_LIMIT
+	}
+
+	chain := bc.chain
+	tipHeight := len(chain) - 1
+	locations, next, err := bc.addrIndex.locations(bcAddress, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AddressHistory{Address: bcAddress, Transactions: []*TransactionView{}, NextCursor: next}
+	for _, loc := range locations {
+		if loc.Height > tipHeight {
+			continue
+		}
+		block := chain[loc.Height]
+		page.Transactions = append(page.Transactions, confirmedTransactionView(block.transactions[loc.Position], block, loc.Height, tipHeight))
+	}
+	return page, nil
+}
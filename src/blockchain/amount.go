@@ -2,14 +2,110 @@ package blockchain
 
 import "encoding/json"
 
+// DEFAULT_MIN_CONFIRMATIONS is the depth at which funds count as confirmed when none is given.
+const DEFAULT_MIN_CONFIRMATIONS = 1
+
+// AmountResponse is the balance of an address for a minimum number of confirmations.
+// Amount is the confirmed balance, kept for clients that only read it. Nonce is the nonce the next
+// transaction of the address must carry, counting its pooled transactions.
 type AmountResponse struct {
-	Amount float32 `json:"amount"`
+	Amount              float32
+	Confirmed           float32
+	UnconfirmedIncoming float32
+	UnconfirmedOutgoing float32
+	Spendable           float32
+	MinConfirmations    int
+	Nonce               uint64
 }
 
 func (ar *AmountResponse) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Amount float32 `json:"amount"`
+		Amount              float32 `json:"amount"`
+		Confirmed           float32 `json:"confirmed"`
+		UnconfirmedIncoming float32 `json:"unconfirmed_incoming"`
+		UnconfirmedOutgoing float32 `json:"unconfirmed_outgoing"`
+		Spendable           float32 `json:"spendable"`
+		MinConfirmations    int     `json:"min_confirmations"`
+		Nonce               uint64  `json:"nonce"`
 	}{
-		Amount: ar.Amount,
+		Amount:              ar.Amount,
+		Confirmed:           ar.Confirmed,
+		UnconfirmedIncoming: ar.UnconfirmedIncoming,
+		UnconfirmedOutgoing: ar.UnconfirmedOutgoing,
+		Spendable:           ar.Spendable,
+		MinConfirmations:    ar.MinConfirmations,
+		Nonce:               ar.Nonce,
 	})
 }
+
+func (ar *AmountResponse) UnmarshalJSON(data []byte) error {
+	tmp := &struct {
+		Amount              *float32 `json:"amount"`
+		Confirmed           *float32 `json:"confirmed"`
+		UnconfirmedIncoming *float32 `json:"unconfirmed_incoming"`
+		UnconfirmedOutgoing *float32 `json:"unconfirmed_outgoing"`
+		Spendable           *float32 `json:"spendable"`
+		MinConfirmations    *int     `json:"min_confirmations"`
+		Nonce               *uint64  `json:"nonce"`
+	}{
+		Amount:              &ar.Amount,
+		Confirmed:           &ar.Confirmed,
+		UnconfirmedIncoming: &ar.UnconfirmedIncoming,
+		UnconfirmedOutgoing: &ar.UnconfirmedOutgoing,
+		Spendable:           &ar.Spendable,
+		MinConfirmations:    &ar.MinConfirmations,
+		Nonce:               &ar.Nonce,
+	}
+	return json.Unmarshal(data, tmp)
+}
+
+// Balance() returns the balance of the address. Transactions with fewer than minConfirmations
+// confirmations, and those still in the pool, count as unconfirmed incoming or outgoing funds.
+// Pending spends are taken off the spendable amount right away, while incoming funds only become
+// spendable once confirmed.
+func (bc *Blockchain) Balance(bcAddress string, minConfirmations int) *AmountResponse {
+	if minConfirmations < 0 {
+		minConfirmations = 0
+	}
+	chain := bc.chain
+	summary := bc.addrIndex.Summary(bcAddress)
+
+	var incoming, outgoing float32
+	add := func(t *Transaction) {
+		if t.recipientAddress == bcAddress {
+			incoming += t.Value()
+		}
+		if t.senderAddress == bcAddress {
+			outgoing += t.Cost()
+		}
+	}
+
+	// a block at height h has len(chain) - h confirmations
+	shallowest := len(chain) - minConfirmations + 1
+	if shallowest < 0 {
+		shallowest = 0
+	}
+	var shallowIncoming, shallowOutgoing float32
+	if summary.TransactionCount > 0 {
+		for height := shallowest; height < len(chain); height++ {
+			for _, t := range chain[height].transactions {
+				add(t)
+			}
+		}
+		shallowIncoming, shallowOutgoing = incoming, outgoing
+	}
+	for _, t := range bc.pool {
+		add(t)
+	}
+
+	confirmed := (summary.Received - shallowIncoming) - (summary.Sent - shallowOutgoing)
+	return &AmountResponse{
+		Amount:              confirmed,
+		Confirmed:           confirmed,
+		UnconfirmedIncoming: incoming,
+		UnconfirmedOutgoing: outgoing,
+		Spendable:           confirmed - outgoing,
+		MinConfirmations:    minConfirmations,
+		Nonce:               bc.NextNonce(bcAddress),
+	}
+}
@@ -0,0 +1,198 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+const (
+	// POA_TURN_TIMEOUT_SEC is how long the validators wait for each validator before them in turn
+	// before producing a block themselves.
+	POA_TURN_TIMEOUT_SEC = 2 * MINING_TIME_SEC
+	// POA_MAX_FUTURE_SEC is how far ahead of the local clock a block may be timestamped.
+	POA_MAX_FUTURE_SEC = 5
+)
+
+var (
+	ErrNotValidator = errors.New("the node has no validator key")
+	ErrNotInTurn    = errors.New("another validator produces the next block")
+	ErrInvalidSeal  = errors.New("block is not signed by a validator")
+	ErrEarlySeal    = errors.New("block is signed out of turn before its validator's turn timed out")
+)
+
+// ProofOfAuthority is an engine for private networks: a fixed set of validators take turns to
+// produce blocks, each signing the blocks at its heights in the configured order. A validator that
+// is offline, or has nothing to include, is skipped: once POA_TURN_TIMEOUT_SEC has passed since the
+// parent block, the next validator may produce the block, then the one after it, and so on. Every
+// node of the network must configure the same validators in the same order.
+type ProofOfAuthority struct {
+	validators []keys.Verifier
+	// signer is the key of this node's validator, or nil if the node only follows the chain
+	signer keys.Signer
+}
+
+// NewProofOfAuthority() returns a pointer to an engine over the validators, producing blocks with
+// the signer if it is not nil, which must then be the key of one of them.
+func NewProofOfAuthority(validators []keys.Verifier, signer keys.Signer) (*ProofOfAuthority, error) {
+	if len(validators) == 0 {
+		return nil, errors.New("proof of authority needs at least one validator")
+	}
+	for i, v := range validators {
+		for _, other := range validators[:i] {
+			if sameKey(v, other) {
+				return nil, fmt.Errorf("duplicate validator %s", keys.ToString(v))
+			}
+		}
+	}
+	poa := &ProofOfAuthority{validators, signer}
+	if signer != nil && poa.index(signer.Public()) < 0 {
+		return nil, errors.New("the signing key is not one of the validators")
+	}
+	return poa, nil
+}
+
+// ParseValidators() parses a comma separated list of [scheme:]hex public keys of validators.
+func ParseValidators(list string) ([]keys.Verifier, error) {
+	var res []keys.Verifier
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		schemeName, key := "", item
+		if i := strings.IndexByte(item, ':'); i >= 0 {
+			schemeName, key = item[:i], item[i+1:]
+		}
+		scheme, err := keys.ParseScheme(schemeName)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := keys.PublicKeyFromString(scheme, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator key %s: %v", key, err)
+		}
+		res = append(res, publicKey)
+	}
+	return res, nil
+}
+
+func sameKey(a, b keys.Verifier) bool {
+	return a.Scheme() == b.Scheme() && bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+// index() returns the position of the key among the validators, or -1.
+func (poa *ProofOfAuthority) index(key keys.Verifier) int {
+	for i, v := range poa.validators {
+		if sameKey(v, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validator() returns the key of the validator in turn for the block at the height. The genesis
+// block is not signed, so the first validator is in turn for the block at height 1.
+func (poa *ProofOfAuthority) Validator(height int) keys.Verifier {
+	return poa.validators[(height-1)%len(poa.validators)]
+}
+
+// Validators() returns the keys of the validators in the order they produce blocks, as
+// ParseValidators() reads them.
+func (poa *ProofOfAuthority) Validators() []string {
+	res := make([]string, len(poa.validators))
+	for i, v := range poa.validators {
+		res[i] = string(v.Scheme()) + ":" + keys.ToString(v)
+	}
+	return res
+}
+
+// earliest() returns the earliest time, in unix nanoseconds, the validator at the position may
+// produce the next block of the chain: at once if it is in turn, and POA_TURN_TIMEOUT_SEC later
+// for every validator in turn before it.
+func (poa *ProofOfAuthority) earliest(chain []*Block, position int) int64 {
+	n := len(poa.validators)
+	behind := (position - (len(chain)-1)%n + n) % n
+	return chain[len(chain)-1].timestamp + int64(behind)*POA_TURN_TIMEOUT_SEC*int64(time.Second)
+}
+
+func (poa *ProofOfAuthority) Name() string {
+	return "poa"
+}
+
+func (poa *ProofOfAuthority) Difficulty() int {
+	return 0
+}
+
+// Prepare() returns an error unless this node's validator is in turn for the next block, or the
+// validators before it have let their turn time out.
+func (poa *ProofOfAuthority) Prepare(chain []*Block) error {
+	return poa.prepare(chain, time.Now().UnixNano())
+}
+
+// prepare() returns an error unless this node's validator may produce the next block at the time.
+func (poa *ProofOfAuthority) prepare(chain []*Block, timestamp int64) error {
+	if poa.signer == nil {
+		return ErrNotValidator
+	}
+	if timestamp < poa.earliest(chain, poa.index(poa.signer.Public())) {
+		return ErrNotInTurn
+	}
+	return nil
+}
+
+// Seal() signs the block with the validator key.
+func (poa *ProofOfAuthority) Seal(chain []*Block, block *Block) error {
+	if err := poa.prepare(chain, block.timestamp); err != nil {
+		return err
+	}
+	block.nonce = 0
+	block.signature = nil
+	hash := block.SealHash()
+	signature, err := poa.signer.Sign(hash[:])
+	if err != nil {
+		return err
+	}
+	block.signature = signature
+	return nil
+}
+
+// VerifyHeader() checks that the block is newer than its parent, not from the future, and signed by
+// a validator whose turn it is by the timestamp of the block.
+func (poa *ProofOfAuthority) VerifyHeader(chain []*Block, block *Block) error {
+	if len(chain) == 0 {
+		return errors.New("the genesis block is not sealed")
+	}
+	if block.nonce != 0 {
+		return errors.New("proof of authority blocks have no nonce")
+	}
+	if block.timestamp <= chain[len(chain)-1].timestamp {
+		return errors.New("block is not newer than its parent")
+	}
+	if block.timestamp > time.Now().UnixNano()+POA_MAX_FUTURE_SEC*int64(time.Second) {
+		return errors.New("block is timestamped in the future")
+	}
+	if len(block.signature) == 0 {
+		return ErrInvalidSeal
+	}
+	hash := block.SealHash()
+	for position, v := range poa.validators {
+		if !v.Verify(hash[:], block.signature) {
+			continue
+		}
+		if block.timestamp < poa.earliest(chain, position) {
+			return ErrEarlySeal
+		}
+		return nil
+	}
+	return ErrInvalidSeal
+}
+
+// IsBetterChain() prefers the longer chain; validators extend a chain by one block each.
+func (poa *ProofOfAuthority) IsBetterChain(current, candidate []*Block) bool {
+	return len(candidate) > len(current)
+}
@@ -0,0 +1,207 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+func newValidators(t *testing.T, n int) ([]keys.Signer, []keys.Verifier) {
+	t.Helper()
+	signers := make([]keys.Signer, n)
+	validators := make([]keys.Verifier, n)
+	for i := range signers {
+		signers[i] = generateKey(t)
+		validators[i] = signers[i].Public()
+	}
+	return signers, validators
+}
+
+func newAuthority(t *testing.T, validators []keys.Verifier, signer keys.Signer) *ProofOfAuthority {
+	t.Helper()
+	poa, err := NewProofOfAuthority(validators, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return poa
+}
+
+func TestProofOfAuthorityTurns(t *testing.T) {
+	signers, validators := newValidators(t, 3)
+	parent := NewBlock(0, [32]byte{}, nil)
+	parent.timestamp = time.Now().Add(-time.Hour).UnixNano()
+	timeout := int64(POA_TURN_TIMEOUT_SEC * time.Second)
+
+	for _, tt := range []struct {
+		name   string
+		height int
+		signer int
+		after  int64
+		err    error
+	}{
+		{"in turn", 1, 0, 1, nil},
+		{"next validator before the timeout", 1, 1, timeout - 1, ErrEarlySeal},
+		{"next validator after the timeout", 1, 1, timeout, nil},
+		{"validator after next after one timeout", 1, 2, timeout, ErrEarlySeal},
+		{"validator after next after two timeouts", 1, 2, 2 * timeout, nil},
+		{"in turn at a later height", 5, 1, 1, nil},
+		{"previous validator at a later height", 5, 0, 1, ErrEarlySeal},
+		{"first validator after the last", 3, 0, timeout, nil},
+	} {
+		chain := make([]*Block, tt.height)
+		for i := range chain {
+			chain[i] = parent
+		}
+		block := NewBlock(0, parent.Hash(), nil)
+		block.timestamp = parent.timestamp + tt.after
+
+		err := newAuthority(t, validators, signers[tt.signer]).Seal(chain, block)
+		if tt.err == nil && err != nil {
+			t.Errorf("%s: Seal() = %v", tt.name, err)
+			continue
+		}
+		if tt.err != nil {
+			if !errors.Is(err, ErrNotInTurn) {
+				t.Errorf("%s: Seal() = %v, want %v", tt.name, err, ErrNotInTurn)
+			}
+			// a validator signing out of turn anyway is caught by the other nodes
+			hash := block.SealHash()
+			if block.signature, err = signers[tt.signer].Sign(hash[:]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := newAuthority(t, validators, nil).VerifyHeader(chain, block); !errors.Is(err, tt.err) {
+			t.Errorf("%s: VerifyHeader() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestProofOfAuthorityInvalidHeaders(t *testing.T) {
+	signers, validators := newValidators(t, 2)
+	poa := newAuthority(t, validators, signers[0])
+	parent := NewBlock(0, [32]byte{}, nil)
+	parent.timestamp = time.Now().Add(-time.Minute).UnixNano()
+	chain := []*Block{parent}
+
+	sealed := func(timestamp int64, signer keys.Signer) *Block {
+		block := NewBlock(0, parent.Hash(), nil)
+		block.timestamp = timestamp
+		hash := block.SealHash()
+		sig, err := signer.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.signature = sig
+		return block
+	}
+	unsigned := NewBlock(0, parent.Hash(), nil)
+
+	for name, block := range map[string]*Block{
+		"not newer than its parent": sealed(parent.timestamp, signers[0]),
+		"from the future":           sealed(time.Now().Add(time.Minute).UnixNano(), signers[0]),
+		"unsigned":                  unsigned,
+		"signed by an outsider":     sealed(parent.timestamp+1, generateKey(t)),
+	} {
+		if err := poa.VerifyHeader(chain, block); err == nil {
+			t.Errorf("block %s accepted", name)
+		}
+	}
+
+	if err := newAuthority(t, validators, nil).Prepare(chain); !errors.Is(err, ErrNotValidator) {
+		t.Errorf("Prepare() of a follower = %v, want %v", err, ErrNotValidator)
+	}
+}
+
+// TestProofOfAuthorityOfflineValidator runs the second of two validators while the first is
+// offline: it produces the first block out of turn, as the genesis block is long past, and its own
+// block in turn, then waits for the first validator's turn to time out with a payment pooled.
+func TestProofOfAuthorityOfflineValidator(t *testing.T) {
+	signers, validators := newValidators(t, 2)
+	bc := NewBlockchain(address.FromPublicKey(signers[1].Public(), address.TestNet), 0, address.TestNet)
+	bc.SetConsensus(newAuthority(t, validators, signers[1]))
+	recipient := address.FromPublicKey(generateKey(t).Public(), address.TestNet)
+
+	for height := 1; height <= 2; height++ {
+		mine(t, bc)
+	}
+	pay(t, bc, signers[1], recipient, 0.1)
+	if bc.Mine() {
+		t.Error("block produced before the offline validator's turn timed out")
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+}
+
+type fakePeer struct {
+	info *ChainInfo
+	err  error
+}
+
+func (p *fakePeer) Info(ctx context.Context) (*ChainInfo, error) {
+	return p.info, p.err
+}
+
+func (p *fakePeer) Chain(ctx context.Context) ([]*Block, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakePeer) RelayTransaction(ctx context.Context, tr *TransactionRequest) error {
+	return errors.New("not implemented")
+}
+
+func (p *fakePeer) ResolveConflicts(ctx context.Context) error {
+	return errors.New("not implemented")
+}
+
+func TestSameNetwork(t *testing.T) {
+	_, validators := newValidators(t, 3)
+	bc := NewBlockchain("", 0, address.TestNet)
+	bc.SetConsensus(newAuthority(t, validators, nil))
+
+	peer := func(change func(info *ChainInfo)) *fakePeer {
+		info := *bc.Info()
+		info.Validators = append([]string{}, info.Validators...)
+		change(&info)
+		return &fakePeer{info: &info}
+	}
+	peers := map[string]*fakePeer{
+		"same":             peer(func(info *ChainInfo) {}),
+		"other network":    peer(func(info *ChainInfo) { info.Network = address.MainNet.Name }),
+		"other consensus":  peer(func(info *ChainInfo) { info.Consensus = "pow"; info.Validators = nil }),
+		"other genesis":    peer(func(info *ChainInfo) { info.GenesisHash = "00" }),
+		"other validators": peer(func(info *ChainInfo) { info.Validators = info.Validators[:2] }),
+		"validators swapped": peer(func(info *ChainInfo) {
+			info.Validators[0], info.Validators[1] = info.Validators[1], info.Validators[0]
+		}),
+		"unreachable": {err: errors.New("connection refused")},
+	}
+	bc.SetPeerDialer(func(neighbor string) PeerClient {
+		return peers[neighbor]
+	})
+
+	var neighbors []string
+	for n := range peers {
+		neighbors = append(neighbors, n)
+	}
+	if got := bc.sameNetwork(neighbors); len(got) != 1 || got[0] != "same" {
+		t.Errorf("sameNetwork() = %v, want [same]", got)
+	}
+}
+
+func TestGenesis(t *testing.T) {
+	a, b := NewBlockchain("", 0, address.MainNet), NewBlockchain("", 0, address.MainNet)
+	if a.chain[0].Hash() != b.chain[0].Hash() {
+		t.Fatal("nodes start from different genesis blocks")
+	}
+
+	other := NewBlockchain("", 0, address.MainNet)
+	other.chain[0].timestamp = 1
+	if a.IsValidChain(other.chain) {
+		t.Error("chain from another genesis block accepted")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -15,6 +16,10 @@ type Block struct {
 	timestamp    int64
 	transactions []*Transaction
 	nonce        int
+	// stateRoot commits to the contract state after the block's transactions are applied
+	stateRoot [32]byte
+	// signature seals the block under an engine where validators sign blocks instead of mining them
+	signature []byte
 }
 
 // NewBlock() takes a nonce and a previous hash and returns a pointer to a new block.
@@ -43,6 +48,14 @@ func (b *Block) GetNonce() int {
 	return b.nonce
 }
 
+func (b *Block) GetStateRoot() [32]byte {
+	return b.stateRoot
+}
+
+func (b *Block) GetSignature() []byte {
+	return b.signature
+}
+
 // Hash() returns the hash of the block.
 func (b *Block) Hash() [32]byte {
 	// marshall the block to json
@@ -54,42 +67,82 @@ func (b *Block) Hash() [32]byte {
 	return sha256.Sum256(res)
 }
 
-// MarshalJSON() returns a json representation of the block.
+// SealHash() returns the hash a validator signs: the hash of the block without its signature.
+func (b *Block) SealHash() [32]byte {
+	unsigned := *b
+	unsigned.signature = nil
+	return unsigned.Hash()
+}
+
+// MarshalJSON() returns a json representation of the block. The state root and the signature are
+// left out while they are empty, so blocks of chains without contracts, or mined with proof of work,
+// hash as they did before either existed.
 func (b *Block) MarshalJSON() ([]byte, error) {
+	var stateRoot string
+	if b.stateRoot != [32]byte{} {
+		stateRoot = fmt.Sprintf("%x", b.stateRoot)
+	}
+
 	return json.Marshal(struct {
 		PrevHash     string         `json:"prevHash"`
 		Timestamp    int64          `json:"timestamp"`
 		Transactions []*Transaction `json:"transactions"`
 		Nonce        int            `json:"nonce"`
+		StateRoot    string         `json:"state_root,omitempty"`
+		Signature    string         `json:"signature,omitempty"`
 	}{
 		PrevHash:     fmt.Sprintf("%x", b.prevHash),
 		Timestamp:    b.timestamp,
 		Transactions: b.transactions,
 		Nonce:        b.nonce,
+		StateRoot:    stateRoot,
+		Signature:    hex.EncodeToString(b.signature),
 	})
 }
 
 // UnmarshalJSON() takes a json representation of a block and returns a pointer to a new block.
 func (b *Block) UnmarshalJSON(data []byte) error {
-	var prevHash string
+	var prevHash, stateRoot, signature string
 
 	tmp := &struct {
 		PrevHash     *string         `json:"prevHash"`
 		Timestamp    *int64          `json:"timestamp"`
 		Transactions *[]*Transaction `json:"transactions"`
 		Nonce        *int            `json:"nonce"`
+		StateRoot    *string         `json:"state_root"`
+		Signature    *string         `json:"signature"`
 	}{
 		PrevHash:     &prevHash,
 		Timestamp:    &b.timestamp,
 		Transactions: &b.transactions,
 		Nonce:        &b.nonce,
+		StateRoot:    &stateRoot,
+		Signature:    &signature,
 	}
 	if err := json.Unmarshal(data, tmp); err != nil {
 		return err
 	}
 
-	decodedPrevHash, _ := hex.DecodeString(prevHash)
-	copy(b.prevHash[:], decodedPrevHash[:32])
+	decodedPrevHash, err := hex.DecodeString(prevHash)
+	if err != nil || len(decodedPrevHash) != 32 {
+		return errors.New("invalid previous hash")
+	}
+	copy(b.prevHash[:], decodedPrevHash)
+
+	if stateRoot != "" {
+		decodedStateRoot, err := hex.DecodeString(stateRoot)
+		if err != nil || len(decodedStateRoot) != 32 {
+			return errors.New("invalid state root")
+		}
+		copy(b.stateRoot[:], decodedStateRoot)
+	}
+	if signature != "" {
+		sig, err := hex.DecodeString(signature)
+		if err != nil {
+			return errors.New("invalid block signature")
+		}
+		b.signature = sig
+	}
 
 	return nil
 }
@@ -0,0 +1,40 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBlockUnmarshalJSON(t *testing.T) {
+	block := NewBlock(7, [32]byte{1, 2, 3}, []*Transaction{NewTransaction(MINING_SENDER, "miner", MINING_REWARD)})
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Block
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if decoded.Hash() != block.Hash() {
+		t.Errorf("decoded block hash = %x, want %x", decoded.Hash(), block.Hash())
+	}
+
+	prevHash := strings.Repeat("ab", 32)
+	for _, tt := range []struct {
+		name string
+		json string
+	}{
+		{"missing previous hash", `{"timestamp": 1, "nonce": 0, "transactions": []}`},
+		{"short previous hash", `{"prevHash": "` + prevHash[:62] + `"}`},
+		{"long previous hash", `{"prevHash": "` + prevHash + `00"}`},
+		{"previous hash not hex", `{"prevHash": "` + strings.Repeat("zz", 32) + `"}`},
+		{"short state root", `{"prevHash": "` + prevHash + `", "state_root": "abcd"}`},
+		{"signature not hex", `{"prevHash": "` + prevHash + `", "signature": "zz"}`},
+	} {
+		var b Block
+		if err := json.Unmarshal([]byte(tt.json), &b); err == nil {
+			t.Errorf("%s: Unmarshal() = nil, want an error", tt.name)
+		}
+	}
+}
@@ -1,17 +1,18 @@
 package blockchain
 
 import (
-	"bytes"
-	"crypto/ecdsa"
-	"crypto/sha256"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/script"
 	"github.com/Rha02/block-beard/src/utils"
+	"github.com/Rha02/block-beard/src/vm"
 )
 
 const (
@@ -33,9 +34,20 @@ type Blockchain struct {
 	chain        []*Block
 	address      string
 	port         uint16
-	mux          sync.Mutex
+	network      *address.Network
+	mining       bool
+	muxMining    sync.Mutex
+	mux          sync.Mutex // guards the chain and the pool while they change
 	neighbors    []string
 	muxNeighbors sync.Mutex
+	dial         PeerDialer
+	events       *EventBus
+	store        *ChainStore
+	txIndex      *TxIndex
+	addrIndex    *AddressIndex
+	contracts    *ContractState
+	tokens       *TokenIndex
+	engine       ConsensusEngine
 }
 
 func (bc *Blockchain) Run() {
@@ -44,20 +56,48 @@ func (bc *Blockchain) Run() {
 }
 
 // NewBlockchain() returns a pointer to a new blockchain
-func NewBlockchain(bcAddress string, port uint16) *Blockchain {
+func NewBlockchain(bcAddress string, port uint16, network *address.Network) *Blockchain {
 	b := new(Block)
 	bc := new(Blockchain)
-	bc.AddBlock(0, b.Hash())
+	bc.events = NewEventBus()
+	bc.addrIndex = NewAddressIndex()
+	bc.contracts = NewContractState()
+	bc.tokens = NewTokenIndex()
+	bc.engine = NewProofOfWork(MiningDifficulty)
+	// the genesis block is not sealed, so adding it cannot fail
+	bc.addBlock(b.Hash())
 	bc.address = bcAddress
+	bc.port = port
+	bc.network = network
 	return bc
 }
 
+func (bc *Blockchain) GetNetwork() *address.Network {
+	return bc.network
+}
+
+// SetConsensus() replaces the proof of work engine the blockchain starts with. It must be called
+// before the blockchain loads a chain or receives blocks.
+func (bc *Blockchain) SetConsensus(engine ConsensusEngine) {
+	bc.engine = engine
+}
+
+// Consensus() returns the engine producing and validating the blocks.
+func (bc *Blockchain) Consensus() ConsensusEngine {
+	return bc.engine
+}
+
+// Events() returns the bus the blockchain publishes its changes to.
+func (bc *Blockchain) Events() *EventBus {
+	return bc.events
+}
+
 func (bc *Blockchain) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Chain   []*Block
-		Pool    []*Transaction
-		Address string
-		Port    uint16
+		Chain   []*Block       `json:"chain"`
+		Pool    []*Transaction `json:"pool"`
+		Address string         `json:"address"`
+		Port    uint16         `json:"port"`
 	}{
 		Chain:   bc.chain,
 		Pool:    bc.pool,
@@ -81,17 +121,20 @@ func (bc *Blockchain) UnmarshalJSON(data []byte) error {
 }
 
 func (bc *Blockchain) SetNeighbors() {
-	bc.neighbors = utils.FindNeighbors(
+	// scan without holding the lock, so readers are not blocked by dial timeouts
+	neighbors := utils.FindNeighbors(
 		utils.GetHost(), bc.port,
 		NEIGHBOR_IP_RANGE_START, NEIGHBOR_IP_RANGE_END,
 		BLOCKCHAIN_PORT_START, BLOCKCHAIN_PORT_END,
 	)
-	fmt.Printf("Neighbors: %v", bc.neighbors)
+	neighbors = bc.sameNetwork(neighbors)
+	bc.muxNeighbors.Lock()
+	bc.neighbors = neighbors
+	bc.muxNeighbors.Unlock()
+	fmt.Printf("Neighbors: %v", neighbors)
 }
 
 func (bc *Blockchain) SyncNeighbors() {
-	bc.muxNeighbors.Lock()
-	defer bc.muxNeighbors.Unlock()
 	bc.SetNeighbors()
 }
 
@@ -104,6 +147,13 @@ func (bc *Blockchain) StartSyncNeighbors() {
 	}()
 }
 
+// GetNeighbors() returns the addresses of the neighbors found by the last sync.
+func (bc *Blockchain) GetNeighbors() []string {
+	bc.muxNeighbors.Lock()
+	defer bc.muxNeighbors.Unlock()
+	return append([]string{}, bc.neighbors...)
+}
+
 func (bc *Blockchain) GetChain() []*Block {
 	return bc.chain
 }
@@ -113,51 +163,146 @@ func (bc *Blockchain) GetTransactions() []*Transaction {
 }
 
 func (bc *Blockchain) ClearTransactionsPool() {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	for _, t := range bc.pool {
+		bc.events.Publish(Event{Kind: EventTransactionRemoved, Transaction: t})
+	}
 	bc.pool = []*Transaction{}
 }
 
-// AddBlock() takes a nonce and a previous hash and adds a new block to the blockchain.
-func (bc *Blockchain) AddBlock(nonce int, prevHash [32]byte) *Block {
-	block := NewBlock(nonce, prevHash, bc.pool)
-	bc.pool = []*Transaction{}
+// dropConfirmed() removes the pooled transactions included in the blocks, and those whose nonce
+// the chain has used up.
+func (bc *Blockchain) dropConfirmed(blocks []*Block) {
+	confirmed := make(map[string]bool)
+	for _, block := range blocks {
+		for _, t := range block.transactions {
+			confirmed[t.ID()] = true
+		}
+	}
+	pool := []*Transaction{}
+	for _, t := range bc.pool {
+		if confirmed[t.ID()] || (t.senderAddress != MINING_SENDER && t.nonce < bc.addrIndex.Summary(t.senderAddress).Nonce) {
+			bc.events.Publish(Event{Kind: EventTransactionRemoved, Transaction: t})
+			continue
+		}
+		pool = append(pool, t)
+	}
+	bc.pool = pool
+}
+
+// dropTransaction() removes the transaction from the pool.
+func (bc *Blockchain) dropTransaction(t *Transaction) {
+	pool := []*Transaction{}
+	for _, pooled := range bc.pool {
+		if pooled == t {
+			bc.events.Publish(Event{Kind: EventTransactionRemoved, Transaction: t})
+			continue
+		}
+		pool = append(pool, pooled)
+	}
+	bc.pool = pool
+}
+
+// lockTimeContext() returns the height of the next block and the time, in unix seconds, lock times
+// are compared with for it: the time the last block was mined.
+func (bc *Blockchain) lockTimeContext() (int, int64) {
+	return len(bc.chain), bc.GetLastBlock().timestamp / int64(time.Second)
+}
+
+// splitPool() separates the pooled transactions the next block can include from those whose lock
+// time has not passed yet, whose sender cannot afford them any more after a reorg, or which follow
+// a transaction of their sender the block cannot include.
+func (bc *Blockchain) splitPool() ([]*Transaction, []*Transaction) {
+	height, now := bc.lockTimeContext()
+	nonces := make(map[string]uint64)
+	balances := newLedger(func(bcAddress string) (float32, float32) {
+		s := bc.addrIndex.Summary(bcAddress)
+		return s.Received, s.Sent
+	})
+	final, pending := []*Transaction{}, []*Transaction{}
+	for _, t := range bc.pool {
+		if t.senderAddress == MINING_SENDER {
+			final = append(final, t)
+			continue
+		}
+		next, ok := nonces[t.senderAddress]
+		if !ok {
+			next = bc.addrIndex.Summary(t.senderAddress).Nonce
+		}
+		if t.IsFinal(height, now) && t.nonce == next && balances.apply(t) {
+			final = append(final, t)
+			next++
+		} else {
+			pending = append(pending, t)
+		}
+		nonces[t.senderAddress] = next
+	}
+	return final, pending
+}
+
+// AddBlock() takes a previous hash and adds a new block to the blockchain with the pooled
+// transactions it can include, sealed by the consensus engine once its state root is known. The
+// genesis block is not sealed; for any other block the engine must be prepared to seal it. A block
+// the engine fails to seal is not added, and the pool and the state are left as they were.
+func (bc *Blockchain) AddBlock(prevHash [32]byte) (*Block, error) {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.addBlock(prevHash)
+}
+
+// addBlock() adds a block like AddBlock(), with bc.mux held.
+func (bc *Blockchain) addBlock(prevHash [32]byte) (*Block, error) {
+	var final, pending []*Transaction
+	if len(bc.chain) == 0 {
+		final, pending = bc.pool, []*Transaction{}
+	} else {
+		final, pending = bc.splitPool()
+	}
+	block := NewBlock(0, prevHash, final)
+	if len(bc.chain) == 0 {
+		// every node starts from the same genesis block, so peers can tell they follow the same chain
+		block.timestamp = 0
+	}
+	block.stateRoot = bc.contracts.connect(block, len(bc.chain))
+	if len(bc.chain) > 0 {
+		if err := bc.engine.Seal(bc.chain, block); err != nil {
+			bc.contracts.disconnect(block, len(bc.chain))
+			return nil, err
+		}
+	}
+	bc.pool = pending
 	bc.chain = append(bc.chain, block)
+	bc.addrIndex.connect(block, len(bc.chain)-1)
+	bc.tokens.connect(block, len(bc.chain)-1)
+	if bc.txIndex != nil {
+		bc.txIndex.connect(block, len(bc.chain)-1)
+	}
+	bc.persist(len(bc.chain) - 1)
 
-	for _, n := range bc.neighbors {
-		endpoint := fmt.Sprintf("http://%s/transaction", n)
-		// make delete request
-		req, _ := http.NewRequest("DELETE", endpoint, nil)
-		res, _ := http.DefaultClient.Do(req)
-		fmt.Printf("%v\n", res)
+	for _, t := range block.transactions {
+		bc.events.Publish(Event{Kind: EventTransactionRemoved, Transaction: t})
 	}
+	bc.events.Publish(Event{Kind: EventNewBlock, Block: block, Height: len(bc.chain) - 1})
 
-	return block
+	return block, nil
 }
 
 func (bc *Blockchain) CreateTransaction(
-	sender, recipient string, amount float32, senderPublicKey *ecdsa.PublicKey, signature *utils.Signature,
+	sender, recipient string, amount, fee float32, senderPublicKey keys.Verifier, signature []byte,
 ) bool {
-	isTransacted := bc.AddTransaction(sender, recipient, amount, senderPublicKey, signature)
+	return bc.BroadcastTransaction(NewSignedTransaction(sender, recipient, amount, fee, senderPublicKey, signature))
+}
+
+// BroadcastTransaction() adds the transaction to the pool and relays it to the peers.
+func (bc *Blockchain) BroadcastTransaction(t *Transaction) bool {
+	isTransacted := bc.PoolTransaction(t)
 
 	if isTransacted {
-		for _, n := range bc.neighbors {
-			publicKeyStr := fmt.Sprintf("%064x%064x", senderPublicKey.X.Bytes(), senderPublicKey.Y.Bytes())
-			signatureStr := signature.ToString()
-			tr := &TransactionRequest{
-				SenderAddress:    &sender,
-				RecipientAddress: &recipient,
-				Amount:           &amount,
-				SenderPublicKey:  &publicKeyStr,
-				Signature:        &signatureStr,
-			}
-			m, _ := json.Marshal(tr)
-			buf := bytes.NewBuffer(m)
-			endpoint := fmt.Sprintf("http://%s/transactions", n)
-
-			req, _ := http.NewRequest("PUT", endpoint, buf)
-			req.Header.Set("Content-Type", "application/json")
-			res, _ := http.DefaultClient.Do(req)
-			fmt.Printf("%v\n", res)
-		}
+		tr := NewTransactionRequest(t)
+		bc.forEachPeer("relaying a transaction to", func(ctx context.Context, p PeerClient) error {
+			return p.RelayTransaction(ctx, tr)
+		})
 	}
 
 	return isTransacted
@@ -165,33 +310,266 @@ func (bc *Blockchain) CreateTransaction(
 
 // AddTransaction() creates a transaction and adds it to the pool.
 func (bc *Blockchain) AddTransaction(
-	sender, recipient string, amount float32, senderPublicKey *ecdsa.PublicKey, signature *utils.Signature,
+	sender, recipient string, amount, fee float32, senderPublicKey keys.Verifier, signature []byte,
 ) bool {
-	t := NewTransaction(sender, recipient, amount)
+	return bc.PoolTransaction(NewSignedTransaction(sender, recipient, amount, fee, senderPublicKey, signature))
+}
+
+// PoolTransaction() validates the transaction and adds it to the pool. A transaction whose lock
+// time has not passed waits in the pool until a block can include it.
+func (bc *Blockchain) PoolTransaction(t *Transaction) bool {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.poolTransaction(t)
+}
+
+// poolTransaction() pools the transaction like PoolTransaction(), with bc.mux held.
+func (bc *Blockchain) poolTransaction(t *Transaction) bool {
+	sender, recipient := t.senderAddress, t.recipientAddress
+
+	if !t.hasValidAmounts() {
+		fmt.Printf("Invalid amount %f or fee %f from %s\n", t.amount, t.fee, sender)
+		return false
+	}
+
+	if t.locktime < 0 {
+		fmt.Printf("Invalid lock time %d from %s\n", t.locktime, sender)
+		return false
+	}
+
+	if err := bc.verifyContract(t); err != nil {
+		fmt.Printf("Invalid contract transaction from %s: %v\n", sender, err)
+		return false
+	}
+
+	if err := bc.verifyToken(t); err != nil {
+		fmt.Printf("Invalid token transaction from %s: %v\n", sender, err)
+		return false
+	}
 
-	if sender != MINING_SENDER && !bc.VerifyTransaction(senderPublicKey, signature, t) {
+	if err := address.Validate(recipient, bc.network); err != nil {
+		fmt.Printf("Invalid recipient address %s: %v\n", recipient, err)
+		return false
+	}
+
+	if sender != MINING_SENDER && !bc.VerifyTransaction(t) {
 		fmt.Printf("Invalid transaction from %s", sender)
 		return false
 	}
 
-	// if bc.GetBalance(sender) < amount {
-	// 	fmt.Printf("Not enough funds to send %f from %s to %s\n", amount, sender, recipient)
-	// 	return false
-	// }
+	if err := bc.checkContractTarget(t); err != nil {
+		fmt.Printf("Invalid contract transaction from %s: %v\n", sender, err)
+		return false
+	}
+
+	// a transaction relayed back by a peer, or submitted twice, is only pooled once
+	id := t.ID()
+	for _, pooled := range bc.pool {
+		if pooled.ID() == id {
+			fmt.Printf("Transaction %s is already pooled\n", id)
+			return true
+		}
+	}
+
+	// the nonce follows the confirmed and pooled transactions of the sender, so none can be replayed
+	next := uint64(len(bc.chain))
+	if sender != MINING_SENDER {
+		next = bc.NextNonce(sender)
+	}
+	if t.nonce != next {
+		fmt.Printf("Invalid nonce %d from %s, want %d\n", t.nonce, sender, next)
+		return false
+	}
+
+	if err := bc.checkTokenTarget(t); err != nil {
+		fmt.Printf("Invalid token transaction from %s: %v\n", sender, err)
+		return false
+	}
+
+	// pooled spends are taken off the spendable balance right away, so the pool cannot overspend it
+	if sender != MINING_SENDER {
+		if spendable := bc.Balance(sender, DEFAULT_MIN_CONFIRMATIONS).Spendable; spendable < t.Cost() {
+			fmt.Printf("Not enough funds to send %f from %s: %f spendable\n", t.Cost(), sender, spendable)
+			return false
+		}
+	}
 	bc.pool = append(bc.pool, t)
+	bc.events.Publish(Event{Kind: EventTransactionAdded, Transaction: t})
 	return true
 }
 
-// VerifyTransaction() takes a public key, a signature, and a transaction and returns whether the transaction is valid.
-func (bc *Blockchain) VerifyTransaction(
-	senderPublicKey *ecdsa.PublicKey, s *utils.Signature, t *Transaction,
-) bool {
-	m, err := json.Marshal(t)
+// VerifyTransaction() takes a transaction and returns whether it may enter the pool. Scripts are
+// run as if the transaction were included in the first block its lock time allows.
+func (bc *Blockchain) VerifyTransaction(t *Transaction) bool {
+	height, now := bc.lockTimeContext()
+	switch {
+	case t.locktime >= script.LOCKTIME_THRESHOLD && t.locktime > now:
+		now = t.locktime
+	case t.locktime < script.LOCKTIME_THRESHOLD && t.locktime > int64(height):
+		height = int(t.locktime)
+	}
+	// the spend uses the funds left after the pooled spends before it
+	pending := t.Cost()
+	for _, pooled := range bc.pool {
+		if pooled.senderAddress == t.senderAddress && pooled.nonce < t.nonce {
+			pending += pooled.Cost()
+		}
+	}
+	ctx := &script.Context{
+		Height: height,
+		Time:   now,
+		Age:    bc.addrIndex.age(t.senderAddress, pending, height),
+	}
+	return bc.verifyTransaction(t, ctx)
+}
+
+// verifyTransaction() takes a transaction and returns whether its signature is valid
+// and was produced by the key that owns the sender address. A spend from a multisig address
+// must instead carry the policy of the address and enough valid signatures of its keys, and
+// a spend from a script address its locking script and a script unlocking it in the context.
+func (bc *Blockchain) verifyTransaction(t *Transaction, ctx *script.Context) bool {
+	if err := address.Validate(t.senderAddress, bc.network); err != nil {
+		fmt.Printf("Invalid sender address %s: %v\n", t.senderAddress, err)
+		return false
+	}
+
+	if t.multisig != nil {
+		return bc.verifyMultisig(t)
+	}
+	if t.lockScript != nil {
+		return bc.verifyScript(t, ctx)
+	}
+
+	if t.senderPublicKey == nil || t.signature == nil {
+		return false
+	}
+
+	if address.FromPublicKey(t.senderPublicKey, bc.network) != t.senderAddress {
+		fmt.Printf("Public key does not match sender address %s\n", t.senderAddress)
+		return false
+	}
+
+	m, err := t.SigningPayload()
 	if err != nil {
 		panic(err)
 	}
-	h := sha256.Sum256(m)
-	return ecdsa.Verify(senderPublicKey, h[:], s.R, s.S)
+	return t.senderPublicKey.Verify(m, t.signature)
+}
+
+// verifyMultisig() returns whether the transaction spends from the address of its multisig
+// policy and carries at least the threshold of valid signatures.
+func (bc *Blockchain) verifyMultisig(t *Transaction) bool {
+	if t.senderPublicKey != nil || t.signature != nil || t.lockScript != nil || t.unlockScript != nil {
+		return false
+	}
+	if t.multisig.Address(bc.network) != t.senderAddress {
+		fmt.Printf("Multisig policy does not match sender address %s\n", t.senderAddress)
+		return false
+	}
+
+	m, err := t.SigningPayload()
+	if err != nil {
+		panic(err)
+	}
+	if err := t.multisig.Verify(m, t.signatures); err != nil {
+		fmt.Printf("Invalid multisig transaction from %s: %v\n", t.senderAddress, err)
+		return false
+	}
+	return true
+}
+
+// verifyScript() returns whether the transaction spends from the address of its locking script
+// and its unlocking script satisfies the locking script in the context.
+func (bc *Blockchain) verifyScript(t *Transaction, ctx *script.Context) bool {
+	if t.senderPublicKey != nil || t.signature != nil || t.signatures != nil {
+		return false
+	}
+	if t.lockScript.Address(bc.network) != t.senderAddress {
+		fmt.Printf("Locking script does not match sender address %s\n", t.senderAddress)
+		return false
+	}
+
+	m, err := t.SigningPayload()
+	if err != nil {
+		panic(err)
+	}
+	ctx.Message = m
+	if err := script.Execute(t.unlockScript, t.lockScript, ctx); err != nil {
+		fmt.Printf("Invalid script transaction from %s: %v\n", t.senderAddress, err)
+		return false
+	}
+	return true
+}
+
+// verifyContract() returns an error unless the contract fields of the transaction are well formed.
+// A contract transaction moves no funds and pays a fee covering its gas limit; a deployment carries
+// valid code, which its gas limit covers, and is sent to the address of the contract it deploys.
+func (bc *Blockchain) verifyContract(t *Transaction) error {
+	if !t.IsContract() {
+		if len(t.input) > 0 {
+			return errors.New("input without a gas limit")
+		}
+		return nil
+	}
+
+	switch {
+	case t.senderAddress == MINING_SENDER:
+		return errors.New("mining rewards cannot deploy or call contracts")
+	case t.amount != 0:
+		return errors.New("contract transactions cannot transfer an amount")
+	case t.gasLimit > vm.MAX_GAS:
+		return fmt.Errorf("gas limit above %d", vm.MAX_GAS)
+	case t.fee < MinContractFee(t.gasLimit):
+		return fmt.Errorf("fee below %v for a gas limit of %d", MinContractFee(t.gasLimit), t.gasLimit)
+	case len(t.input) > vm.MAX_ARGS:
+		return fmt.Errorf("more than %d arguments", vm.MAX_ARGS)
+	}
+	for _, arg := range t.input {
+		if len(arg) > vm.MAX_ITEM_SIZE {
+			return vm.ErrItemSize
+		}
+	}
+
+	if !t.IsDeploy() {
+		return nil
+	}
+	if len(t.input) > 0 {
+		return errors.New("deployments take no input")
+	}
+	if err := vm.Validate(t.code); err != nil {
+		return err
+	}
+	if t.gasLimit < vm.DeployGas(t.code) {
+		return fmt.Errorf("deployment needs a gas limit of at least %d", vm.DeployGas(t.code))
+	}
+	if t.recipientAddress != vm.ContractAddress(t.senderAddress, t.code, bc.network) {
+		return errors.New("recipient is not the address of the contract")
+	}
+	return nil
+}
+
+// checkContractTarget() returns an error if a deployment targets a deployed or pooled contract or a
+// call a contract that is not deployed. Blocks may still include such transactions, which then fail.
+func (bc *Blockchain) checkContractTarget(t *Transaction) error {
+	if !t.IsContract() {
+		return nil
+	}
+	exists := bc.contracts.Exists(t.recipientAddress)
+	if !t.IsDeploy() {
+		if !exists {
+			return ErrNoContract
+		}
+		return nil
+	}
+	if exists {
+		return ErrContractExists
+	}
+	for _, pooled := range bc.pool {
+		if pooled.IsDeploy() && pooled.recipientAddress == t.recipientAddress {
+			return ErrContractExists
+		}
+	}
+	return nil
 }
 
 // GetLastBlock() returns a pointer to the last block in the blockchain.
@@ -203,83 +581,103 @@ func (bc *Blockchain) GetLastBlock() *Block {
 func (bc *Blockchain) CopyPool() []*Transaction {
 	var res []*Transaction
 	for _, t := range bc.pool {
-		res = append(res, NewTransaction(
-			t.senderAddress,
-			t.recipientAddress,
-			t.amount,
-		))
+		c := *t
+		res = append(res, &c)
 	}
 	return res
 }
 
-// ValidateProof() takes a nonce, a previous hash, a list of transactions, and a difficulty and returns whether the proof is valid.
-func (bc *Blockchain) ValidateProof(nonce int, prevHash [32]byte, transactions []*Transaction, difficulty int) bool {
-	zeroes := strings.Repeat("0", difficulty)
-	b := Block{
-		prevHash:     prevHash,
-		timestamp:    0,
-		transactions: transactions,
-		nonce:        nonce,
+// Mine() produces a new block, if the consensus engine lets this node produce the next one, and
+// announces it to the peers.
+func (bc *Blockchain) Mine() bool {
+	bc.mux.Lock()
+	mined := bc.mine()
+	bc.mux.Unlock()
+	if !mined {
+		return false
 	}
-	hashStr := fmt.Sprintf("%x", b.Hash())
-	return hashStr[:difficulty] == zeroes
-}
 
-// ProofOfWork() returns a valid nonce for the current pool of transactions.
-func (bc *Blockchain) ProofOfWork() int {
-	nonce := 0
-	for !bc.ValidateProof(nonce, bc.GetLastBlock().Hash(), bc.CopyPool(), MiningDifficulty) {
-		nonce++
-	}
-	return nonce
+	// peers are announced the block without holding the lock: a peer fetches the chain to resolve
+	// conflicts, and may be announcing a block of its own to this node meanwhile
+	bc.forEachPeer("announcing a block to", func(ctx context.Context, p PeerClient) error {
+		return p.ResolveConflicts(ctx)
+	})
+	return true
 }
 
-// Mine() mines a new block.
-func (bc *Blockchain) Mine() bool {
-	bc.mux.Lock()
-	defer bc.mux.Unlock()
-
-	if len(bc.pool) == 0 {
-		fmt.Println("No transactions to mine!")
+// mine() produces a new block like Mine(), with bc.mux held.
+func (bc *Blockchain) mine() bool {
+	// a block is produced even if the pool is empty or only holds transactions whose lock time has
+	// not passed, so the chain moves on towards their lock times and rewards the funds spent later
+	if err := bc.engine.Prepare(bc.chain); err != nil {
+		fmt.Printf("Cannot produce the next block: %v\n", err)
 		return false
 	}
 
-	nonce := bc.ProofOfWork()
-	bc.AddTransaction(MINING_SENDER, bc.address, MINING_REWARD, nil, nil)
-	bc.AddBlock(nonce, bc.GetLastBlock().Hash())
+	// the reward is part of the block, so it must be in the pool before the block is sealed; its
+	// nonce is the height of the block, so no two rewards are alike
+	reward := NewTransaction(MINING_SENDER, bc.address, MINING_REWARD+bc.poolFees())
+	reward.SetNonce(uint64(len(bc.chain)))
+	bc.poolTransaction(reward)
+	if _, err := bc.addBlock(bc.GetLastBlock().Hash()); err != nil {
+		fmt.Printf("Cannot seal the next block: %v\n", err)
+		bc.dropTransaction(reward)
+		return false
+	}
 	fmt.Println("Mined a new block successfully!")
+	return true
+}
 
-	for _, n := range bc.neighbors {
-		endpoint := fmt.Sprintf("http://%s/block", n)
-		req, _ := http.NewRequest("PUT", endpoint, nil)
-		req.Header.Set("Content-Type", "application/json")
-		res, _ := http.DefaultClient.Do(req)
-		fmt.Printf("%v\n", res)
+// poolFees() returns the sum of the fees of the pooled transactions the next block can include,
+// which the miner collects.
+func (bc *Blockchain) poolFees() float32 {
+	var fees float32
+	final, _ := bc.splitPool()
+	for _, t := range final {
+		fees += t.fee
 	}
-
-	return true
+	return fees
 }
 
-// StartMining() starts the mining process.
+// StartMining() starts the mining process, unless it is running already.
 func (bc *Blockchain) StartMining() {
+	bc.muxMining.Lock()
+	running := bc.mining
+	bc.mining = true
+	bc.muxMining.Unlock()
+	if !running {
+		bc.mineEvery()
+	}
+}
+
+// mineEvery() mines a block every MINING_TIME_SEC seconds.
+func (bc *Blockchain) mineEvery() {
 	bc.Mine()
-	time.AfterFunc(time.Second*MINING_TIME_SEC, bc.StartMining)
+	time.AfterFunc(time.Second*MINING_TIME_SEC, bc.mineEvery)
 }
 
-// GetBalance() returns the balance of a given address.
-func (bc *Blockchain) GetBalance(address string) float32 {
-	var balance float32
-	for _, block := range bc.chain {
-		for _, transaction := range block.transactions {
-			if transaction.senderAddress == address {
-				balance -= transaction.amount
-			}
-			if transaction.recipientAddress == address {
-				balance += transaction.amount
-			}
+// isMining() reports whether the mining process was started.
+func (bc *Blockchain) isMining() bool {
+	bc.muxMining.Lock()
+	defer bc.muxMining.Unlock()
+	return bc.mining
+}
+
+// NextNonce() returns the nonce the next transaction of the address must carry: the number of
+// transactions it has sent, pooled ones included.
+func (bc *Blockchain) NextNonce(bcAddress string) uint64 {
+	nonce := bc.addrIndex.Summary(bcAddress).Nonce
+	for _, t := range bc.pool {
+		if t.senderAddress == bcAddress {
+			nonce++
 		}
 	}
-	return balance
+	return nonce
+}
+
+// GetBalance() returns the balance of a given address.
+func (bc *Blockchain) GetBalance(address string) float32 {
+	return bc.addrIndex.Summary(address).Balance
 }
 
 // ToString() returns a developer-friendly string representation of the blockchain.
@@ -295,16 +693,72 @@ func (bc *Blockchain) ToString() string {
 }
 
 func (bc *Blockchain) IsValidChain(chain []*Block) bool {
-	if len(chain) == 0 {
+	if len(chain) == 0 || chain[0].Hash() != bc.chain[0].Hash() {
 		return false
 	}
 
 	prevBlock := chain[0]
 	idx := 1
+	// nonces holds the number of transactions each address has sent, which its next one must carry,
+	// balances what each address can spend before the next transaction, and credits the blocks
+	// crediting each address, which relative lock times are measured from
+	nonces := make(map[string]uint64)
+	balances := newLedger(nil)
+	credits := make(map[string][]credit)
+	credit := func(t *Transaction, height int) {
+		if t.Value() > 0 {
+			credits[t.recipientAddress] = addCredit(credits[t.recipientAddress], height, balances.received[t.recipientAddress])
+		}
+	}
+	for _, t := range prevBlock.transactions {
+		balances.apply(t)
+		credit(t, 0)
+	}
+	// the contract state is replayed to check the state root of every block
+	state := NewContractState()
+	if state.connect(prevBlock, 0) != prevBlock.stateRoot {
+		return false
+	}
 
 	for idx < len(chain) {
 		block := chain[idx]
-		if block.prevHash != prevBlock.Hash() || !bc.ValidateProof(block.nonce, block.prevHash, block.transactions, MiningDifficulty) {
+		if block.prevHash != prevBlock.Hash() || bc.engine.VerifyHeader(chain[:idx], block) != nil {
+			return false
+		}
+		// the mining reward is the last transaction of a block and pays the reward and its fees
+		var fees float32
+		for i, t := range block.transactions {
+			now := prevBlock.timestamp / int64(time.Second)
+			if !t.hasValidAmounts() || t.locktime < 0 || !t.IsFinal(idx, now) || address.Validate(t.recipientAddress, bc.network) != nil {
+				return false
+			}
+			if bc.verifyContract(t) != nil || bc.verifyToken(t) != nil {
+				return false
+			}
+			if t.senderAddress == MINING_SENDER {
+				if i != len(block.transactions)-1 || t.fee != 0 || t.amount != MINING_REWARD+fees || t.nonce != uint64(idx) {
+					return false
+				}
+				balances.apply(t)
+				credit(t, idx)
+				continue
+			}
+			if t.nonce != nonces[t.senderAddress] || !balances.apply(t) {
+				return false
+			}
+			nonces[t.senderAddress]++
+			fees += t.fee
+			ctx := &script.Context{
+				Height: idx,
+				Time:   now,
+				Age:    spentAge(credits[t.senderAddress], balances.sent[t.senderAddress], idx),
+			}
+			if !bc.verifyTransaction(t, ctx) {
+				return false
+			}
+			credit(t, idx)
+		}
+		if state.connect(block, idx) != block.stateRoot {
 			return false
 		}
 		prevBlock = block
@@ -313,31 +767,79 @@ func (bc *Blockchain) IsValidChain(chain []*Block) bool {
 	return true
 }
 
+// ResolveConflicts() adopts the best valid chain of the peers, if it is better than this one. The
+// chains are fetched and validated without holding the lock, and compared again once it is held.
 func (bc *Blockchain) ResolveConflicts() bool {
-	var longestChain []*Block
-	maxLength := len(bc.chain)
-
-	for _, n := range bc.neighbors {
-		endpoint := fmt.Sprintf("http://%s/chain", n)
-		res, _ := http.Get(endpoint)
-		defer res.Body.Close()
-		if res.StatusCode == http.StatusOK {
-			var bcRes Blockchain
-			decoder := json.NewDecoder(res.Body)
-			_ = decoder.Decode(&bcRes)
-
-			chain := bcRes.GetChain()
-
-			if len(chain) > maxLength && bc.IsValidChain(chain) {
-				maxLength = len(chain)
-				longestChain = chain
-			}
+	var bestChain []*Block
+	bc.mux.Lock()
+	best := bc.chain
+	bc.mux.Unlock()
+
+	bc.forEachPeer("fetching the chain of", func(ctx context.Context, p PeerClient) error {
+		chain, err := p.Chain(ctx)
+		if err != nil {
+			return err
+		}
+		if bc.engine.IsBetterChain(best, chain) && bc.IsValidChain(chain) {
+			best = chain
+			bestChain = chain
 		}
+		return nil
+	})
+
+	if bestChain == nil {
+		return false
 	}
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	// a block mined meanwhile may have made this chain the better one
+	if !bc.engine.IsBetterChain(bc.chain, bestChain) {
+		return false
+	}
+	bc.replaceChain(bestChain)
+	return true
+}
 
-	if longestChain != nil {
-		bc.chain = longestChain
-		return true
+// replaceChain() adopts a longer chain and publishes the reorg and the blocks it connects. The
+// caller holds bc.mux.
+func (bc *Blockchain) replaceChain(chain []*Block) {
+	old := bc.chain
+	fork := 0
+	for fork < len(old) && fork < len(chain) && old[fork].Hash() == chain[fork].Hash() {
+		fork++
+	}
+	bc.chain = chain
+	for height := len(old) - 1; height >= fork; height-- {
+		bc.addrIndex.disconnect(old[height], height)
+		bc.contracts.disconnect(old[height], height)
+		bc.tokens.disconnect(old[height], height)
+		if bc.txIndex != nil {
+			bc.txIndex.disconnect(old[height], height)
+		}
+	}
+	for height := fork; height < len(chain); height++ {
+		bc.addrIndex.connect(chain[height], height)
+		bc.contracts.connect(chain[height], height)
+		bc.tokens.connect(chain[height], height)
+		if bc.txIndex != nil {
+			bc.txIndex.connect(chain[height], height)
+		}
+	}
+	bc.persist(fork)
+	bc.dropConfirmed(chain[fork:])
+
+	if fork < len(old) {
+		bc.events.Publish(Event{Kind: EventReorg, Reorg: &Reorg{
+			ForkHeight:   fork - 1,
+			OldHeight:    len(old) - 1,
+			NewHeight:    len(chain) - 1,
+			OldTip:       fmt.Sprintf("%x", old[len(old)-1].Hash()),
+			NewTip:       fmt.Sprintf("%x", chain[len(chain)-1].Hash()),
+			Disconnected: len(old) - fork,
+			Connected:    len(chain) - fork,
+		}})
+	}
+	for height := fork; height < len(chain); height++ {
+		bc.events.Publish(Event{Kind: EventNewBlock, Block: chain[height], Height: height})
 	}
-	return false
 }
@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+func TestStartMiningOnce(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	bc.StartMining()
+	bc.StartMining()
+	if len(bc.chain) != 2 {
+		t.Errorf("%d blocks after starting to mine twice, want 2", len(bc.chain))
+	}
+	if !bc.MiningInfo().Mining {
+		t.Error("MiningInfo() does not report mining")
+	}
+}
+
+func TestConcurrentMining(t *testing.T) {
+	const miners, blocks, payments = 4, 3, 20
+	bc := newTestChain(t, address.TestNet)
+	k := generateKey(t)
+	fund(t, bc, k)
+	recipient := address.FromPublicKey(generateKey(t).Public(), bc.network)
+	var paid []*Transaction
+	for nonce := uint64(0); nonce < payments; nonce++ {
+		paid = append(paid, payment(t, bc, k, recipient, 0.01, nonce))
+	}
+	height := len(bc.chain)
+
+	var wg sync.WaitGroup
+	for i := 0; i < miners; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < blocks; j++ {
+				bc.Mine()
+			}
+		}()
+	}
+	rejected := make(chan *Transaction, payments)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, tx := range paid {
+			if !bc.PoolTransaction(tx) {
+				rejected <- tx
+			}
+		}
+	}()
+	wg.Wait()
+	close(rejected)
+	for tx := range rejected {
+		t.Errorf("payment %d rejected while mining", tx.nonce)
+	}
+	mine(t, bc)
+
+	if want := height + miners*blocks + 1; len(bc.chain) != want {
+		t.Errorf("%d blocks, want %d", len(bc.chain), want)
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain mined concurrently is not valid")
+	}
+	confirmed := make(map[string]bool)
+	for _, block := range bc.chain {
+		for _, tx := range block.transactions {
+			confirmed[tx.ID()] = true
+		}
+	}
+	for _, tx := range paid {
+		if !confirmed[tx.ID()] {
+			t.Errorf("payment %d not confirmed", tx.nonce)
+		}
+	}
+}
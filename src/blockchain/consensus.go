@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// POW_MEDIAN_TIME_BLOCKS is the number of recent blocks whose median timestamp a block must be newer than.
+	POW_MEDIAN_TIME_BLOCKS = 11
+	// POW_MAX_FUTURE_SEC is how far ahead of the local clock a mined block may be timestamped.
+	POW_MAX_FUTURE_SEC = MINING_TIME_SEC
+)
+
+var (
+	// ErrInvalidProof is returned for a block whose nonce does not meet the difficulty.
+	ErrInvalidProof = errors.New("invalid proof of work")
+	// ErrInvalidTimestamp is returned for a block timestamped before the median of the recent blocks
+	// or too far in the future.
+	ErrInvalidTimestamp = errors.New("block timestamp is out of range")
+)
+
+// ConsensusEngine decides how blocks are produced, which blocks may extend a chain and which of two
+// chains a node follows. The chain passed to it holds the blocks before the one produced or checked,
+// so the height of that block is the length of the chain.
+type ConsensusEngine interface {
+	// Name() returns the name of the engine, as reported by the node.
+	Name() string
+	// Difficulty() returns the proof of work difficulty of blocks, or 0 if the engine uses none.
+	Difficulty() int
+	// Prepare() returns an error unless the node may produce the next block of the chain now.
+	Prepare(chain []*Block) error
+	// Seal() completes the next block of the chain, whose transactions and state root are final,
+	// so that VerifyHeader() accepts it.
+	Seal(chain []*Block, block *Block) error
+	// VerifyHeader() returns an error unless the block is sealed to follow the chain.
+	VerifyHeader(chain []*Block, block *Block) error
+	// IsBetterChain() reports whether the candidate chain should replace the current one.
+	IsBetterChain(current, candidate []*Block) bool
+}
+
+// ProofOfWork is the default engine: any node may mine a block by finding a nonce whose block
+// hash starts with difficulty zeroes, and the longest chain wins.
+type ProofOfWork struct {
+	difficulty int
+}
+
+func NewProofOfWork(difficulty int) *ProofOfWork {
+	return &ProofOfWork{difficulty}
+}
+
+func (pow *ProofOfWork) Name() string {
+	return "pow"
+}
+
+func (pow *ProofOfWork) Difficulty() int {
+	return pow.difficulty
+}
+
+// Prepare() lets any node mine.
+func (pow *ProofOfWork) Prepare(chain []*Block) error {
+	return nil
+}
+
+// Seal() finds the nonce of the block.
+func (pow *ProofOfWork) Seal(chain []*Block, block *Block) error {
+	block.nonce = 0
+	for !pow.ValidateProof(block.nonce, block.prevHash, block.timestamp, block.transactions) {
+		block.nonce++
+	}
+	return nil
+}
+
+// VerifyHeader() checks the timestamp and the nonce of the block, which carries no signature. A miner
+// cannot date a block back past the median of the recent blocks, nor ahead of the clock by more than
+// POW_MAX_FUTURE_SEC, so lock times by timestamp cannot be passed early by forging it.
+func (pow *ProofOfWork) VerifyHeader(chain []*Block, block *Block) error {
+	if len(chain) == 0 {
+		return errors.New("the genesis block is not sealed")
+	}
+	if len(block.signature) > 0 {
+		return errors.New("proof of work blocks are not signed")
+	}
+	if block.timestamp <= medianTime(chain) || block.timestamp > time.Now().UnixNano()+POW_MAX_FUTURE_SEC*int64(time.Second) {
+		return ErrInvalidTimestamp
+	}
+	if !pow.ValidateProof(block.nonce, block.prevHash, block.timestamp, block.transactions) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// IsBetterChain() prefers the longer chain, which has the most work as every block has the same difficulty.
+func (pow *ProofOfWork) IsBetterChain(current, candidate []*Block) bool {
+	return len(candidate) > len(current)
+}
+
+// ValidateProof() takes a nonce, a previous hash, a timestamp and a list of transactions and returns whether
+// the proof is valid. The proof does not cover the state root of the block, which is checked by replaying it.
+func (pow *ProofOfWork) ValidateProof(nonce int, prevHash [32]byte, timestamp int64, transactions []*Transaction) bool {
+	zeroes := strings.Repeat("0", pow.difficulty)
+	b := Block{
+		prevHash:     prevHash,
+		timestamp:    timestamp,
+		transactions: transactions,
+		nonce:        nonce,
+	}
+	hashStr := fmt.Sprintf("%x", b.Hash())
+	return hashStr[:pow.difficulty] == zeroes
+}
+
+// medianTime() returns the median timestamp of the last POW_MEDIAN_TIME_BLOCKS blocks of the chain.
+func medianTime(chain []*Block) int64 {
+	start := len(chain) - POW_MEDIAN_TIME_BLOCKS
+	if start < 0 {
+		start = 0
+	}
+	var timestamps []int64
+	for _, block := range chain[start:] {
+		timestamps = append(timestamps, block.timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/vm"
+)
+
+func TestProofOfWorkInvalidHeaders(t *testing.T) {
+	pow := NewProofOfWork(1)
+	// the median of the last blocks is a minute old, though the newest block is older still
+	start := time.Now().Add(-time.Hour)
+	var chain []*Block
+	for _, age := range []time.Duration{0, 59 * time.Minute, 59 * time.Minute, 59 * time.Minute, 10 * time.Minute} {
+		block := NewBlock(0, [32]byte{}, nil)
+		block.timestamp = start.Add(age).UnixNano()
+		chain = append(chain, block)
+	}
+	median := start.Add(59 * time.Minute).UnixNano()
+
+	sealed := func(timestamp int64) *Block {
+		block := NewBlock(0, chain[len(chain)-1].Hash(), nil)
+		block.timestamp = timestamp
+		if err := pow.Seal(chain, block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+	if err := pow.VerifyHeader(chain, sealed(time.Now().UnixNano())); err != nil {
+		t.Fatalf("VerifyHeader() = %v", err)
+	}
+
+	redated := sealed(time.Now().UnixNano())
+	redated.timestamp -= int64(time.Second)
+	for {
+		if !pow.ValidateProof(redated.nonce, redated.prevHash, redated.timestamp, redated.transactions) {
+			break
+		}
+		redated.timestamp--
+	}
+	for _, tt := range []struct {
+		name  string
+		block *Block
+		want  error
+	}{
+		{"redated after it was sealed", redated, ErrInvalidProof},
+		{"at the median of the recent blocks", sealed(median), ErrInvalidTimestamp},
+		{"older than the median of the recent blocks", sealed(median - int64(time.Second)), ErrInvalidTimestamp},
+		{"from the future", sealed(time.Now().Add(time.Minute).UnixNano()), ErrInvalidTimestamp},
+	} {
+		if err := pow.VerifyHeader(chain, tt.block); !errors.Is(err, tt.want) {
+			t.Errorf("VerifyHeader() of a block %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// failingEngine is a proof of work that cannot seal blocks.
+type failingEngine struct {
+	*ProofOfWork
+}
+
+var errSeal = errors.New("cannot seal")
+
+func (e failingEngine) Seal(chain []*Block, block *Block) error {
+	return errSeal
+}
+
+func TestSealFailure(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	k := generateKey(t)
+	fund(t, bc, k)
+	code, err := vm.Assemble(counterCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploy := poolContract(t, bc, k, vm.ContractAddress(address.FromPublicKey(k.Public(), bc.network), code, bc.network), code)
+	height, root := len(bc.chain), bc.contracts.Root()
+
+	bc.SetConsensus(failingEngine{NewProofOfWork(1)})
+	if bc.Mine() {
+		t.Fatal("Mine() = true with an engine that cannot seal")
+	}
+	if _, err := bc.AddBlock(bc.GetLastBlock().Hash()); !errors.Is(err, errSeal) {
+		t.Errorf("AddBlock() = %v, want %v", err, errSeal)
+	}
+	if len(bc.chain) != height {
+		t.Errorf("%d blocks after failing to seal, want %d", len(bc.chain), height)
+	}
+	if len(bc.pool) != 1 || bc.pool[0] != deploy {
+		t.Errorf("pool after failing to seal = %v, want the deployment only", bc.pool)
+	}
+	if bc.contracts.Root() != root {
+		t.Error("contract state changed by a block that was not sealed")
+	}
+
+	bc.SetConsensus(NewProofOfWork(1))
+	mine(t, bc)
+	if r, ok := bc.Receipt(deploy.ID()); !ok || !r.Success {
+		t.Errorf("receipt of the deployment = %+v, %v", r, ok)
+	}
+}
@@ -0,0 +1,351 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"sort"
+	"sync"
+
+	"github.com/Rha02/block-beard/src/vm"
+)
+
+// CONTRACT_GAS_PRICE is the fee a contract transaction pays per unit of its gas limit.
+const CONTRACT_GAS_PRICE = 0.000001
+
+var (
+	ErrNoContract     = errors.New("no contract at the address")
+	ErrContractExists = errors.New("a contract is already deployed at the address")
+)
+
+// contract is a deployed contract and its storage.
+type contract struct {
+	code     vm.Code
+	deployer string
+	height   int
+	storage  map[string][]byte
+}
+
+// Receipt is the outcome of a confirmed contract transaction. A failed call is still confirmed
+// and pays its fee, but leaves the storage of the contract as it was.
+type Receipt struct {
+	TransactionID string `json:"transaction_id"`
+	Contract      string `json:"contract"`
+	Height        int    `json:"height"`
+	Success       bool   `json:"success"`
+	GasUsed       uint64 `json:"gas_used"`
+	Return        string `json:"return,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ContractView is a contract as returned by the query API, with its storage hex encoded.
+type ContractView struct {
+	Address  string            `json:"address"`
+	Deployer string            `json:"deployer"`
+	Height   int               `json:"height"`
+	Code     vm.Code           `json:"code"`
+	Asm      string            `json:"asm"`
+	Storage  map[string]string `json:"storage"`
+}
+
+// stateChange is what reverting a change to the contract state restores: the previous value of a
+// storage key, or, when key is empty, the absence of the contract.
+type stateChange struct {
+	contract string
+	key      string
+	value    []byte
+}
+
+// ContractState holds the deployed contracts, their storage and the receipts of contract
+// transactions. Like the address index it is updated as blocks connect and disconnect; the changes
+// of every block are journaled so that a reorg can revert them.
+type ContractState struct {
+	mux       sync.RWMutex
+	contracts map[string]*contract
+	receipts  map[string]*Receipt
+	journal   map[int][]stateChange
+}
+
+func NewContractState() *ContractState {
+	return &ContractState{
+		contracts: make(map[string]*contract),
+		receipts:  make(map[string]*Receipt),
+		journal:   make(map[int][]stateChange),
+	}
+}
+
+// MinContractFee() returns the least fee a contract transaction with the gas limit must pay.
+func MinContractFee(gasLimit uint64) float32 {
+	return float32(gasLimit) * CONTRACT_GAS_PRICE
+}
+
+// storageOverlay buffers the writes of a call on top of the storage of a contract.
+type storageOverlay struct {
+	base   map[string][]byte
+	writes map[string][]byte
+}
+
+func newStorageOverlay(base map[string][]byte) *storageOverlay {
+	return &storageOverlay{base, make(map[string][]byte)}
+}
+
+func (s *storageOverlay) Get(key []byte) []byte {
+	if value, ok := s.writes[string(key)]; ok {
+		return value
+	}
+	return s.base[string(key)]
+}
+
+func (s *storageOverlay) Set(key, value []byte) {
+	s.writes[string(key)] = append([]byte{}, value...)
+}
+
+// connect() applies the contract transactions of the block at the height and returns the new state root.
+func (cs *ContractState) connect(block *Block, height int) [32]byte {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	var changes []stateChange
+	for _, t := range block.transactions {
+		if !t.IsContract() {
+			continue
+		}
+		r := cs.apply(t, height, &changes)
+		cs.receipts[r.TransactionID] = r
+	}
+	cs.journal[height] = changes
+	return cs.root()
+}
+
+// apply() executes a contract transaction included at the height, journaling its changes.
+func (cs *ContractState) apply(t *Transaction, height int, changes *[]stateChange) *Receipt {
+	r := &Receipt{TransactionID: t.ID(), Contract: t.recipientAddress, Height: height}
+
+	if t.IsDeploy() {
+		r.GasUsed = vm.DeployGas(t.code)
+		if _, ok := cs.contracts[t.recipientAddress]; ok {
+			r.Error = ErrContractExists.Error()
+			return r
+		}
+		cs.contracts[t.recipientAddress] = &contract{t.code, t.senderAddress, height, make(map[string][]byte)}
+		*changes = append(*changes, stateChange{contract: t.recipientAddress})
+		r.Success = true
+		return r
+	}
+
+	c, ok := cs.contracts[t.recipientAddress]
+	if !ok {
+		r.GasUsed = vm.GAS_CALL
+		r.Error = ErrNoContract.Error()
+		return r
+	}
+	res, err := execute(c, t.senderAddress, t.recipientAddress, height, t.input, t.gasLimit, func(storage *storageOverlay) {
+		for key, value := range storage.writes {
+			*changes = append(*changes, stateChange{t.recipientAddress, key, c.storage[key]})
+			setStorage(c.storage, key, value)
+		}
+	})
+	r.GasUsed = res.GasUsed
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Return = hex.EncodeToString(res.Return)
+	r.Success = true
+	return r
+}
+
+// execute() calls the contract with the gas limit, which includes the base cost of a call, and
+// passes the writes of a successful call to commit.
+func execute(
+	c *contract, caller, contractAddress string, height int, input [][]byte, gasLimit uint64,
+	commit func(*storageOverlay),
+) (*vm.Result, error) {
+	if gasLimit < vm.GAS_CALL {
+		return &vm.Result{GasUsed: gasLimit}, vm.ErrOutOfGas
+	}
+	storage := newStorageOverlay(c.storage)
+	ctx := &vm.Context{Caller: caller, Contract: contractAddress, Height: height, Input: input}
+	res, err := vm.Execute(c.code, ctx, storage, gasLimit-vm.GAS_CALL)
+	res.GasUsed += vm.GAS_CALL
+	if err != nil {
+		return res, err
+	}
+	if commit != nil {
+		commit(storage)
+	}
+	return res, nil
+}
+
+// setStorage() sets the value of the key, deleting it if the value is empty.
+func setStorage(storage map[string][]byte, key string, value []byte) {
+	if len(value) == 0 {
+		delete(storage, key)
+		return
+	}
+	storage[key] = value
+}
+
+// disconnect() reverts the contract transactions of the block at the height, which must be the last
+// one connected.
+func (cs *ContractState) disconnect(block *Block, height int) {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	changes := cs.journal[height]
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.key == "" {
+			delete(cs.contracts, change.contract)
+			continue
+		}
+		setStorage(cs.contracts[change.contract].storage, change.key, change.value)
+	}
+	delete(cs.journal, height)
+
+	for _, t := range block.transactions {
+		if !t.IsContract() {
+			continue
+		}
+		id := t.ID()
+		if r, ok := cs.receipts[id]; ok && r.Height == height {
+			delete(cs.receipts, id)
+		}
+	}
+}
+
+// rebuild() replays the contract transactions of the chain from scratch.
+func (cs *ContractState) rebuild(chain []*Block) {
+	cs.mux.Lock()
+	cs.contracts = make(map[string]*contract)
+	cs.receipts = make(map[string]*Receipt)
+	cs.journal = make(map[int][]stateChange)
+	cs.mux.Unlock()
+	for height, block := range chain {
+		cs.connect(block, height)
+	}
+}
+
+// writeBytes() writes the length of the bytes and the bytes to the hash.
+func writeBytes(h hash.Hash, b []byte) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+	h.Write(b)
+}
+
+// root() returns the hash committing to every contract, its code and its storage, or the zero
+// hash when no contract is deployed, so chains without contracts have no state root.
+func (cs *ContractState) root() [32]byte {
+	var res [32]byte
+	if len(cs.contracts) == 0 {
+		return res
+	}
+
+	addresses := make([]string, 0, len(cs.contracts))
+	for a := range cs.contracts {
+		addresses = append(addresses, a)
+	}
+	sort.Strings(addresses)
+
+	h := sha256.New()
+	for _, a := range addresses {
+		c := cs.contracts[a]
+		writeBytes(h, []byte(a))
+		writeBytes(h, []byte(c.deployer))
+		writeBytes(h, c.code)
+
+		keys := make([]string, 0, len(c.storage))
+		for key := range c.storage {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		binary.Write(h, binary.BigEndian, uint64(len(keys)))
+		for _, key := range keys {
+			writeBytes(h, []byte(key))
+			writeBytes(h, c.storage[key])
+		}
+	}
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
+// Root() returns the state root as of the last connected block.
+func (cs *ContractState) Root() [32]byte {
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+	return cs.root()
+}
+
+// Exists() returns whether a contract is deployed at the address.
+func (cs *ContractState) Exists(contractAddress string) bool {
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+	_, ok := cs.contracts[contractAddress]
+	return ok
+}
+
+// Receipt() returns the receipt of the contract transaction with the ID and whether it was confirmed.
+func (cs *ContractState) Receipt(id string) (*Receipt, bool) {
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+	r, ok := cs.receipts[id]
+	return r, ok
+}
+
+// View() returns the view of the contract at the address and whether it exists.
+func (cs *ContractState) View(contractAddress string) (*ContractView, bool) {
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+	c, ok := cs.contracts[contractAddress]
+	if !ok {
+		return nil, false
+	}
+	v := &ContractView{
+		Address:  contractAddress,
+		Deployer: c.deployer,
+		Height:   c.height,
+		Code:     c.code,
+		Asm:      c.code.String(),
+		Storage:  make(map[string]string, len(c.storage)),
+	}
+	for key, value := range c.storage {
+		v.Storage[hex.EncodeToString([]byte(key))] = hex.EncodeToString(value)
+	}
+	return v, true
+}
+
+// Call() runs the contract at the height as if the caller called it with the input, without
+// changing its storage.
+func (cs *ContractState) Call(contractAddress, caller string, input [][]byte, height int, gasLimit uint64) (*vm.Result, error) {
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+	c, ok := cs.contracts[contractAddress]
+	if !ok {
+		return nil, ErrNoContract
+	}
+	return execute(c, caller, contractAddress, height, input, gasLimit, nil)
+}
+
+// Contract() returns the view of the contract at the address and whether it exists.
+func (bc *Blockchain) Contract(contractAddress string) (*ContractView, bool) {
+	return bc.contracts.View(contractAddress)
+}
+
+// CallContract() simulates a call of the contract by the caller in the next block, with at most
+// gasLimit gas, and returns its result without changing any state.
+func (bc *Blockchain) CallContract(contractAddress, caller string, input [][]byte, gasLimit uint64) (*vm.Result, error) {
+	if gasLimit == 0 || gasLimit > vm.MAX_GAS {
+		gasLimit = vm.MAX_GAS
+	}
+	return bc.contracts.Call(contractAddress, caller, input, len(bc.chain), gasLimit)
+}
+
+// Receipt() returns the receipt of the confirmed contract transaction with the ID and whether it exists.
+func (bc *Blockchain) Receipt(id string) (*Receipt, bool) {
+	return bc.contracts.Receipt(id)
+}
+
+// StateRoot() returns the state root of the contracts as of the tip.
+func (bc *Blockchain) StateRoot() [32]byte {
+	return bc.contracts.Root()
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/script"
+	"github.com/Rha02/block-beard/src/vm"
+)
+
+const testGasLimit = 5000
+
+// counterCode counts the calls of the contract in its storage.
+const counterCode = "'count' SLOAD DUP SIZE JUMPI @counted POP 0 counted: 1 ADD 'count' SWAP SSTORE STOP"
+
+// poolContract() pools a transaction of the key deploying the code to the recipient, or calling it if the code is empty.
+func poolContract(t *testing.T, bc *Blockchain, k keys.Signer, recipient string, code vm.Code) *Transaction {
+	t.Helper()
+	sender := address.FromPublicKey(k.Public(), bc.network)
+	tx := NewSignedTransaction(sender, recipient, 0, MinContractFee(testGasLimit), k.Public(), nil)
+	tx.SetContract(code, nil, testGasLimit)
+	tx.SetNonce(bc.NextNonce(sender))
+	m, err := tx.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.signature, err = k.Sign(m); err != nil {
+		t.Fatal(err)
+	}
+	if !bc.PoolTransaction(tx) {
+		t.Fatal("contract transaction rejected")
+	}
+	return tx
+}
+
+// count() returns the number of calls the counter at the address has stored.
+func count(t *testing.T, bc *Blockchain, contractAddress string) int64 {
+	t.Helper()
+	c, ok := bc.contracts.contracts[contractAddress]
+	if !ok {
+		t.Fatal("counter not deployed")
+	}
+	n, err := script.DecodeNum(c.storage["count"], vm.MAX_NUM_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// extend() returns the chain extended by n empty blocks, which leave the contract state as it is.
+func extend(t *testing.T, chain []*Block, n int) []*Block {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		chain = appendBlock(t, chain, nil)
+	}
+	return chain
+}
+
+// TestContractReorg calls a counter in two blocks that a reorg disconnects, and connects again.
+func TestContractReorg(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	k := generateKey(t)
+	fund(t, bc, k)
+	code, err := vm.Assemble(counterCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := vm.ContractAddress(address.FromPublicKey(k.Public(), bc.network), code, bc.network)
+	poolContract(t, bc, k, counter, code)
+	mine(t, bc)
+	deployed := bc.chain
+	deployedRoot := bc.contracts.Root()
+
+	var calls []*Transaction
+	for i := 0; i < 2; i++ {
+		calls = append(calls, poolContract(t, bc, k, counter, nil))
+		mine(t, bc)
+	}
+	if n := count(t, bc, counter); n != 2 {
+		t.Fatalf("count = %d, want 2", n)
+	}
+	called := bc.chain
+	calledRoot := bc.contracts.Root()
+
+	// a longer fork without the calls reverts them
+	fork := extend(t, deployed, len(called)-len(deployed)+1)
+	if !bc.IsValidChain(fork) {
+		t.Fatal("fork is not valid")
+	}
+	bc.replaceChain(fork)
+	if root := bc.contracts.Root(); root != deployedRoot {
+		t.Errorf("state root after the reorg = %x, want %x as deployed", root, deployedRoot)
+	}
+	if n := count(t, bc, counter); n != 0 {
+		t.Errorf("count after the reorg = %d, want 0", n)
+	}
+	for _, tx := range calls {
+		if _, ok := bc.Receipt(tx.ID()); ok {
+			t.Errorf("receipt of the disconnected call %s kept", tx.ID())
+		}
+	}
+	rebuilt := NewContractState()
+	rebuilt.rebuild(fork)
+	if rebuilt.Root() != bc.contracts.Root() {
+		t.Errorf("state root after the reorg = %x, want %x as rebuilt", bc.contracts.Root(), rebuilt.Root())
+	}
+
+	// the calls come back with the chain that has them
+	bc.replaceChain(extend(t, called, len(fork)-len(called)+1))
+	if root := bc.contracts.Root(); root != calledRoot {
+		t.Errorf("state root after reconnecting the calls = %x, want %x", root, calledRoot)
+	}
+	if n := count(t, bc, counter); n != 2 {
+		t.Errorf("count after reconnecting the calls = %d, want 2", n)
+	}
+	for _, tx := range calls {
+		if r, ok := bc.Receipt(tx.ID()); !ok || !r.Success {
+			t.Errorf("receipt of the reconnected call %s = %+v, %v", tx.ID(), r, ok)
+		}
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+}
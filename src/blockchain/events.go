@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Kinds of events published by a blockchain.
+const (
+	EventNewBlock           = "new_block"
+	EventTransactionAdded   = "transaction_added"
+	EventTransactionRemoved = "transaction_removed"
+	EventReorg              = "reorg"
+)
+
+// EVENT_BUFFER_SIZE is the number of events a subscriber may fall behind before it is dropped.
+const EVENT_BUFFER_SIZE = 256
+
+// Event is a change to the chain or the transaction pool.
+type Event struct {
+	Kind string
+	// Block and Height are set for new blocks.
+	Block  *Block
+	Height int
+	// Transaction is set for transactions added to or removed from the pool.
+	Transaction *Transaction
+	// Reorg is set when the chain was replaced by a peer's.
+	Reorg *Reorg
+}
+
+// Reorg describes the replacement of the chain by a longer one.
+type Reorg struct {
+	ForkHeight   int    `json:"fork_height"`
+	OldHeight    int    `json:"old_height"`
+	NewHeight    int    `json:"new_height"`
+	OldTip       string `json:"old_tip"`
+	NewTip       string `json:"new_tip"`
+	Disconnected int    `json:"disconnected"`
+	Connected    int    `json:"connected"`
+}
+
+// Subscription receives the events published after it was created.
+type Subscription struct {
+	bus  *EventBus
+	c    chan Event
+	once sync.Once
+	// overflowed is set when the subscriber fell behind and was dropped.
+	overflowed bool
+}
+
+// Events() returns the channel events are delivered on; it is closed once the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Overflowed() reports whether the subscription ended because its subscriber fell behind.
+// It is only meaningful once Events() was closed.
+func (s *Subscription) Overflowed() bool {
+	return s.overflowed
+}
+
+// Unsubscribe() ends the subscription and closes its channel.
+func (s *Subscription) Unsubscribe() {
+	s.bus.mux.Lock()
+	defer s.bus.mux.Unlock()
+	s.bus.remove(s)
+}
+
+// EventBus fans events out to subscribers without ever blocking the publisher.
+type EventBus struct {
+	mux         sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe() returns a subscription to every event published from now on.
+func (b *EventBus) Subscribe() *Subscription {
+	s := &Subscription{bus: b, c: make(chan Event, EVENT_BUFFER_SIZE)}
+	b.mux.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mux.Unlock()
+	return s
+}
+
+// remove() drops a subscriber; the caller holds the lock.
+func (b *EventBus) remove(s *Subscription) {
+	s.once.Do(func() {
+		delete(b.subscribers, s)
+		close(s.c)
+	})
+}
+
+// Publish() delivers an event to every subscriber. A subscriber whose buffer is full is
+// dropped rather than waited for, so a slow consumer cannot stall the chain.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for s := range b.subscribers {
+		select {
+		case s.c <- e:
+		default:
+			fmt.Println("Dropping a subscriber that fell behind")
+			s.overflowed = true
+			b.remove(s)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/script"
+)
+
+const htlcAmount = 0.5
+
+func generateKey(t *testing.T) keys.Signer {
+	t.Helper()
+	k, err := keys.GenerateKey(keys.P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// newTestChain() returns a blockchain of the network mined by a key of its own, with an easy proof of work.
+func newTestChain(t *testing.T, network *address.Network) *Blockchain {
+	t.Helper()
+	bc := NewBlockchain(address.FromPublicKey(generateKey(t).Public(), network), 0, network)
+	bc.SetConsensus(NewProofOfWork(1))
+	return bc
+}
+
+func mine(t *testing.T, bc *Blockchain) {
+	t.Helper()
+	if !bc.Mine() {
+		t.Fatalf("%s: Mine() = false", bc.network.Name)
+	}
+}
+
+// fund() mines a block rewarding the key, which can then spend MINING_REWARD more.
+func fund(t *testing.T, bc *Blockchain, k keys.Signer) {
+	t.Helper()
+	miner := bc.address
+	bc.address = address.FromPublicKey(k.Public(), bc.network)
+	mine(t, bc)
+	bc.address = miner
+}
+
+// payment() returns a payment with the nonce signed by the key.
+func payment(t *testing.T, bc *Blockchain, k keys.Signer, recipient string, amount float32, nonce uint64) *Transaction {
+	t.Helper()
+	tx := NewSignedTransaction(address.FromPublicKey(k.Public(), bc.network), recipient, amount, 0, k.Public(), nil)
+	tx.SetNonce(nonce)
+	m, err := tx.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.signature, err = k.Sign(m); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// pay() pools a payment signed by the key, following the pooled transactions of its address.
+func pay(t *testing.T, bc *Blockchain, k keys.Signer, recipient string, amount float32) {
+	t.Helper()
+	tx := payment(t, bc, k, recipient, amount, bc.NextNonce(address.FromPublicKey(k.Public(), bc.network)))
+	if !bc.PoolTransaction(tx) {
+		t.Fatalf("%s: payment to %s rejected", bc.network.Name, recipient)
+	}
+}
+
+// spendHTLC() returns a spend of the amount from the address of the locking script on the chain,
+// signed by the key and unlocked by the rest of the unlocking script, which follows the signature.
+func spendHTLC(
+	t *testing.T, bc *Blockchain, lock script.Script, k keys.Signer, recipient string, amount float32, locktime int64,
+	rest func(b *script.Builder) *script.Builder,
+) *Transaction {
+	t.Helper()
+	sender := lock.Address(bc.network)
+	tx := NewScriptTransaction(sender, recipient, amount, 0, lock, nil)
+	tx.SetNonce(bc.NextNonce(sender))
+	tx.SetLocktime(locktime)
+	m, err := tx.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := k.Sign(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.unlockScript = rest(script.NewBuilder().AddData(sig)).Script()
+	return tx
+}
+
+func claim(t *testing.T, bc *Blockchain, lock script.Script, k keys.Signer, preimage []byte) *Transaction {
+	return claimAmount(t, bc, lock, k, preimage, htlcAmount)
+}
+
+func claimAmount(t *testing.T, bc *Blockchain, lock script.Script, k keys.Signer, preimage []byte, amount float32) *Transaction {
+	return spendHTLC(t, bc, lock, k, address.FromPublicKey(k.Public(), bc.network), amount, 0, func(b *script.Builder) *script.Builder {
+		return b.AddData(preimage).AddInt(1)
+	})
+}
+
+func refund(t *testing.T, bc *Blockchain, lock script.Script, k keys.Signer, locktime int64) *Transaction {
+	return spendHTLC(t, bc, lock, k, address.FromPublicKey(k.Public(), bc.network), htlcAmount, locktime, func(b *script.Builder) *script.Builder {
+		return b.AddInt(0)
+	})
+}
+
+// confirmed() returns the height of the block confirming the transaction, or -1.
+func confirmed(bc *Blockchain, tx *Transaction) int {
+	id := tx.ID()
+	for height, block := range bc.chain {
+		for _, t := range block.transactions {
+			if t.ID() == id {
+				return height
+			}
+		}
+	}
+	return -1
+}
+
+// TestAtomicSwap swaps coins between a mainnet and a testnet chain: Alice locks coins on mainnet for
+// Bob, Bob locks coins on testnet for Alice under the same hash, Alice claims on testnet with the
+// secret, and Bob claims on mainnet with the secret her claim revealed.
+func TestAtomicSwap(t *testing.T) {
+	mainnet, testnet := newTestChain(t, address.MainNet), newTestChain(t, address.TestNet)
+	alice, bob := generateKey(t), generateKey(t)
+	secret := []byte("the secret Alice picked")
+	hash := sha256.Sum256(secret)
+
+	// Alice refunds later than Bob, so Bob has time to claim once she has revealed the secret
+	fund(t, mainnet, alice)
+	fund(t, testnet, bob)
+	aliceLocktime, bobLocktime := int64(len(mainnet.chain)+20), int64(len(testnet.chain)+10)
+	aliceLock := script.HashTimeLock(hash[:], bob.Public(), aliceLocktime, alice.Public())
+	pay(t, mainnet, alice, aliceLock.Address(address.MainNet), htlcAmount)
+	mine(t, mainnet)
+	bobLock := script.HashTimeLock(hash[:], alice.Public(), bobLocktime, bob.Public())
+	pay(t, testnet, bob, bobLock.Address(address.TestNet), htlcAmount)
+	mine(t, testnet)
+
+	if testnet.PoolTransaction(claim(t, testnet, bobLock, alice, []byte("a guess"))) {
+		t.Fatal("claim with the wrong preimage accepted")
+	}
+	if testnet.PoolTransaction(claim(t, testnet, bobLock, bob, secret)) {
+		t.Fatal("claim signed by the refund key accepted")
+	}
+	if testnet.PoolTransaction(claimAmount(t, testnet, bobLock, alice, secret, 1000)) {
+		t.Fatal("claim of more than the HTLC locks accepted")
+	}
+	aliceClaim := claim(t, testnet, bobLock, alice, secret)
+	if !testnet.PoolTransaction(aliceClaim) {
+		t.Fatal("Alice's claim rejected")
+	}
+	mine(t, testnet)
+	if confirmed(testnet, aliceClaim) < 0 {
+		t.Fatal("Alice's claim not mined")
+	}
+
+	// Bob learns the secret from the unlocking script of Alice's claim
+	var revealed []byte
+	for _, block := range testnet.chain {
+		for _, tx := range block.transactions {
+			if tx.senderAddress == bobLock.Address(address.TestNet) {
+				pushed, err := tx.unlockScript.PushedData()
+				if err != nil {
+					t.Fatal(err)
+				}
+				revealed = pushed[1]
+			}
+		}
+	}
+	if !bytes.Equal(revealed, secret) {
+		t.Fatalf("revealed secret = %q, want %q", revealed, secret)
+	}
+
+	bobClaim := claim(t, mainnet, aliceLock, bob, revealed)
+	if testnet.PoolTransaction(bobClaim) {
+		t.Fatal("mainnet claim accepted by the testnet chain")
+	}
+	if !mainnet.PoolTransaction(bobClaim) {
+		t.Fatal("Bob's claim rejected")
+	}
+	mine(t, mainnet)
+	if confirmed(mainnet, bobClaim) < 0 {
+		t.Fatal("Bob's claim not mined")
+	}
+
+	for _, c := range []struct {
+		bc       *Blockchain
+		lock     script.Script
+		locktime int64
+		claimer  keys.Signer
+		refunder keys.Signer
+	}{
+		{mainnet, aliceLock, aliceLocktime, bob, alice},
+		{testnet, bobLock, bobLocktime, alice, bob},
+	} {
+		name := c.bc.network.Name
+		if balance := c.bc.GetBalance(c.lock.Address(c.bc.network)); balance != 0 {
+			t.Errorf("%s: HTLC balance = %f, want 0", name, balance)
+		}
+		if received := c.bc.addrIndex.Summary(address.FromPublicKey(c.claimer.Public(), c.bc.network)).Received; received != htlcAmount {
+			t.Errorf("%s: claimed %f, want %f", name, received, htlcAmount)
+		}
+
+		// once the HTLC expires, the refund key finds nothing left to take back
+		for int64(len(c.bc.chain)) < c.locktime {
+			mine(t, c.bc)
+		}
+		refundAfterClaim := refund(t, c.bc, c.lock, c.refunder, c.locktime)
+		if c.bc.PoolTransaction(refundAfterClaim) {
+			t.Errorf("%s: refund after the claim accepted", name)
+		}
+		if c.bc.IsValidChain(appendBlock(t, c.bc.chain, []*Transaction{refundAfterClaim})) {
+			t.Errorf("%s: chain refunding after the claim accepted", name)
+		}
+		if !c.bc.IsValidChain(c.bc.chain) {
+			t.Errorf("%s: chain is not valid", name)
+		}
+	}
+}
+
+// TestHTLCRefund refunds an unclaimed HTLC on mainnet and testnet, with the refund locked until a
+// block height and until a unix time. The refund is pooled early and only mined once its lock time passes.
+func TestHTLCRefund(t *testing.T) {
+	for _, network := range []*address.Network{address.MainNet, address.TestNet} {
+		for _, byTime := range []bool{false, true} {
+			network, byTime := network, byTime
+			name := network.Name + " height lock"
+			if byTime {
+				name = network.Name + " time lock"
+			}
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				testHTLCRefund(t, network, byTime)
+			})
+		}
+	}
+}
+
+func testHTLCRefund(t *testing.T, network *address.Network, byTime bool) {
+	bc := newTestChain(t, network)
+	claimer, refunder := generateKey(t), generateKey(t)
+	fund(t, bc, refunder)
+	hash := sha256.Sum256([]byte("a secret never revealed"))
+
+	var locktime int64
+	if byTime {
+		locktime = time.Now().Unix() + 1
+	} else {
+		locktime = int64(len(bc.chain) + 3)
+	}
+	lock := script.HashTimeLock(hash[:], claimer.Public(), locktime, refunder.Public())
+	pay(t, bc, refunder, lock.Address(network), htlcAmount)
+	mine(t, bc)
+
+	if bc.PoolTransaction(refund(t, bc, lock, refunder, 0)) {
+		t.Fatal("refund without a lock time accepted before the HTLC expired")
+	}
+	if bc.PoolTransaction(refund(t, bc, lock, claimer, locktime)) {
+		t.Fatal("refund signed by the claim key accepted")
+	}
+	tx := refund(t, bc, lock, refunder, locktime)
+	if !bc.PoolTransaction(tx) {
+		t.Fatal("refund rejected")
+	}
+
+	// blocks are mined until the refund is final, and the refund is held in the pool until then
+	for i := 0; confirmed(bc, tx) < 0; i++ {
+		if i == 20 {
+			t.Fatal("refund not mined")
+		}
+		if byTime {
+			time.Sleep(250 * time.Millisecond)
+		}
+		mine(t, bc)
+	}
+	height := confirmed(bc, tx)
+	if byTime {
+		if prev := bc.chain[height-1].timestamp / int64(time.Second); prev < locktime {
+			t.Errorf("refund mined after a block from %d, before the lock time %d", prev, locktime)
+		}
+	} else if int64(height) != locktime {
+		t.Errorf("refund mined at height %d, want %d", height, locktime)
+	}
+
+	if balance := bc.GetBalance(lock.Address(network)); balance != 0 {
+		t.Errorf("HTLC balance = %f, want 0", balance)
+	}
+	if received := bc.addrIndex.Summary(address.FromPublicKey(refunder.Public(), network)).Received; received != MINING_REWARD+htlcAmount {
+		t.Errorf("received %f, want the reward funding the HTLC and the refund of %f", received, htlcAmount)
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+}
+
+// TestRelativeTimeLock spends from an address locked for a number of blocks after each funding, which
+// funds sent to it later cannot lock again.
+func TestRelativeTimeLock(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	owner, other := generateKey(t), generateKey(t)
+	recipient := address.FromPublicKey(generateKey(t).Public(), address.TestNet)
+	fund(t, bc, owner)
+	fund(t, bc, other)
+	lock := script.RelativeTimeLock(3, owner.Public())
+	locked := lock.Address(address.TestNet)
+	signature := func(b *script.Builder) *script.Builder { return b }
+
+	pay(t, bc, owner, locked, htlcAmount)
+	mine(t, bc)
+	if bc.PoolTransaction(spendHTLC(t, bc, lock, owner, recipient, htlcAmount, 0, signature)) {
+		t.Fatal("spend accepted before the funds aged")
+	}
+	mine(t, bc)
+	mine(t, bc)
+
+	if bc.PoolTransaction(payment(t, bc, other, locked, 0, bc.NextNonce(address.FromPublicKey(other.Public(), address.TestNet)))) {
+		t.Error("transfer of nothing accepted")
+	}
+	pay(t, bc, other, locked, 0.25)
+	mine(t, bc)
+	if bc.PoolTransaction(spendHTLC(t, bc, lock, owner, recipient, htlcAmount+0.25, 0, signature)) {
+		t.Error("spend of the funds sent last accepted before they aged")
+	}
+	if !bc.PoolTransaction(spendHTLC(t, bc, lock, owner, recipient, htlcAmount, 0, signature)) {
+		t.Fatal("spend of the aged funds rejected after more funds were sent")
+	}
+	mine(t, bc)
+
+	if balance := bc.GetBalance(locked); balance != 0.25 {
+		t.Errorf("balance = %f, want the 0.25 sent last", balance)
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+	early := spendHTLC(t, bc, lock, owner, recipient, 0.25, 0, signature)
+	if bc.IsValidChain(appendBlock(t, bc.chain, []*Transaction{early})) {
+		t.Error("chain spending the funds sent last before they aged accepted")
+	}
+}
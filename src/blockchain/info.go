@@ -0,0 +1,125 @@
+package blockchain
+
+import "fmt"
+
+// ChainInfo is a summary of the state of a node's blockchain. Validators holds the proof of
+// authority validators in the order they produce blocks.
+type ChainInfo struct {
+	Height              int      `json:"height"`
+	LastHash            string   `json:"last_hash"`
+	GenesisHash         string   `json:"genesis_hash"`
+	Difficulty          int      `json:"difficulty"`
+	Consensus           string   `json:"consensus"`
+	Validators          []string `json:"validators,omitempty"`
+	PendingTransactions int      `json:"pending_transactions"`
+	Network             string   `json:"network"`
+	MiningAddress       string   `json:"mining_address"`
+	Mining              bool     `json:"mining"`
+	Peers               int      `json:"peers"`
+	TxIndex             bool     `json:"tx_index"`
+}
+
+// SubmitResponse is the reply to an accepted transaction.
+type SubmitResponse struct {
+	Message string `json:"message"`
+	ID      string `json:"id"`
+}
+
+// TransactionsResponse is the content of the transaction pool.
+type TransactionsResponse struct {
+	Transactions []*TransactionView `json:"transactions"`
+}
+
+// MiningInfo describes the node's block production.
+type MiningInfo struct {
+	Mining              bool    `json:"mining"`
+	Difficulty          int     `json:"difficulty"`
+	Consensus           string  `json:"consensus"`
+	Reward              float32 `json:"reward"`
+	IntervalSec         int     `json:"interval_sec"`
+	MiningAddress       string  `json:"mining_address"`
+	Height              int     `json:"height"`
+	PendingTransactions int     `json:"pending_transactions"`
+}
+
+// CallRequest asks for a read-only call of a contract: the hex encoded arguments of the call, the
+// address it is made from, which may be empty, and the most gas it may use, or 0 for the most a
+// transaction may use.
+type CallRequest struct {
+	Address  string   `json:"address"`
+	Caller   string   `json:"caller,omitempty"`
+	Input    []string `json:"input,omitempty"`
+	GasLimit uint64   `json:"gas_limit,omitempty"`
+}
+
+// CallResponse is the outcome of a read-only call, with what the contract returned hex encoded.
+type CallResponse struct {
+	Success bool   `json:"success"`
+	Return  string `json:"return,omitempty"`
+	GasUsed uint64 `json:"gas_used"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TokensResponse is the list of issued tokens.
+type TokensResponse struct {
+	Tokens []*TokenView `json:"tokens"`
+}
+
+// PeersResponse is the list of neighbors a node relays to.
+type PeersResponse struct {
+	Peers []string `json:"peers"`
+}
+
+// Info() returns a summary of the blockchain.
+func (bc *Blockchain) Info() *ChainInfo {
+	var validators []string
+	if poa, ok := bc.engine.(*ProofOfAuthority); ok {
+		validators = poa.Validators()
+	}
+	return &ChainInfo{
+		Height:              len(bc.chain) - 1,
+		LastHash:            fmt.Sprintf("%x", bc.GetLastBlock().Hash()),
+		GenesisHash:         fmt.Sprintf("%x", bc.chain[0].Hash()),
+		Difficulty:          bc.engine.Difficulty(),
+		Validators:          validators,
+		Consensus:           bc.engine.Name(),
+		PendingTransactions: len(bc.pool),
+		Network:             bc.network.Name,
+		MiningAddress:       bc.address,
+		Mining:              bc.isMining(),
+		Peers:               len(bc.GetNeighbors()),
+		TxIndex:             bc.txIndex != nil,
+	}
+}
+
+// GetBlock() returns the block at the height and whether it exists.
+func (bc *Blockchain) GetBlock(height int) (*Block, bool) {
+	if height < 0 || height >= len(bc.chain) {
+		return nil, false
+	}
+	return bc.chain[height], true
+}
+
+// MiningInfo() returns the state of block production.
+func (bc *Blockchain) MiningInfo() *MiningInfo {
+	return &MiningInfo{
+		Mining:              bc.isMining(),
+		Difficulty:          bc.engine.Difficulty(),
+		Consensus:           bc.engine.Name(),
+		Reward:              MINING_REWARD,
+		IntervalSec:         MINING_TIME_SEC,
+		MiningAddress:       bc.address,
+		Height:              len(bc.chain) - 1,
+		PendingTransactions: len(bc.pool),
+	}
+}
+
+// GetBlockByHash() returns the block with the hash, its height and whether it exists.
+func (bc *Blockchain) GetBlockByHash(hash [32]byte) (*Block, int, bool) {
+	for height, block := range bc.chain {
+		if block.Hash() == hash {
+			return block, height, true
+		}
+	}
+	return nil, 0, false
+}
@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/multisig"
+)
+
+// spendMultisig() returns a spend from the address of the policy signed by the co-signers.
+func spendMultisig(
+	t *testing.T, bc *Blockchain, policy *multisig.Policy, recipient string, amount float32, nonce uint64, cosigners ...keys.Signer,
+) *Transaction {
+	t.Helper()
+	tx := NewMultisigTransaction(policy.Address(bc.network), recipient, amount, 0, policy, make([][]byte, policy.N()))
+	tx.SetNonce(nonce)
+	m, err := tx.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range cosigners {
+		i, err := policy.Index(k.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.signatures[i], err = k.Sign(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tx
+}
+
+// TestMultisigTreasury spends from a 2-of-3 treasury, which can never spend more than it holds.
+func TestMultisigTreasury(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	signers, validators := newValidators(t, 3)
+	policy, err := multisig.NewPolicy(2, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury := policy.Address(address.TestNet)
+	funder := generateKey(t)
+	recipient := address.FromPublicKey(generateKey(t).Public(), address.TestNet)
+	fund(t, bc, funder)
+	pay(t, bc, funder, treasury, 0.5)
+	mine(t, bc)
+
+	if bc.PoolTransaction(spendMultisig(t, bc, policy, recipient, 0.5, 0, signers[0])) {
+		t.Error("spend signed by one of two co-signers accepted")
+	}
+	if bc.PoolTransaction(spendMultisig(t, bc, policy, recipient, 1, 0, signers[0], signers[2])) {
+		t.Error("spend of more than the treasury holds accepted")
+	}
+	if !bc.PoolTransaction(spendMultisig(t, bc, policy, recipient, 0.5, 0, signers[0], signers[2])) {
+		t.Fatal("spend of the treasury rejected")
+	}
+	if bc.PoolTransaction(spendMultisig(t, bc, policy, recipient, 0.25, 1, signers[1], signers[2])) {
+		t.Error("second spend of the emptied treasury accepted")
+	}
+	mine(t, bc)
+
+	if balance := bc.GetBalance(treasury); balance != 0 {
+		t.Errorf("treasury balance = %f, want 0", balance)
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+	overspend := spendMultisig(t, bc, policy, recipient, 0.25, 1, signers[1], signers[2])
+	if bc.IsValidChain(appendBlock(t, bc.chain, []*Transaction{overspend})) {
+		t.Error("chain spending from the emptied treasury accepted")
+	}
+}
@@ -0,0 +1,66 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PEER_TIMEOUT_SEC bounds every request a node sends to a neighbor.
+const PEER_TIMEOUT_SEC = 5
+
+// PeerClient is the part of the blockchain server API a node uses to talk to a neighbor.
+type PeerClient interface {
+	Info(ctx context.Context) (*ChainInfo, error)
+	Chain(ctx context.Context) ([]*Block, error)
+	RelayTransaction(ctx context.Context, tr *TransactionRequest) error
+	ResolveConflicts(ctx context.Context) error
+}
+
+// PeerDialer returns a client for the neighbor at host:port.
+type PeerDialer func(neighbor string) PeerClient
+
+// SetPeerDialer() sets how the blockchain reaches its neighbors; without one it does not talk to them.
+func (bc *Blockchain) SetPeerDialer(dial PeerDialer) {
+	bc.dial = dial
+}
+
+// forEachPeer() calls f for every neighbor with a timeout and logs the failures.
+func (bc *Blockchain) forEachPeer(action string, f func(ctx context.Context, p PeerClient) error) {
+	if bc.dial == nil {
+		return
+	}
+	for _, n := range bc.GetNeighbors() {
+		ctx, cancel := context.WithTimeout(context.Background(), PEER_TIMEOUT_SEC*time.Second)
+		if err := f(ctx, bc.dial(n)); err != nil {
+			fmt.Printf("Error %s %s: %v\n", action, n, err)
+		}
+		cancel()
+	}
+}
+
+// sameNetwork() returns the neighbors running the same network and consensus engine as the
+// blockchain, from the same genesis block and with the same validators, so that nodes of another
+// network on the same host are not relayed transactions or synced with.
+func (bc *Blockchain) sameNetwork(neighbors []string) []string {
+	if bc.dial == nil {
+		return neighbors
+	}
+	own := bc.Info()
+	var res []string
+	for _, n := range neighbors {
+		ctx, cancel := context.WithTimeout(context.Background(), PEER_TIMEOUT_SEC*time.Second)
+		info, err := bc.dial(n).Info(ctx)
+		cancel()
+		if err != nil {
+			fmt.Printf("Error fetching the info of %s: %v\n", n, err)
+			continue
+		}
+		if info.Network == own.Network && info.Consensus == own.Consensus &&
+			info.GenesisHash == own.GenesisHash && strings.Join(info.Validators, ",") == strings.Join(own.Validators, ",") {
+			res = append(res, n)
+		}
+	}
+	return res
+}
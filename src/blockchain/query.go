@@ -0,0 +1,267 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/multisig"
+	"github.com/Rha02/block-beard/src/script"
+	"github.com/Rha02/block-beard/src/vm"
+)
+
+const (
+	BLOCK_PAGE_DEFAULT_LIMIT = 20
+	BLOCK_PAGE_MAX_LIMIT     = 100
+)
+
+// Statuses of a transaction.
+const (
+	TransactionPending   = "pending"
+	TransactionConfirmed = "confirmed"
+)
+
+// ErrInvalidCursor is returned for a page cursor this node did not hand out.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// BlockView is a block as returned by the query API.
+type BlockView struct {
+	Height           int                `json:"height"`
+	Hash             string             `json:"hash"`
+	PrevHash         string             `json:"prev_hash"`
+	Timestamp        int64              `json:"timestamp"`
+	Nonce            int                `json:"nonce"`
+	StateRoot        string             `json:"state_root,omitempty"`
+	Signature        string             `json:"signature,omitempty"`
+	Confirmations    int                `json:"confirmations"`
+	TransactionCount int                `json:"transaction_count"`
+	Transactions     []*TransactionView `json:"transactions,omitempty"`
+}
+
+// TransactionView is a transaction as returned by the query API, with where it was confirmed.
+type TransactionView struct {
+	ID               string           `json:"id"`
+	SenderAddress    string           `json:"sender_address"`
+	RecipientAddress string           `json:"recipient_address"`
+	Amount           float32          `json:"amount"`
+	Fee              float32          `json:"fee"`
+	Nonce            uint64           `json:"nonce,omitempty"`
+	Locktime         int64            `json:"locktime,omitempty"`
+	SignatureScheme  string           `json:"signature_scheme,omitempty"`
+	SenderPublicKey  string           `json:"sender_public_key,omitempty"`
+	Signature        string           `json:"signature,omitempty"`
+	Multisig         *multisig.Policy `json:"multisig,omitempty"`
+	Signatures       []string         `json:"signatures,omitempty"`
+	LockScript       script.Script    `json:"lock_script,omitempty"`
+	UnlockScript     script.Script    `json:"unlock_script,omitempty"`
+	Code             vm.Code          `json:"code,omitempty"`
+	Input            []string         `json:"input,omitempty"`
+	GasLimit         uint64           `json:"gas_limit,omitempty"`
+	Token            string           `json:"token,omitempty"`
+	TokenAction      string           `json:"token_action,omitempty"`
+	Mintable         bool             `json:"mintable,omitempty"`
+	Status           string           `json:"status"`
+	BlockHeight      *int             `json:"block_height,omitempty"`
+	BlockHash        string           `json:"block_hash,omitempty"`
+	Confirmations    int              `json:"confirmations"`
+}
+
+// BlockPage is a page of blocks; NextCursor is empty on the last page.
+type BlockPage struct {
+	Blocks     []*BlockView `json:"blocks"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// ChainTip summarizes the last block of the chain.
+type ChainTip struct {
+	Height              int    `json:"height"`
+	Hash                string `json:"hash"`
+	PrevHash            string `json:"prev_hash"`
+	Timestamp           int64  `json:"timestamp"`
+	TransactionCount    int    `json:"transaction_count"`
+	Difficulty          int    `json:"difficulty"`
+	StateRoot           string `json:"state_root,omitempty"`
+	PendingTransactions int    `json:"pending_transactions"`
+}
+
+// NewTransactionView() returns the view of a pending transaction.
+func NewTransactionView(t *Transaction) *TransactionView {
+	v := &TransactionView{
+		ID:               t.ID(),
+		SenderAddress:    t.senderAddress,
+		RecipientAddress: t.recipientAddress,
+		Amount:           t.amount,
+		Fee:              t.fee,
+		Nonce:            t.nonce,
+		Locktime:         t.locktime,
+		Status:           TransactionPending,
+	}
+	if t.senderPublicKey != nil {
+		v.SignatureScheme = string(t.senderPublicKey.Scheme())
+		v.SenderPublicKey = keys.ToString(t.senderPublicKey)
+		v.Signature = fmt.Sprintf("%x", t.signature)
+	}
+	if t.multisig != nil {
+		v.Multisig = t.multisig
+		v.Signatures = EncodeSignatures(t.signatures)
+	}
+	v.LockScript = t.lockScript
+	v.UnlockScript = t.unlockScript
+	v.Code = t.code
+	v.Input = EncodeInput(t.input)
+	v.GasLimit = t.gasLimit
+	v.Token = t.token
+	v.TokenAction = t.tokenAction
+	v.Mintable = t.mintable
+	return v
+}
+
+// confirmedTransactionView() returns the view of a transaction included in the block at the height.
+func confirmedTransactionView(t *Transaction, block *Block, height, tipHeight int) *TransactionView {
+	v := NewTransactionView(t)
+	v.Status = TransactionConfirmed
+	v.BlockHeight = &height
+	v.BlockHash = fmt.Sprintf("%x", block.Hash())
+	v.Confirmations = tipHeight - height + 1
+	return v
+}
+
+// NewBlockView() returns the view of the block at the height of a chain whose tip is at tipHeight,
+// with its transactions if full is set.
+func NewBlockView(block *Block, height, tipHeight int, full bool) *BlockView {
+	v := &BlockView{
+		Height:           height,
+		Hash:             fmt.Sprintf("%x", block.Hash()),
+		PrevHash:         fmt.Sprintf("%x", block.prevHash),
+		Timestamp:        block.timestamp,
+		Nonce:            block.nonce,
+		Confirmations:    tipHeight - height + 1,
+		TransactionCount: len(block.transactions),
+	}
+	if block.stateRoot != [32]byte{} {
+		v.StateRoot = fmt.Sprintf("%x", block.stateRoot)
+	}
+	v.Signature = fmt.Sprintf("%x", block.signature)
+	if full {
+		v.Transactions = make([]*TransactionView, len(block.transactions))
+		for i, t := range block.transactions {
+			v.Transactions[i] = confirmedTransactionView(t, block, height, tipHeight)
+		}
+	}
+	return v
+}
+
+// BlockView() returns the view of the block at the height and whether it exists.
+func (bc *Blockchain) BlockView(height int) (*BlockView, bool) {
+	chain := bc.chain
+	if height < 0 || height >= len(chain) {
+		return nil, false
+	}
+	return NewBlockView(chain[height], height, len(chain)-1, true), true
+}
+
+// BlockViewByHash() returns the view of the block with the hash and whether it exists.
+func (bc *Blockchain) BlockViewByHash(hash [32]byte) (*BlockView, bool) {
+	block, height, ok := bc.GetBlockByHash(hash)
+	if !ok {
+		return nil, false
+	}
+	return NewBlockView(block, height, len(bc.chain)-1, true), true
+}
+
+// Blocks() returns a page of at most limit blocks starting at the cursor, newest first unless
+// ascending is set. An empty cursor starts at the tip, or at genesis when ascending.
+func (bc *Blockchain) Blocks(cursor string, limit int, ascending bool) (*BlockPage, error) {
+	chain := bc.chain
+	tipHeight := len(chain) - 1
+
+	if limit <= 0 {
+		limit = BLOCK_PAGE_DEFAULT_LIMIT
+	}
+	if limit > BLOCK_PAGE_MAX_LIMIT {
+		limit = BLOCK_PAGE_MAX_LIMIT
+	}
+
+	start, step := tipHeight, -1
+	if ascending {
+		start, step = 0, 1
+	}
+	if cursor != "" {
+		height, err := strconv.Atoi(cursor)
+		if err != nil || height < 0 || height > tipHeight {
+			return nil, ErrInvalidCursor
+		}
+		start = height
+	}
+
+	page := &BlockPage{Blocks: []*BlockView{}}
+	height := start
+	for ; height >= 0 && height <= tipHeight && len(page.Blocks) < limit; height += step {
+		page.Blocks = append(page.Blocks, NewBlockView(chain[height], height, tipHeight, false))
+	}
+	if height >= 0 && height <= tipHeight {
+		page.NextCursor = strconv.Itoa(height)
+	}
+	return page, nil
+}
+
+// Transaction() returns the view of the pending or confirmed transaction with the ID and whether it exists.
+// Confirmed transactions are looked up in the transaction index when it is enabled, and searched for
+// from the tip down otherwise.
+func (bc *Blockchain) Transaction(id string) (*TransactionView, bool) {
+	for _, t := range bc.pool {
+		if t.ID() == id {
+			return NewTransactionView(t), true
+		}
+	}
+
+	chain := bc.chain
+	tipHeight := len(chain) - 1
+	if bc.txIndex != nil {
+		loc, ok := bc.txIndex.Get(id)
+		if !ok || loc.Height > tipHeight || loc.Position >= len(chain[loc.Height].transactions) {
+			return nil, false
+		}
+		block := chain[loc.Height]
+		return confirmedTransactionView(block.transactions[loc.Position], block, loc.Height, tipHeight), true
+	}
+
+	for height := tipHeight; height >= 0; height-- {
+		for _, t := range chain[height].transactions {
+			if t.ID() == id {
+				return confirmedTransactionView(t, chain[height], height, tipHeight), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// PendingTransactions() returns the views of the transactions in the pool.
+func (bc *Blockchain) PendingTransactions() []*TransactionView {
+	pool := bc.pool
+	views := make([]*TransactionView, len(pool))
+	for i, t := range pool {
+		views[i] = NewTransactionView(t)
+	}
+	return views
+}
+
+// Tip() returns a summary of the last block of the chain.
+func (bc *Blockchain) Tip() *ChainTip {
+	chain := bc.chain
+	last := chain[len(chain)-1]
+	tip := &ChainTip{
+		Height:              len(chain) - 1,
+		Hash:                fmt.Sprintf("%x", last.Hash()),
+		PrevHash:            fmt.Sprintf("%x", last.prevHash),
+		Timestamp:           last.timestamp,
+		TransactionCount:    len(last.transactions),
+		Difficulty:          bc.engine.Difficulty(),
+		PendingTransactions: len(bc.pool),
+	}
+	if last.stateRoot != [32]byte{} {
+		tip.StateRoot = fmt.Sprintf("%x", last.stateRoot)
+	}
+	return tip
+}
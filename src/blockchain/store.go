@@ -0,0 +1,234 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	CHAIN_STORE_CHAIN_FILE   = "chain.jsonl"
+	CHAIN_STORE_TXINDEX_FILE = "txindex.jsonl"
+)
+
+// ChainStore is a directory holding the chain and the transaction index of a node.
+// Both are logs with an entry per block, so a new block only appends to them and a reorg only
+// rewrites the blocks after the fork.
+type ChainStore struct {
+	chain   *blockLog
+	txIndex *blockLog
+}
+
+// storedTxIndex is the part of the transaction index confirmed by the block with the hash.
+type storedTxIndex struct {
+	Tip          string                `json:"tip"`
+	Transactions map[string]TxLocation `json:"transactions"`
+}
+
+// blockLog is a file holding a line of json for every block of the chain, by height.
+type blockLog struct {
+	path    string
+	offsets []int64 // where the entry of the block at each height starts
+	size    int64
+}
+
+// NewChainStore() returns a pointer to a chain store backed by the directory, creating it if needed.
+func NewChainStore(dir string) (*ChainStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &ChainStore{
+		chain:   &blockLog{path: filepath.Join(dir, CHAIN_STORE_CHAIN_FILE)},
+		txIndex: &blockLog{path: filepath.Join(dir, CHAIN_STORE_TXINDEX_FILE)},
+	}, nil
+}
+
+// load() decodes the entries of the log in order. An entry left incomplete by a crash is dropped,
+// and overwritten by the next write.
+func (l *blockLog) load(decode func(data []byte) error) error {
+	l.offsets, l.size = nil, 0
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offsets []int64
+	var size int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := decode(line); err != nil {
+			return fmt.Errorf("%s at height %d: %w", filepath.Base(l.path), len(offsets), err)
+		}
+		offsets = append(offsets, size)
+		size += int64(len(line))
+	}
+	l.offsets, l.size = offsets, size
+	return nil
+}
+
+// write() replaces the entries from the height on with the json representations of the entries.
+func (l *blockLog) write(height int, entries []interface{}) error {
+	if height > len(l.offsets) {
+		return fmt.Errorf("%s has no entry before height %d", filepath.Base(l.path), height)
+	}
+	start := l.size
+	if height < len(l.offsets) {
+		start = l.offsets[height]
+	}
+	offsets := l.offsets[:height:height]
+	var data []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, start+int64(len(data)))
+		data = append(append(data, line...), '\n')
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(start); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.WriteAt(data, start); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	l.offsets, l.size = offsets, start+int64(len(data))
+	return nil
+}
+
+// LoadChain() returns the stored chain, or nil if none was stored yet.
+func (cs *ChainStore) LoadChain() ([]*Block, error) {
+	var chain []*Block
+	err := cs.chain.load(func(data []byte) error {
+		block := new(Block)
+		if err := json.Unmarshal(data, block); err != nil {
+			return err
+		}
+		chain = append(chain, block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// SaveChain() stores the blocks of the chain from the height on, replacing the stored ones.
+func (cs *ChainStore) SaveChain(chain []*Block, height int) error {
+	// blocks a failed write left out are saved too
+	if n := len(cs.chain.offsets); height > n {
+		height = n
+	}
+	entries := make([]interface{}, 0, len(chain)-height)
+	for _, block := range chain[height:] {
+		entries = append(entries, block)
+	}
+	return cs.chain.write(height, entries)
+}
+
+// LoadTxIndex() returns the stored transaction index if it was saved for every block of the chain.
+func (cs *ChainStore) LoadTxIndex(chain []*Block) (map[string]TxLocation, bool, error) {
+	locations := make(map[string]TxLocation)
+	height, matches := 0, true
+	err := cs.txIndex.load(func(data []byte) error {
+		var stored storedTxIndex
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return err
+		}
+		matches = matches && height < len(chain) && stored.Tip == fmt.Sprintf("%x", chain[height].Hash())
+		for id, loc := range stored.Transactions {
+			locations[id] = loc
+		}
+		height++
+		return nil
+	})
+	if err != nil {
+		// an index that cannot be read is built again
+		fmt.Println("Error loading the transaction index:", err)
+		return nil, false, nil
+	}
+	if !matches || height != len(chain) {
+		return nil, false, nil
+	}
+	return locations, true, nil
+}
+
+// SaveTxIndex() stores the transaction index of the blocks of the chain from the height on,
+// replacing the stored ones.
+func (cs *ChainStore) SaveTxIndex(chain []*Block, height int, idx *TxIndex) error {
+	if n := len(cs.txIndex.offsets); height > n {
+		height = n
+	}
+	entries := make([]interface{}, 0, len(chain)-height)
+	for _, block := range chain[height:] {
+		entries = append(entries, storedTxIndex{fmt.Sprintf("%x", block.Hash()), idx.block(block)})
+	}
+	return cs.txIndex.write(height, entries)
+}
+
+// UseStore() loads the chain saved in the store, if it is valid, and saves every later change to it.
+func (bc *Blockchain) UseStore(store *ChainStore) error {
+	chain, err := store.LoadChain()
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		if err := store.SaveChain(bc.chain, 0); err != nil {
+			return err
+		}
+		bc.store = store
+		return nil
+	}
+	if chain[0].Hash() != bc.chain[0].Hash() {
+		return errors.New("stored chain starts from another genesis block")
+	}
+	if !bc.IsValidChain(chain) {
+		return errors.New("stored chain is invalid")
+	}
+	bc.chain = chain
+	bc.addrIndex.rebuild(chain)
+	bc.contracts.rebuild(chain)
+	bc.tokens.rebuild(chain)
+	bc.store = store
+	return nil
+}
+
+// persist() saves the blocks from the height on, and their transaction index, if the blockchain
+// has a store.
+func (bc *Blockchain) persist(height int) {
+	if bc.store == nil {
+		return
+	}
+	if err := bc.store.SaveChain(bc.chain, height); err != nil {
+		fmt.Println("Error saving the chain:", err)
+	}
+	if bc.txIndex != nil {
+		if err := bc.store.SaveTxIndex(bc.chain, height, bc.txIndex); err != nil {
+			fmt.Println("Error saving the transaction index:", err)
+		}
+	}
+}
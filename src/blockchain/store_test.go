@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+// reopen() returns a node of the miner of bc using the store in the directory, with a transaction index.
+func reopen(t *testing.T, bc *Blockchain, dir string) *Blockchain {
+	t.Helper()
+	node := NewBlockchain(bc.address, 0, bc.network)
+	node.SetConsensus(NewProofOfWork(1))
+	store, err := NewChainStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.EnableTxIndex(); err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestChainStore(t *testing.T) {
+	dir := t.TempDir()
+	chainFile, indexFile := filepath.Join(dir, CHAIN_STORE_CHAIN_FILE), filepath.Join(dir, CHAIN_STORE_TXINDEX_FILE)
+	bc := reopen(t, newTestChain(t, address.TestNet), dir)
+
+	// a new block is appended to the stored chain and index
+	for i := 0; i < 3; i++ {
+		chain, index := readFile(t, chainFile), readFile(t, indexFile)
+		mine(t, bc)
+		if got := readFile(t, chainFile); len(got) <= len(chain) || !bytes.HasPrefix(got, chain) {
+			t.Fatalf("block %d rewrote the stored chain", len(bc.chain)-1)
+		}
+		if got := readFile(t, indexFile); len(got) <= len(index) || !bytes.HasPrefix(got, index) {
+			t.Fatalf("block %d rewrote the stored transaction index", len(bc.chain)-1)
+		}
+	}
+
+	// a reorg keeps the blocks before the fork
+	kept := bc.store.chain.offsets[2]
+	before := readFile(t, chainFile)[:kept]
+	bc.replaceChain(extend(t, bc.chain[:2], 4))
+	if got := readFile(t, chainFile); !bytes.HasPrefix(got, before) {
+		t.Error("reorg rewrote the blocks before the fork")
+	}
+
+	node := reopen(t, bc, dir)
+	if len(node.chain) != len(bc.chain) || node.GetLastBlock().Hash() != bc.GetLastBlock().Hash() {
+		t.Fatalf("reopened chain of %d blocks, want %d", len(node.chain), len(bc.chain))
+	}
+	if node.txIndex.Len() != bc.txIndex.Len() {
+		t.Errorf("reopened transaction index of %d transactions, want %d", node.txIndex.Len(), bc.txIndex.Len())
+	}
+	for height, block := range bc.chain {
+		for i, tx := range block.transactions {
+			if loc, ok := node.txIndex.Get(tx.ID()); !ok || loc != (TxLocation{height, i}) {
+				t.Errorf("reopened location of %s = %v, %v, want %d:%d", tx.ID(), loc, ok, height, i)
+			}
+		}
+	}
+
+	// a block left incomplete by a crash is dropped, and overwritten by the next one
+	f, err := os.OpenFile(chainFile, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"nonce":`))
+	f.Close()
+	node = reopen(t, bc, dir)
+	if len(node.chain) != len(bc.chain) {
+		t.Fatalf("chain of %d blocks after a crash, want %d", len(node.chain), len(bc.chain))
+	}
+	mine(t, node)
+	if node = reopen(t, node, dir); len(node.chain) != len(bc.chain)+1 {
+		t.Errorf("chain of %d blocks after mining past a crash, want %d", len(node.chain), len(bc.chain)+1)
+	}
+
+	// an index saved for another chain is built again
+	other := reopen(t, newTestChain(t, address.TestNet), t.TempDir())
+	mine(t, other)
+	if err := os.WriteFile(indexFile, readFile(t, other.store.txIndex.path), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node = reopen(t, node, dir)
+	if loc, ok := node.txIndex.Get(node.GetLastBlock().transactions[0].ID()); !ok || loc.Height != len(node.chain)-1 {
+		t.Errorf("location of the last reward = %v, %v after loading the index of another chain", loc, ok)
+	}
+	for _, tx := range other.GetLastBlock().transactions {
+		if _, ok := node.txIndex.Get(tx.ID()); ok {
+			t.Errorf("transaction %s of another chain indexed", tx.ID())
+		}
+	}
+}
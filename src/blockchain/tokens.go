@@ -0,0 +1,371 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Actions of a token transaction.
+const (
+	TokenIssue    = "issue"
+	TokenMint     = "mint"
+	TokenTransfer = "transfer"
+	TokenBurn     = "burn"
+)
+
+const (
+	TOKEN_SYMBOL_MIN_LENGTH = 2
+	TOKEN_SYMBOL_MAX_LENGTH = 12
+)
+
+var (
+	ErrNoToken            = errors.New("no token with the symbol")
+	ErrTokenExists        = errors.New("a token with the symbol is already issued")
+	ErrNotIssuer          = errors.New("only the issuer of the token can mint it")
+	ErrFixedSupply        = errors.New("the supply of the token is fixed")
+	ErrInsufficientTokens = errors.New("not enough of the token")
+)
+
+// token is an issued token and the balances of its holders.
+type token struct {
+	issuer   string
+	mintable bool
+	height   int
+	supply   float32
+	balances map[string]float32
+}
+
+// TokenView is a token as returned by the query API.
+type TokenView struct {
+	Symbol   string  `json:"symbol"`
+	Issuer   string  `json:"issuer"`
+	Mintable bool    `json:"mintable"`
+	Height   int     `json:"height"`
+	Supply   float32 `json:"supply"`
+	Holders  int     `json:"holders"`
+}
+
+// TokenBalance is the balance of an address in a token. Pooled transfers and burns are taken off
+// the spendable amount right away, while incoming tokens only become spendable once confirmed.
+type TokenBalance struct {
+	Token               string  `json:"token"`
+	Address             string  `json:"address"`
+	Confirmed           float32 `json:"confirmed"`
+	UnconfirmedIncoming float32 `json:"unconfirmed_incoming"`
+	UnconfirmedOutgoing float32 `json:"unconfirmed_outgoing"`
+	Spendable           float32 `json:"spendable"`
+}
+
+// tokenChange is what reverting a change to the tokens restores: the previous balance of a holder,
+// the previous supply when holder is empty, or the absence of a token it created.
+type tokenChange struct {
+	symbol  string
+	holder  string
+	value   float32
+	created bool
+}
+
+// TokenIndex holds the issued tokens and the balance of every holder in each of them. Like the
+// contract state it is updated as blocks connect and disconnect, journaling the changes of every
+// block so that a reorg restores the balances exactly.
+type TokenIndex struct {
+	mux     sync.RWMutex
+	tokens  map[string]*token
+	journal map[int][]tokenChange
+}
+
+func NewTokenIndex() *TokenIndex {
+	return &TokenIndex{
+		tokens:  make(map[string]*token),
+		journal: make(map[int][]tokenChange),
+	}
+}
+
+// ValidTokenSymbol() returns whether the symbol is made of upper case letters and digits, starts
+// with a letter and has an allowed length.
+func ValidTokenSymbol(symbol string) bool {
+	if len(symbol) < TOKEN_SYMBOL_MIN_LENGTH || len(symbol) > TOKEN_SYMBOL_MAX_LENGTH {
+		return false
+	}
+	for i, c := range symbol {
+		if (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// connect() applies the token transactions of the block at the height.
+func (idx *TokenIndex) connect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	var changes []tokenChange
+	for _, t := range block.transactions {
+		if t.token == "" {
+			continue
+		}
+		// like a failed contract call, a token transaction the balances do not allow is still
+		// confirmed and pays its fee, but moves no tokens
+		idx.apply(t, height, &changes)
+	}
+	idx.journal[height] = changes
+}
+
+// apply() applies a token transaction included at the height, journaling its changes, or returns
+// why the tokens do not allow it.
+func (idx *TokenIndex) apply(t *Transaction, height int, changes *[]tokenChange) error {
+	tk, ok := idx.tokens[t.token]
+	if t.tokenAction == TokenIssue {
+		if ok {
+			return ErrTokenExists
+		}
+		idx.tokens[t.token] = &token{t.senderAddress, t.mintable, height, t.amount, make(map[string]float32)}
+		*changes = append(*changes, tokenChange{symbol: t.token, created: true})
+		idx.setBalance(t.token, t.recipientAddress, t.amount, changes)
+		return nil
+	}
+	if !ok {
+		return ErrNoToken
+	}
+
+	switch t.tokenAction {
+	case TokenMint:
+		if t.senderAddress != tk.issuer {
+			return ErrNotIssuer
+		}
+		if !tk.mintable {
+			return ErrFixedSupply
+		}
+		idx.setSupply(t.token, tk.supply+t.amount, changes)
+		idx.setBalance(t.token, t.recipientAddress, tk.balances[t.recipientAddress]+t.amount, changes)
+	case TokenTransfer:
+		if tk.balances[t.senderAddress] < t.amount {
+			return ErrInsufficientTokens
+		}
+		idx.setBalance(t.token, t.senderAddress, tk.balances[t.senderAddress]-t.amount, changes)
+		idx.setBalance(t.token, t.recipientAddress, tk.balances[t.recipientAddress]+t.amount, changes)
+	case TokenBurn:
+		if tk.balances[t.senderAddress] < t.amount {
+			return ErrInsufficientTokens
+		}
+		idx.setBalance(t.token, t.senderAddress, tk.balances[t.senderAddress]-t.amount, changes)
+		idx.setSupply(t.token, tk.supply-t.amount, changes)
+	}
+	return nil
+}
+
+// setBalance() sets the balance of the holder in the token, dropping the holder at zero.
+func (idx *TokenIndex) setBalance(symbol, holder string, value float32, changes *[]tokenChange) {
+	tk := idx.tokens[symbol]
+	if changes != nil {
+		*changes = append(*changes, tokenChange{symbol: symbol, holder: holder, value: tk.balances[holder]})
+	}
+	if value == 0 {
+		delete(tk.balances, holder)
+		return
+	}
+	tk.balances[holder] = value
+}
+
+// setSupply() sets the supply of the token.
+func (idx *TokenIndex) setSupply(symbol string, value float32, changes *[]tokenChange) {
+	tk := idx.tokens[symbol]
+	*changes = append(*changes, tokenChange{symbol: symbol, value: tk.supply})
+	tk.supply = value
+}
+
+// disconnect() reverts the token transactions of the block at the height, which must be the last
+// one connected.
+func (idx *TokenIndex) disconnect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	changes := idx.journal[height]
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		switch {
+		case change.created:
+			delete(idx.tokens, change.symbol)
+		case change.holder == "":
+			idx.tokens[change.symbol].supply = change.value
+		default:
+			idx.setBalance(change.symbol, change.holder, change.value, nil)
+		}
+	}
+	delete(idx.journal, height)
+}
+
+// rebuild() replays the token transactions of the chain from scratch.
+func (idx *TokenIndex) rebuild(chain []*Block) {
+	idx.mux.Lock()
+	idx.tokens = make(map[string]*token)
+	idx.journal = make(map[int][]tokenChange)
+	idx.mux.Unlock()
+	for height, block := range chain {
+		idx.connect(block, height)
+	}
+}
+
+func (tk *token) view(symbol string) *TokenView {
+	return &TokenView{
+		Symbol:   symbol,
+		Issuer:   tk.issuer,
+		Mintable: tk.mintable,
+		Height:   tk.height,
+		Supply:   tk.supply,
+		Holders:  len(tk.balances),
+	}
+}
+
+// View() returns the view of the token with the symbol and whether it was issued.
+func (idx *TokenIndex) View(symbol string) (*TokenView, bool) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	tk, ok := idx.tokens[symbol]
+	if !ok {
+		return nil, false
+	}
+	return tk.view(symbol), true
+}
+
+// Tokens() returns the views of the issued tokens ordered by symbol.
+func (idx *TokenIndex) Tokens() []*TokenView {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	res := make([]*TokenView, 0, len(idx.tokens))
+	for symbol, tk := range idx.tokens {
+		res = append(res, tk.view(symbol))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Symbol < res[j].Symbol })
+	return res
+}
+
+// Balance() returns the confirmed balance of the address in the token with the symbol.
+func (idx *TokenIndex) Balance(symbol, bcAddress string) float32 {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	if tk, ok := idx.tokens[symbol]; ok {
+		return tk.balances[bcAddress]
+	}
+	return 0
+}
+
+// Holdings() returns the confirmed balances of the address in every token it holds, by symbol.
+func (idx *TokenIndex) Holdings(bcAddress string) map[string]float32 {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	res := make(map[string]float32)
+	for symbol, tk := range idx.tokens {
+		if balance, ok := tk.balances[bcAddress]; ok {
+			res[symbol] = balance
+		}
+	}
+	return res
+}
+
+// verifyToken() returns an error unless the token fields of the transaction are well formed. A token
+// transaction moves a positive amount of a token with a valid symbol, except that the issue of a
+// mintable token may start with no supply; a burn is sent back to the sender.
+func (bc *Blockchain) verifyToken(t *Transaction) error {
+	if t.token == "" {
+		if t.tokenAction != "" || t.mintable {
+			return errors.New("token fields without a token")
+		}
+		return nil
+	}
+
+	switch {
+	case t.senderAddress == MINING_SENDER:
+		return errors.New("mining rewards cannot move tokens")
+	case t.IsContract():
+		return errors.New("token transactions cannot deploy or call contracts")
+	case !ValidTokenSymbol(t.token):
+		return fmt.Errorf("invalid token symbol %q", t.token)
+	case t.mintable && t.tokenAction != TokenIssue:
+		return errors.New("only an issue sets whether a token is mintable")
+	case t.amount < 0 || (t.amount == 0 && !t.mintable):
+		return errors.New("token amount must be positive")
+	}
+
+	switch t.tokenAction {
+	case TokenIssue, TokenMint, TokenTransfer:
+		return nil
+	case TokenBurn:
+		if t.recipientAddress != t.senderAddress {
+			return errors.New("a burn must be sent to the sender")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown token action %q", t.tokenAction)
+	}
+}
+
+// checkTokenTarget() returns an error if the confirmed tokens and the pool would not allow the token
+// transaction: an issue of a token already issued or pooled, a mint of a token that is not mintable
+// or not by its issuer, or a transfer or burn of more than the sender can spend. Blocks may still
+// include such transactions, which then move no tokens.
+func (bc *Blockchain) checkTokenTarget(t *Transaction) error {
+	if t.token == "" {
+		return nil
+	}
+	tk, ok := bc.tokens.View(t.token)
+	if t.tokenAction == TokenIssue {
+		if ok {
+			return ErrTokenExists
+		}
+		for _, pooled := range bc.pool {
+			if pooled.token == t.token && pooled.tokenAction == TokenIssue {
+				return ErrTokenExists
+			}
+		}
+		return nil
+	}
+	if !ok {
+		return ErrNoToken
+	}
+
+	switch t.tokenAction {
+	case TokenMint:
+		if t.senderAddress != tk.Issuer {
+			return ErrNotIssuer
+		}
+		if !tk.Mintable {
+			return ErrFixedSupply
+		}
+	default:
+		if bc.TokenBalance(t.senderAddress, t.token).Spendable < t.amount {
+			return ErrInsufficientTokens
+		}
+	}
+	return nil
+}
+
+// Token() returns the view of the token with the symbol and whether it was issued.
+func (bc *Blockchain) Token(symbol string) (*TokenView, bool) {
+	return bc.tokens.View(symbol)
+}
+
+// Tokens() returns the views of the issued tokens ordered by symbol.
+func (bc *Blockchain) Tokens() []*TokenView {
+	return bc.tokens.Tokens()
+}
+
+// TokenBalance() returns the balance of the address in the token with the symbol, counting the
+// pooled token transactions as unconfirmed.
+func (bc *Blockchain) TokenBalance(bcAddress, symbol string) *TokenBalance {
+	b := &TokenBalance{Token: symbol, Address: bcAddress, Confirmed: bc.tokens.Balance(symbol, bcAddress)}
+	for _, t := range bc.pool {
+		if t.token != symbol {
+			continue
+		}
+		if t.recipientAddress == bcAddress && t.tokenAction != TokenBurn {
+			b.UnconfirmedIncoming += t.amount
+		}
+		if t.senderAddress == bcAddress && (t.tokenAction == TokenTransfer || t.tokenAction == TokenBurn) {
+			b.UnconfirmedOutgoing += t.amount
+		}
+	}
+	b.Spendable = b.Confirmed - b.UnconfirmedOutgoing
+	return b
+}
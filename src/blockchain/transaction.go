@@ -1,8 +1,16 @@
 package blockchain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/multisig"
+	"github.com/Rha02/block-beard/src/script"
+	"github.com/Rha02/block-beard/src/vm"
 )
 
 // Transaction is a struct for a transaction in the blockchain.
@@ -10,6 +18,29 @@ type Transaction struct {
 	senderAddress    string
 	recipientAddress string
 	amount           float32
+	fee              float32
+	// the number of transactions the sender sent before this one, so a signed transaction is only
+	// valid once; a mining reward carries the height of its block instead
+	nonce           uint64
+	senderPublicKey keys.Verifier
+	signature       []byte
+	// a spend from a multisig address carries the policy and one signature slot per key instead
+	multisig   *multisig.Policy
+	signatures [][]byte
+	// a spend from a script address carries the locking script and the script unlocking it instead
+	lockScript   script.Script
+	unlockScript script.Script
+	// the transaction cannot be mined before the block height or unix time of the lock time
+	locktime int64
+	// a contract deployment carries the code of the contract, and a call the arguments to it;
+	// both carry the most gas they may use
+	code     vm.Code
+	input    [][]byte
+	gasLimit uint64
+	// a token transaction moves the amount in the token with the symbol instead of in coins
+	token       string
+	tokenAction string
+	mintable    bool
 }
 
 // NewTransaction() takes a sender, recipient, and amount and returns a pointer to a new transaction.
@@ -21,53 +52,540 @@ func NewTransaction(sender, recipient string, amount float32) *Transaction {
 	}
 }
 
+// NewSignedTransaction() returns a pointer to a new transaction paying the fee to the miner
+// and carrying the sender's public key and signature.
+func NewSignedTransaction(
+	sender, recipient string, amount, fee float32, senderPublicKey keys.Verifier, signature []byte,
+) *Transaction {
+	t := NewTransaction(sender, recipient, amount)
+	t.fee = fee
+	t.senderPublicKey = senderPublicKey
+	t.signature = signature
+	return t
+}
+
+// NewMultisigTransaction() returns a pointer to a new transaction spending from the address of the
+// multisig policy, with the co-signers' signatures in the order of the policy's keys.
+func NewMultisigTransaction(
+	sender, recipient string, amount, fee float32, policy *multisig.Policy, signatures [][]byte,
+) *Transaction {
+	t := NewTransaction(sender, recipient, amount)
+	t.fee = fee
+	t.multisig = policy
+	t.signatures = signatures
+	return t
+}
+
+// NewScriptTransaction() returns a pointer to a new transaction spending from the address of the
+// locking script, authorized by running the unlocking script before it.
+func NewScriptTransaction(
+	sender, recipient string, amount, fee float32, lockScript, unlockScript script.Script,
+) *Transaction {
+	t := NewTransaction(sender, recipient, amount)
+	t.fee = fee
+	t.lockScript = lockScript
+	t.unlockScript = unlockScript
+	return t
+}
+
+func (t *Transaction) GetSenderAddress() string {
+	return t.senderAddress
+}
+
+func (t *Transaction) GetRecipientAddress() string {
+	return t.recipientAddress
+}
+
+func (t *Transaction) GetAmount() float32 {
+	return t.amount
+}
+
+func (t *Transaction) GetFee() float32 {
+	return t.fee
+}
+
+// Value() returns the coins the transaction sends to the recipient: its amount, or nothing for a
+// token transaction, whose amount is in the token.
+func (t *Transaction) Value() float32 {
+	if t.token != "" {
+		return 0
+	}
+	return t.amount
+}
+
+// Cost() returns the coins the transaction takes from the sender: its value and the fee.
+func (t *Transaction) Cost() float32 {
+	return t.Value() + t.fee
+}
+
+// hasValidAmounts() returns whether the amount and the fee of the transaction are finite and not negative,
+// and whether a transfer of coins moves a positive amount. Contract transactions move no coins, and token
+// transactions are checked against the token.
+func (t *Transaction) hasValidAmounts() bool {
+	for _, x := range []float32{t.amount, t.fee} {
+		if x < 0 || math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return false
+		}
+	}
+	return t.amount > 0 || t.IsContract() || t.token != ""
+}
+
+func (t *Transaction) GetNonce() uint64 {
+	return t.nonce
+}
+
+// SetNonce() sets the number of transactions the sender sent before this one. It must be set
+// before the transaction is signed.
+func (t *Transaction) SetNonce(nonce uint64) {
+	t.nonce = nonce
+}
+
+func (t *Transaction) GetSenderPublicKey() keys.Verifier {
+	return t.senderPublicKey
+}
+
+func (t *Transaction) GetSignature() []byte {
+	return t.signature
+}
+
+func (t *Transaction) GetMultisig() *multisig.Policy {
+	return t.multisig
+}
+
+func (t *Transaction) GetSignatures() [][]byte {
+	return t.signatures
+}
+
+func (t *Transaction) GetLocktime() int64 {
+	return t.locktime
+}
+
+// SetLocktime() sets the block height, or the unix time from script.LOCKTIME_THRESHOLD on, before
+// which the transaction cannot be mined. It must be set before the transaction is signed.
+func (t *Transaction) SetLocktime(locktime int64) {
+	t.locktime = locktime
+}
+
+// IsFinal() returns whether the transaction may be included in the block at the height, whose
+// previous block was mined at the unix time.
+func (t *Transaction) IsFinal(height int, time int64) bool {
+	switch {
+	case t.locktime == 0:
+		return true
+	case t.locktime < script.LOCKTIME_THRESHOLD:
+		return int64(height) >= t.locktime
+	default:
+		return time >= t.locktime
+	}
+}
+
+func (t *Transaction) GetCode() vm.Code {
+	return t.code
+}
+
+func (t *Transaction) GetInput() [][]byte {
+	return t.input
+}
+
+func (t *Transaction) GetGasLimit() uint64 {
+	return t.gasLimit
+}
+
+// SetContract() makes the transaction deploy the code, or call the contract it is sent to with the
+// input if the code is empty, using at most gasLimit gas. It must be set before the transaction is signed.
+func (t *Transaction) SetContract(code vm.Code, input [][]byte, gasLimit uint64) {
+	t.code = code
+	t.input = input
+	t.gasLimit = gasLimit
+}
+
+// IsContract() returns whether the transaction deploys or calls a contract.
+func (t *Transaction) IsContract() bool {
+	return len(t.code) > 0 || t.gasLimit > 0
+}
+
+// IsDeploy() returns whether the transaction deploys a contract.
+func (t *Transaction) IsDeploy() bool {
+	return len(t.code) > 0
+}
+
+func (t *Transaction) GetToken() string {
+	return t.token
+}
+
+func (t *Transaction) GetTokenAction() string {
+	return t.tokenAction
+}
+
+func (t *Transaction) IsMintable() bool {
+	return t.mintable
+}
+
+// SetToken() makes the transaction issue, mint, transfer or burn the amount of the token with the
+// symbol; mintable sets whether an issued token can be minted later. It must be set before the
+// transaction is signed.
+func (t *Transaction) SetToken(symbol, action string, mintable bool) {
+	t.token = symbol
+	t.tokenAction = action
+	t.mintable = mintable
+}
+
+func (t *Transaction) GetLockScript() script.Script {
+	return t.lockScript
+}
+
+func (t *Transaction) GetUnlockScript() script.Script {
+	return t.unlockScript
+}
+
+// SigningPayload() returns the bytes covered by the sender's signature. The fee, nonce, lock time,
+// contract and token fields are left out when they are zero, so transactions signed before they
+// existed stay valid.
+func (t *Transaction) SigningPayload() ([]byte, error) {
+	return json.Marshal(struct {
+		SenderAddress    string   `json:"sender_address"`
+		RecipientAddress string   `json:"recipient_address"`
+		Amount           float32  `json:"amount"`
+		Fee              float32  `json:"fee,omitempty"`
+		Nonce            uint64   `json:"nonce,omitempty"`
+		Locktime         int64    `json:"locktime,omitempty"`
+		Code             vm.Code  `json:"code,omitempty"`
+		Input            []string `json:"input,omitempty"`
+		GasLimit         uint64   `json:"gas_limit,omitempty"`
+		Token            string   `json:"token,omitempty"`
+		TokenAction      string   `json:"token_action,omitempty"`
+		Mintable         bool     `json:"mintable,omitempty"`
+	}{
+		SenderAddress:    t.senderAddress,
+		RecipientAddress: t.recipientAddress,
+		Amount:           t.amount,
+		Fee:              t.fee,
+		Nonce:            t.nonce,
+		Locktime:         t.locktime,
+		Code:             t.code,
+		Input:            EncodeInput(t.input),
+		GasLimit:         t.gasLimit,
+		Token:            t.token,
+		TokenAction:      t.tokenAction,
+		Mintable:         t.mintable,
+	})
+}
+
+// ID() returns the identifier of the transaction: the hex encoded hash of its canonical encoding,
+// the json representation blocks are hashed with.
+func (t *Transaction) ID() string {
+	m, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(m))
+}
+
 // ToString() returns a developer-friendly string representation of the transaction.
 func (t *Transaction) ToString() string {
 	return fmt.Sprintf(
-		"Sender: %s, Recipient: %s, Amount: %f",
-		t.senderAddress, t.recipientAddress, t.amount,
+		"Sender: %s, Recipient: %s, Amount: %f, Fee: %f",
+		t.senderAddress, t.recipientAddress, t.amount, t.fee,
 	)
 }
 
 // MarshalJSON() returns a json representation of the transaction.
 func (t *Transaction) MarshalJSON() ([]byte, error) {
+	var scheme, publicKey string
+	if t.senderPublicKey != nil {
+		scheme = string(t.senderPublicKey.Scheme())
+		publicKey = keys.ToString(t.senderPublicKey)
+	}
+
 	return json.Marshal(struct {
-		SenderAddress    string  `json:"sender_address"`
-		RecipientAddress string  `json:"recipient_address"`
-		Amount           float32 `json:"amount"`
+		SenderAddress    string           `json:"sender_address"`
+		RecipientAddress string           `json:"recipient_address"`
+		Amount           float32          `json:"amount"`
+		Fee              float32          `json:"fee,omitempty"`
+		Nonce            uint64           `json:"nonce,omitempty"`
+		Locktime         int64            `json:"locktime,omitempty"`
+		SignatureScheme  string           `json:"signature_scheme,omitempty"`
+		SenderPublicKey  string           `json:"sender_public_key,omitempty"`
+		Signature        string           `json:"signature,omitempty"`
+		Multisig         *multisig.Policy `json:"multisig,omitempty"`
+		Signatures       []string         `json:"signatures,omitempty"`
+		LockScript       script.Script    `json:"lock_script,omitempty"`
+		UnlockScript     script.Script    `json:"unlock_script,omitempty"`
+		Code             vm.Code          `json:"code,omitempty"`
+		Input            []string         `json:"input,omitempty"`
+		GasLimit         uint64           `json:"gas_limit,omitempty"`
+		Token            string           `json:"token,omitempty"`
+		TokenAction      string           `json:"token_action,omitempty"`
+		Mintable         bool             `json:"mintable,omitempty"`
 	}{
 		SenderAddress:    t.senderAddress,
 		RecipientAddress: t.recipientAddress,
 		Amount:           t.amount,
+		Fee:              t.fee,
+		Nonce:            t.nonce,
+		Locktime:         t.locktime,
+		SignatureScheme:  scheme,
+		SenderPublicKey:  publicKey,
+		Signature:        hex.EncodeToString(t.signature),
+		Multisig:         t.multisig,
+		Signatures:       EncodeSignatures(t.signatures),
+		LockScript:       t.lockScript,
+		UnlockScript:     t.unlockScript,
+		Code:             t.code,
+		Input:            EncodeInput(t.input),
+		GasLimit:         t.gasLimit,
+		Token:            t.token,
+		TokenAction:      t.tokenAction,
+		Mintable:         t.mintable,
 	})
 }
 
 func (t *Transaction) UnmarshalJSON(data []byte) error {
+	var schemeName, publicKey, signature string
+	var signatures, input []string
+
 	tmp := &struct {
-		SenderAddress    *string  `json:"sender_address"`
-		RecipientAddress *string  `json:"recipient_address"`
-		Amount           *float32 `json:"amount"`
+		SenderAddress    *string           `json:"sender_address"`
+		RecipientAddress *string           `json:"recipient_address"`
+		Amount           *float32          `json:"amount"`
+		Fee              *float32          `json:"fee"`
+		Nonce            *uint64           `json:"nonce"`
+		Locktime         *int64            `json:"locktime"`
+		SignatureScheme  *string           `json:"signature_scheme"`
+		SenderPublicKey  *string           `json:"sender_public_key"`
+		Signature        *string           `json:"signature"`
+		Multisig         **multisig.Policy `json:"multisig"`
+		Signatures       *[]string         `json:"signatures"`
+		LockScript       *script.Script    `json:"lock_script"`
+		UnlockScript     *script.Script    `json:"unlock_script"`
+		Code             *vm.Code          `json:"code"`
+		Input            *[]string         `json:"input"`
+		GasLimit         *uint64           `json:"gas_limit"`
+		Token            *string           `json:"token"`
+		TokenAction      *string           `json:"token_action"`
+		Mintable         *bool             `json:"mintable"`
 	}{
 		SenderAddress:    &t.senderAddress,
 		RecipientAddress: &t.recipientAddress,
 		Amount:           &t.amount,
+		Fee:              &t.fee,
+		Nonce:            &t.nonce,
+		Locktime:         &t.locktime,
+		SignatureScheme:  &schemeName,
+		SenderPublicKey:  &publicKey,
+		Signature:        &signature,
+		Multisig:         &t.multisig,
+		Signatures:       &signatures,
+		LockScript:       &t.lockScript,
+		UnlockScript:     &t.unlockScript,
+		Code:             &t.code,
+		Input:            &input,
+		GasLimit:         &t.gasLimit,
+		Token:            &t.token,
+		TokenAction:      &t.tokenAction,
+		Mintable:         &t.mintable,
 	}
 
 	if err := json.Unmarshal(data, tmp); err != nil {
 		return err
 	}
 
+	if publicKey != "" {
+		scheme, err := keys.ParseScheme(schemeName)
+		if err != nil {
+			return err
+		}
+		pk, err := keys.PublicKeyFromString(scheme, publicKey)
+		if err != nil {
+			return err
+		}
+		t.senderPublicKey = pk
+	}
+	if signature != "" {
+		sig, err := hex.DecodeString(signature)
+		if err != nil {
+			return err
+		}
+		t.signature = sig
+	}
+	if signatures != nil {
+		sigs, err := DecodeSignatures(signatures)
+		if err != nil {
+			return err
+		}
+		t.signatures = sigs
+	}
+	args, err := DecodeInput(input)
+	if err != nil {
+		return err
+	}
+	t.input = args
+
 	return nil
 }
 
+// EncodeSignatures() hex encodes the signature slots of a multisig spend, leaving empty slots empty.
+func EncodeSignatures(signatures [][]byte) []string {
+	if signatures == nil {
+		return nil
+	}
+	res := make([]string, len(signatures))
+	for i, sig := range signatures {
+		res[i] = hex.EncodeToString(sig)
+	}
+	return res
+}
+
+// DecodeSignatures() decodes hex encoded signature slots, where an empty string is a missing signature.
+func DecodeSignatures(signatures []string) ([][]byte, error) {
+	res := make([][]byte, len(signatures))
+	for i, sig := range signatures {
+		if sig == "" {
+			continue
+		}
+		b, err := hex.DecodeString(sig)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = b
+	}
+	return res, nil
+}
+
+// EncodeInput() hex encodes the arguments of a contract call.
+func EncodeInput(input [][]byte) []string {
+	if input == nil {
+		return nil
+	}
+	res := make([]string, len(input))
+	for i, arg := range input {
+		res[i] = hex.EncodeToString(arg)
+	}
+	return res
+}
+
+// DecodeInput() decodes hex encoded arguments of a contract call.
+func DecodeInput(input []string) ([][]byte, error) {
+	if input == nil {
+		return nil, nil
+	}
+	res := make([][]byte, len(input))
+	for i, arg := range input {
+		b, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = b
+	}
+	return res, nil
+}
+
 type TransactionRequest struct {
-	SenderAddress    *string  `json:"sender_address"`
-	RecipientAddress *string  `json:"recipient_address"`
-	Amount           *float32 `json:"amount"`
-	SenderPublicKey  *string  `json:"sender_public_key"`
-	Signature        *string  `json:"signature"`
+	SenderAddress    *string          `json:"sender_address"`
+	RecipientAddress *string          `json:"recipient_address"`
+	Amount           *float32         `json:"amount"`
+	Fee              *float32         `json:"fee,omitempty"`
+	Nonce            *uint64          `json:"nonce,omitempty"`
+	Locktime         *int64           `json:"locktime,omitempty"`
+	SenderPublicKey  *string          `json:"sender_public_key,omitempty"`
+	Signature        *string          `json:"signature,omitempty"`
+	SignatureScheme  *string          `json:"signature_scheme,omitempty"`
+	Multisig         *multisig.Policy `json:"multisig,omitempty"`
+	Signatures       []string         `json:"signatures,omitempty"`
+	LockScript       script.Script    `json:"lock_script,omitempty"`
+	UnlockScript     script.Script    `json:"unlock_script,omitempty"`
+	Code             vm.Code          `json:"code,omitempty"`
+	Input            []string         `json:"input,omitempty"`
+	GasLimit         *uint64          `json:"gas_limit,omitempty"`
+	Token            string           `json:"token,omitempty"`
+	TokenAction      string           `json:"token_action,omitempty"`
+	Mintable         bool             `json:"mintable,omitempty"`
+}
+
+// GetFee() returns the fee of the request, which is zero when none was given.
+func (tr *TransactionRequest) GetFee() float32 {
+	if tr.Fee == nil {
+		return 0
+	}
+	return *tr.Fee
 }
 
+// GetNonce() returns the nonce of the request, which is zero when none was given.
+func (tr *TransactionRequest) GetNonce() uint64 {
+	if tr.Nonce == nil {
+		return 0
+	}
+	return *tr.Nonce
+}
+
+// GetLocktime() returns the lock time of the request, which is zero when none was given.
+func (tr *TransactionRequest) GetLocktime() int64 {
+	if tr.Locktime == nil {
+		return 0
+	}
+	return *tr.Locktime
+}
+
+// GetGasLimit() returns the gas limit of the request, which is zero when none was given.
+func (tr *TransactionRequest) GetGasLimit() uint64 {
+	if tr.GasLimit == nil {
+		return 0
+	}
+	return *tr.GasLimit
+}
+
+// IsValid() returns whether the request names a sender, recipient and amount and is signed
+// by a single key or by the co-signers of a multisig policy, or unlocks a locking script.
 func (tr *TransactionRequest) IsValid() bool {
-	return tr.SenderAddress != nil && tr.RecipientAddress != nil && tr.Amount != nil && tr.SenderPublicKey != nil && tr.Signature != nil
+	if tr.SenderAddress == nil || tr.RecipientAddress == nil || tr.Amount == nil {
+		return false
+	}
+	if tr.Multisig != nil {
+		return tr.Signatures != nil
+	}
+	if tr.LockScript != nil {
+		return tr.UnlockScript != nil
+	}
+	return tr.SenderPublicKey != nil && tr.Signature != nil
+}
+
+// NewTransactionRequest() returns the request a peer adds the transaction to its pool with.
+func NewTransactionRequest(t *Transaction) *TransactionRequest {
+	tr := &TransactionRequest{
+		SenderAddress:    &t.senderAddress,
+		RecipientAddress: &t.recipientAddress,
+		Amount:           &t.amount,
+		Fee:              &t.fee,
+	}
+	if t.nonce != 0 {
+		tr.Nonce = &t.nonce
+	}
+	if t.locktime != 0 {
+		tr.Locktime = &t.locktime
+	}
+	if t.IsContract() {
+		tr.Code = t.code
+		tr.Input = EncodeInput(t.input)
+		tr.GasLimit = &t.gasLimit
+	}
+	tr.Token = t.token
+	tr.TokenAction = t.tokenAction
+	tr.Mintable = t.mintable
+	if t.multisig != nil {
+		tr.Multisig = t.multisig
+		tr.Signatures = EncodeSignatures(t.signatures)
+		return tr
+	}
+	if t.lockScript != nil {
+		tr.LockScript = t.lockScript
+		tr.UnlockScript = t.unlockScript
+		return tr
+	}
+	publicKey := keys.ToString(t.senderPublicKey)
+	signature := hex.EncodeToString(t.signature)
+	scheme := string(t.senderPublicKey.Scheme())
+	tr.SenderPublicKey = &publicKey
+	tr.Signature = &signature
+	tr.SignatureScheme = &scheme
+	return tr
 }
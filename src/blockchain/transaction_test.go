@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+// appendBlock() returns the chain extended by a block of the transactions, sealed without checking them,
+// as a dishonest miner would.
+func appendBlock(t *testing.T, chain []*Block, transactions []*Transaction) []*Block {
+	t.Helper()
+	parent := chain[len(chain)-1]
+	block := NewBlock(0, parent.Hash(), transactions)
+	block.stateRoot = parent.stateRoot
+	if err := NewProofOfWork(1).Seal(chain, block); err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]*Block{}, chain...), block)
+}
+
+func TestTransactionReplay(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	k := generateKey(t)
+	sender := address.FromPublicKey(k.Public(), address.TestNet)
+	recipient := address.FromPublicKey(generateKey(t).Public(), address.TestNet)
+	fund(t, bc, k)
+
+	pay(t, bc, k, recipient, 0.1)
+	paid := bc.pool[0]
+	mine(t, bc)
+	if n := bc.NextNonce(sender); n != 1 {
+		t.Fatalf("NextNonce() = %d, want 1", n)
+	}
+
+	if bc.PoolTransaction(paid) {
+		t.Error("confirmed payment replayed into the pool")
+	}
+	if bc.IsValidChain(appendBlock(t, bc.chain, []*Transaction{paid})) {
+		t.Error("chain replaying a confirmed payment accepted")
+	}
+
+	renumbered := *paid
+	renumbered.SetNonce(1)
+	if bc.PoolTransaction(&renumbered) {
+		t.Error("payment accepted with a nonce its signature does not cover")
+	}
+
+	if bc.PoolTransaction(payment(t, bc, k, recipient, 0.1, 2)) {
+		t.Error("payment skipping a nonce accepted")
+	}
+
+	reward := bc.chain[2].transactions[len(bc.chain[2].transactions)-1]
+	if reward.senderAddress != MINING_SENDER || reward.nonce != 2 {
+		t.Fatalf("reward of block 2 = %s with nonce %d, want the height as its nonce", reward.senderAddress, reward.nonce)
+	}
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+}
+
+func TestPoolAdmission(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	k, other := generateKey(t), generateKey(t)
+	sender := address.FromPublicKey(k.Public(), address.TestNet)
+	recipient := address.FromPublicKey(other.Public(), address.TestNet)
+	fund(t, bc, k)
+	fund(t, bc, other)
+
+	// amounts json cannot encode cannot be signed either, and must be rejected before the signature is checked
+	signed := func(amount, fee float32) *Transaction {
+		tx := NewSignedTransaction(sender, recipient, amount, fee, k.Public(), nil)
+		if m, err := tx.SigningPayload(); err == nil {
+			if tx.signature, err = k.Sign(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return tx
+	}
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	for _, tt := range []struct {
+		name        string
+		amount, fee float32
+	}{
+		{"a negative amount pulling coins from the recipient", -0.5, 0},
+		{"an amount that is not a number", nan, 0},
+		{"an infinite amount", inf, 0},
+		{"a negative fee", 0.1, -0.1},
+		{"a fee that is not a number", 0.1, nan},
+		{"an infinite fee", 0.1, inf},
+		{"more than the balance", MINING_REWARD, 0.1},
+	} {
+		if bc.PoolTransaction(signed(tt.amount, tt.fee)) {
+			t.Errorf("payment of %s accepted", tt.name)
+		}
+	}
+	if bc.IsValidChain(appendBlock(t, bc.chain, []*Transaction{signed(-0.5, 0)})) {
+		t.Error("chain with a negative payment accepted")
+	}
+
+	// pooled spends leave less to spend, and pooled incoming funds are not spendable until confirmed
+	pay(t, bc, k, recipient, 0.75)
+	pay(t, bc, other, sender, 0.5)
+	if spendable := bc.Balance(sender, DEFAULT_MIN_CONFIRMATIONS).Spendable; spendable != MINING_REWARD-0.75 {
+		t.Errorf("spendable = %f, want %f", spendable, MINING_REWARD-0.75)
+	}
+	if bc.PoolTransaction(payment(t, bc, k, recipient, 0.5, bc.NextNonce(sender))) {
+		t.Error("payment of more than the spendable balance accepted")
+	}
+	mine(t, bc)
+	pay(t, bc, k, recipient, 0.5)
+	if !bc.IsValidChain(bc.chain) {
+		t.Error("chain is not valid")
+	}
+}
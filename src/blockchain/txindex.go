@@ -0,0 +1,102 @@
+package blockchain
+
+import "sync"
+
+// TxLocation is where a transaction was confirmed.
+type TxLocation struct {
+	Height   int `json:"height"`
+	Position int `json:"position"`
+}
+
+// TxIndex maps transaction IDs to the block that confirmed them.
+// IDs are unique within a valid chain: every transaction carries the nonce of its sender, and a
+// mining reward the height of its block.
+type TxIndex struct {
+	mux       sync.RWMutex
+	locations map[string]TxLocation
+}
+
+func NewTxIndex() *TxIndex {
+	return &TxIndex{locations: make(map[string]TxLocation)}
+}
+
+// Get() returns the location of the transaction with the ID and whether it was indexed.
+func (idx *TxIndex) Get(id string) (TxLocation, bool) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	loc, ok := idx.locations[id]
+	return loc, ok
+}
+
+// Len() returns the number of indexed transactions.
+func (idx *TxIndex) Len() int {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	return len(idx.locations)
+}
+
+// connect() indexes the transactions of the block at the height.
+func (idx *TxIndex) connect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	for i, t := range block.transactions {
+		idx.locations[t.ID()] = TxLocation{height, i}
+	}
+}
+
+// disconnect() drops the transactions of the block at the height from the index.
+func (idx *TxIndex) disconnect(block *Block, height int) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	for _, t := range block.transactions {
+		id := t.ID()
+		if loc, ok := idx.locations[id]; ok && loc.Height == height {
+			delete(idx.locations, id)
+		}
+	}
+}
+
+// block() returns the indexed locations of the transactions of the block, for persisting.
+func (idx *TxIndex) block(block *Block) map[string]TxLocation {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	res := make(map[string]TxLocation, len(block.transactions))
+	for _, t := range block.transactions {
+		id := t.ID()
+		if loc, ok := idx.locations[id]; ok {
+			res[id] = loc
+		}
+	}
+	return res
+}
+
+// EnableTxIndex() maintains a transaction index from now on. It is loaded from the store when it
+// was saved for every block of the chain, and built from the chain otherwise.
+func (bc *Blockchain) EnableTxIndex() error {
+	idx := NewTxIndex()
+	if bc.store != nil {
+		locations, ok, err := bc.store.LoadTxIndex(bc.chain)
+		if err != nil {
+			return err
+		}
+		if ok {
+			idx.locations = locations
+			bc.txIndex = idx
+			return nil
+		}
+	}
+
+	for height, block := range bc.chain {
+		idx.connect(block, height)
+	}
+	bc.txIndex = idx
+	if bc.store != nil {
+		return bc.store.SaveTxIndex(bc.chain, 0, idx)
+	}
+	return nil
+}
+
+// TxIndex() returns the transaction index, or nil if it is not enabled.
+func (bc *Blockchain) TxIndex() *TxIndex {
+	return bc.txIndex
+}
@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+func TestTxIndex(t *testing.T) {
+	bc := newTestChain(t, address.TestNet)
+	k := generateKey(t)
+	recipient := address.FromPublicKey(generateKey(t).Public(), address.TestNet)
+	fund(t, bc, k)
+	// the same payment and the same reward in every block only differ by their nonces
+	for i := 0; i < 3; i++ {
+		pay(t, bc, k, recipient, 0.1)
+		mine(t, bc)
+	}
+
+	idx := NewTxIndex()
+	count := 0
+	for height, block := range bc.chain {
+		idx.connect(block, height)
+		count += len(block.transactions)
+	}
+	if n := idx.Len(); n != count {
+		t.Fatalf("Len() = %d, want %d", n, count)
+	}
+	for height, block := range bc.chain {
+		for i, tx := range block.transactions {
+			if loc, ok := idx.Get(tx.ID()); !ok || loc != (TxLocation{height, i}) {
+				t.Errorf("Get() of transaction %d of block %d = %v, %v", i, height, loc, ok)
+			}
+		}
+	}
+
+	tip := len(bc.chain) - 1
+	idx.disconnect(bc.chain[tip], tip)
+	for height, block := range bc.chain {
+		for _, tx := range block.transactions {
+			if _, ok := idx.Get(tx.ID()); ok != (height < tip) {
+				t.Errorf("transaction of block %d indexed = %v after disconnecting block %d", height, ok, tip)
+			}
+		}
+	}
+	if n := idx.Len(); n != count-len(bc.chain[tip].transactions) {
+		t.Errorf("Len() after disconnecting block %d = %d, want %d", tip, n, count-len(bc.chain[tip].transactions))
+	}
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+)
+
+// Client is a client for the blockchain server API.
+type Client struct {
+	transport
+}
+
+// New() returns a client for the blockchain server at baseURL, such as http://localhost:3000.
+func New(baseURL string) *Client {
+	return NewWithConfig(baseURL, DefaultConfig())
+}
+
+// NewWithConfig() returns a client for the blockchain server at baseURL with custom timeouts and retries.
+func NewWithConfig(baseURL string, config Config) *Client {
+	return &Client{newTransport(baseURL, config)}
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Chain() returns every block of the node's chain.
+func (c *Client) Chain(ctx context.Context) ([]*blockchain.Block, error) {
+	var bc blockchain.Blockchain
+	if err := c.do(ctx, http.MethodGet, "/", nil, &bc, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return bc.GetChain(), nil
+}
+
+// Info() returns a summary of the node's chain.
+func (c *Client) Info(ctx context.Context) (*blockchain.ChainInfo, error) {
+	var info blockchain.ChainInfo
+	if err := c.do(ctx, http.MethodGet, "/info", nil, &info, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Tip() returns a summary of the last block of the node's chain.
+func (c *Client) Tip(ctx context.Context) (*blockchain.ChainTip, error) {
+	var tip blockchain.ChainTip
+	if err := c.do(ctx, http.MethodGet, "/tip", nil, &tip, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &tip, nil
+}
+
+// Block() returns the block at the height.
+func (c *Client) Block(ctx context.Context, height int) (*blockchain.BlockView, error) {
+	var block blockchain.BlockView
+	if err := c.do(ctx, http.MethodGet, "/block?height="+strconv.Itoa(height), nil, &block, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// BlockByHash() returns the block with the hex encoded hash.
+func (c *Client) BlockByHash(ctx context.Context, hash string) (*blockchain.BlockView, error) {
+	var block blockchain.BlockView
+	if err := c.do(ctx, http.MethodGet, "/block?hash="+url.QueryEscape(hash), nil, &block, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Blocks() returns a page of block summaries starting at the cursor, newest first unless ascending
+// is set. An empty cursor starts at the tip, or at genesis when ascending; a limit of 0 uses the
+// node's default. The NextCursor of the page gets the following one.
+func (c *Client) Blocks(ctx context.Context, cursor string, limit int, ascending bool) (*blockchain.BlockPage, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if ascending {
+		query.Set("order", "asc")
+	}
+
+	var page blockchain.BlockPage
+	if err := c.do(ctx, http.MethodGet, "/blocks?"+query.Encode(), nil, &page, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Transaction() returns the pending or confirmed transaction with the ID.
+func (c *Client) Transaction(ctx context.Context, id string) (*blockchain.TransactionView, error) {
+	var t blockchain.TransactionView
+	if err := c.do(ctx, http.MethodGet, "/transaction?id="+url.QueryEscape(id), nil, &t, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Mempool() returns the transactions waiting to be mined.
+func (c *Client) Mempool(ctx context.Context) ([]*blockchain.TransactionView, error) {
+	var pool blockchain.TransactionsResponse
+	if err := c.do(ctx, http.MethodGet, "/transactions", nil, &pool, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return pool.Transactions, nil
+}
+
+// ClearMempool() drops every transaction waiting to be mined.
+func (c *Client) ClearMempool(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/transactions", nil, nil, http.StatusOK)
+}
+
+// SendTransaction() submits a signed transaction, which the node relays to its peers, and returns its ID.
+// It is never retried, since the node may have accepted a request whose response was lost;
+// resubmitting the same transaction is harmless, as it is only pooled once.
+func (c *Client) SendTransaction(ctx context.Context, tr *blockchain.TransactionRequest) (string, error) {
+	var res blockchain.SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/transactions", tr, &res, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// RelayTransaction() adds a transaction received from a peer to the node's pool without relaying it further.
+func (c *Client) RelayTransaction(ctx context.Context, tr *blockchain.TransactionRequest) error {
+	return c.do(ctx, http.MethodPut, "/transactions", tr, nil, http.StatusOK)
+}
+
+// Contract() returns the code and storage of the contract at the address.
+func (c *Client) Contract(ctx context.Context, contractAddress string) (*blockchain.ContractView, error) {
+	var contract blockchain.ContractView
+	if err := c.do(ctx, http.MethodGet, "/contract?address="+url.QueryEscape(contractAddress), nil, &contract, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &contract, nil
+}
+
+// CallContract() runs a contract without changing its state and returns what it returned.
+func (c *Client) CallContract(ctx context.Context, call *blockchain.CallRequest) (*blockchain.CallResponse, error) {
+	var res blockchain.CallResponse
+	if err := c.do(ctx, http.MethodPost, "/contract/call", call, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Receipt() returns the outcome of the confirmed contract transaction with the ID.
+func (c *Client) Receipt(ctx context.Context, id string) (*blockchain.Receipt, error) {
+	var r blockchain.Receipt
+	if err := c.do(ctx, http.MethodGet, "/receipt?id="+url.QueryEscape(id), nil, &r, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Tokens() returns the tokens issued on the chain.
+func (c *Client) Tokens(ctx context.Context) ([]*blockchain.TokenView, error) {
+	var res blockchain.TokensResponse
+	if err := c.do(ctx, http.MethodGet, "/tokens", nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return res.Tokens, nil
+}
+
+// Token() returns the issuer and supply of the token with the symbol.
+func (c *Client) Token(ctx context.Context, symbol string) (*blockchain.TokenView, error) {
+	var tk blockchain.TokenView
+	if err := c.do(ctx, http.MethodGet, "/token?symbol="+url.QueryEscape(symbol), nil, &tk, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &tk, nil
+}
+
+// TokenBalance() returns the confirmed, unconfirmed and spendable amounts of the address in the token.
+func (c *Client) TokenBalance(ctx context.Context, bcAddress, symbol string) (*blockchain.TokenBalance, error) {
+	query := url.Values{
+		"address": {bcAddress},
+		"token":   {symbol},
+	}
+	var balance blockchain.TokenBalance
+	if err := c.do(ctx, http.MethodGet, "/token/balance?"+query.Encode(), nil, &balance, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// Peers() returns the neighbors the node relays to.
+func (c *Client) Peers(ctx context.Context) ([]string, error) {
+	var peers blockchain.PeersResponse
+	if err := c.do(ctx, http.MethodGet, "/peers", nil, &peers, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return peers.Peers, nil
+}
+
+// MiningInfo() returns the state of the node's block production.
+func (c *Client) MiningInfo(ctx context.Context) (*blockchain.MiningInfo, error) {
+	var info blockchain.MiningInfo
+	if err := c.do(ctx, http.MethodGet, "/mining", nil, &info, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Mine() mines a block from the current transaction pool.
+func (c *Client) Mine(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/mine", nil, nil, http.StatusOK)
+}
+
+// StartMining() makes the node mine periodically.
+func (c *Client) StartMining(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/mine/start", nil, nil, http.StatusOK)
+}
+
+// ResolveConflicts() makes the node adopt the longest valid chain among its peers.
+func (c *Client) ResolveConflicts(ctx context.Context) error {
+	return c.do(ctx, http.MethodPut, "/consensus", nil, nil, http.StatusOK)
+}
+
+// Balance() returns the confirmed, unconfirmed and spendable amounts of the address, with funds
+// counting as confirmed from minConfirmations confirmations.
+func (c *Client) Balance(ctx context.Context, bcAddress string, minConfirmations int) (*blockchain.AmountResponse, error) {
+	query := url.Values{
+		"blockchain_address": {bcAddress},
+		"min_confirmations":  {strconv.Itoa(minConfirmations)},
+	}
+	var amount blockchain.AmountResponse
+	if err := c.do(ctx, http.MethodGet, "/amount?"+query.Encode(), nil, &amount, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// Address() returns the confirmed balance and the received and sent totals of the address.
+func (c *Client) Address(ctx context.Context, bcAddress string) (*blockchain.AddressSummary, error) {
+	var summary blockchain.AddressSummary
+	if err := c.do(ctx, http.MethodGet, "/address?address="+url.QueryEscape(bcAddress), nil, &summary, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// AddressHistory() returns a page of the confirmed transactions of the address, newest first.
+// An empty cursor starts at the newest one; a limit of 0 uses the node's default.
+func (c *Client) AddressHistory(ctx context.Context, bcAddress, cursor string, limit int) (*blockchain.AddressHistory, error) {
+	query := url.Values{"address": {bcAddress}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var page blockchain.AddressHistory
+	if err := c.do(ctx, http.MethodGet, "/address/transactions?"+query.Encode(), nil, &page, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
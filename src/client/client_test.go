@@ -0,0 +1,383 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+)
+
+// testConfig retries quickly so the tests of retries do not wait.
+var testConfig = Config{Timeout: 5 * time.Second, Retries: 2, RetryBackoff: time.Millisecond}
+
+// newTestServer() returns a client for a server answering every request with handler, and a
+// counter of the requests it received.
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*Client, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewWithConfig(srv.URL+"/", testConfig), &requests
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestSuccessfulCalls(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		status int
+		body   interface{}
+		call   func(c *Client) (interface{}, error)
+		want   interface{}
+	}{
+		{
+			name:   "tip",
+			method: http.MethodGet,
+			uri:    "/tip",
+			status: http.StatusOK,
+			body:   blockchain.ChainTip{Height: 7, Hash: "00ab", Difficulty: 3},
+			call:   func(c *Client) (interface{}, error) { return c.Tip(context.Background()) },
+			want:   &blockchain.ChainTip{Height: 7, Hash: "00ab", Difficulty: 3},
+		},
+		{
+			name:   "blocks",
+			method: http.MethodGet,
+			uri:    "/blocks?cursor=5&limit=2&order=asc",
+			status: http.StatusOK,
+			body:   blockchain.BlockPage{NextCursor: "7"},
+			call: func(c *Client) (interface{}, error) {
+				return c.Blocks(context.Background(), "5", 2, true)
+			},
+			want: &blockchain.BlockPage{NextCursor: "7"},
+		},
+		{
+			name:   "address history",
+			method: http.MethodGet,
+			uri:    "/address/transactions?address=1abc&cursor=c1",
+			status: http.StatusOK,
+			body:   blockchain.AddressHistory{Address: "1abc"},
+			call: func(c *Client) (interface{}, error) {
+				return c.AddressHistory(context.Background(), "1abc", "c1", 0)
+			},
+			want: &blockchain.AddressHistory{Address: "1abc"},
+		},
+		{
+			name:   "peers",
+			method: http.MethodGet,
+			uri:    "/peers",
+			status: http.StatusOK,
+			body:   blockchain.PeersResponse{Peers: []string{"127.0.0.1:3001"}},
+			call:   func(c *Client) (interface{}, error) { return c.Peers(context.Background()) },
+			want:   []string{"127.0.0.1:3001"},
+		},
+		{
+			name:   "send transaction",
+			method: http.MethodPost,
+			uri:    "/transactions",
+			status: http.StatusCreated,
+			body:   blockchain.SubmitResponse{Message: "Transaction successful", ID: "tx1"},
+			call: func(c *Client) (interface{}, error) {
+				return c.SendTransaction(context.Background(), &blockchain.TransactionRequest{})
+			},
+			want: "tx1",
+		},
+		{
+			name:   "mine",
+			method: http.MethodGet,
+			uri:    "/mine",
+			status: http.StatusOK,
+			body:   map[string]string{"message": "Mining successful"},
+			call:   func(c *Client) (interface{}, error) { return nil, c.Mine(context.Background()) },
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.method || r.URL.RequestURI() != tt.uri {
+					t.Errorf("request %s %s, want %s %s", r.Method, r.URL.RequestURI(), tt.method, tt.uri)
+				}
+				if r.Header.Get("Accept") != "application/json" {
+					t.Errorf("Accept header %q", r.Header.Get("Accept"))
+				}
+				writeJSON(w, tt.status, tt.body)
+			})
+
+			got, err := tt.call(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("got %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	sender, recipient := "1sender", "1recipient"
+	var amount float32 = 1.5
+	c, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type header %q", r.Header.Get("Content-Type"))
+		}
+		var tr blockchain.TransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+			t.Fatal(err)
+		}
+		if *tr.SenderAddress != sender || *tr.RecipientAddress != recipient || *tr.Amount != amount {
+			t.Errorf("request body %+v", tr)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Transaction successful"})
+	})
+
+	err := c.RelayTransaction(context.Background(), &blockchain.TransactionRequest{
+		SenderAddress:    &sender,
+		RecipientAddress: &recipient,
+		Amount:           &amount,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	c, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params"`
+			ID      int64           `json:"id"`
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/rpc" {
+			t.Errorf("request %s %s, want POST /rpc", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.JSONRPC != "2.0" || req.Method != "getBlock" || string(req.Params) != `{"height":3}` || req.ID == 0 {
+			t.Errorf("rpc request %+v", req)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"result":  map[string]interface{}{"height": 3, "hash": "00cd"},
+			"id":      req.ID,
+		})
+	})
+
+	var block blockchain.BlockView
+	if err := c.Call(context.Background(), "getBlock", map[string]int{"height": 3}, &block); err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != 3 || block.Hash != "00cd" {
+		t.Errorf("result %+v", block)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"error":   map[string]interface{}{"code": -32602, "message": "Invalid params"},
+			"id":      1,
+		})
+	})
+
+	var out json.RawMessage
+	err := c.Call(context.Background(), "getBlock", map[string]int{"height": -1}, &out)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("error %v is not an *RPCError", err)
+	}
+	if rpcErr.Code != -32602 || rpcErr.Message != "Invalid params" {
+		t.Errorf("error %+v", rpcErr)
+	}
+	if out != nil {
+		t.Errorf("result %s decoded from an error response", out)
+	}
+	if *requests != 1 {
+		t.Errorf("%d requests for an error object, want 1", *requests)
+	}
+	if StatusCode(err) != 0 {
+		t.Errorf("status code %d of an rpc error", StatusCode(err))
+	}
+}
+
+func TestNon200Responses(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		call     func(c *Client) error
+		message  string
+		requests int32
+	}{
+		{
+			name:   "not found",
+			status: http.StatusNotFound,
+			body:   `{"message":"Block not found"}`,
+			call: func(c *Client) error {
+				_, err := c.Block(context.Background(), 99)
+				return err
+			},
+			message:  "Block not found",
+			requests: 1,
+		},
+		{
+			name:   "bad request without a message",
+			status: http.StatusBadRequest,
+			body:   `not json`,
+			call: func(c *Client) error {
+				_, err := c.Tip(context.Background())
+				return err
+			},
+			requests: 1,
+		},
+		{
+			name:   "unavailable read is retried",
+			status: http.StatusServiceUnavailable,
+			body:   `{"message":"busy"}`,
+			call: func(c *Client) error {
+				_, err := c.Info(context.Background())
+				return err
+			},
+			message:  "busy",
+			requests: int32(testConfig.Retries) + 1,
+		},
+		{
+			name:   "unavailable submit is not retried",
+			status: http.StatusServiceUnavailable,
+			body:   `{"message":"busy"}`,
+			call: func(c *Client) error {
+				_, err := c.SendTransaction(context.Background(), &blockchain.TransactionRequest{})
+				return err
+			},
+			message:  "busy",
+			requests: 1,
+		},
+		{
+			name:   "unavailable rpc write is not retried",
+			status: http.StatusServiceUnavailable,
+			call: func(c *Client) error {
+				return c.Call(context.Background(), "sendTransaction", nil, nil)
+			},
+			requests: 1,
+		},
+		{
+			name:   "unavailable rpc read is retried",
+			status: http.StatusServiceUnavailable,
+			call: func(c *Client) error {
+				return c.Call(context.Background(), "getTip", nil, nil)
+			},
+			requests: int32(testConfig.Retries) + 1,
+		},
+		{
+			name:   "unexpected success status",
+			status: http.StatusOK,
+			body:   `{"message":"ok"}`,
+			call: func(c *Client) error {
+				_, err := c.SendTransaction(context.Background(), &blockchain.TransactionRequest{})
+				return err
+			},
+			message:  "ok",
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+
+			err := tt.call(c)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error %v is not an *Error", err)
+			}
+			if e.StatusCode != tt.status || StatusCode(err) != tt.status {
+				t.Errorf("status %d, want %d", e.StatusCode, tt.status)
+			}
+			if e.Message != tt.message {
+				t.Errorf("message %q, want %q", e.Message, tt.message)
+			}
+			if IsNotFound(err) != (tt.status == http.StatusNotFound) {
+				t.Errorf("IsNotFound() = %v", IsNotFound(err))
+			}
+			if *requests != tt.requests {
+				t.Errorf("%d requests, want %d", *requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnSuccess(t *testing.T) {
+	var failures int32 = 1
+	c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, blockchain.PeersResponse{Peers: []string{}})
+	})
+
+	if _, err := c.Peers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 2 {
+		t.Errorf("%d requests, want 2", *requests)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Tip(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, want %v", err, context.Canceled)
+	}
+	if *requests != 0 {
+		t.Errorf("%d requests with a canceled context", *requests)
+	}
+}
+
+func TestWalletClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.RequestURI() != "/wallet/amount?blockchain_address=1abc&min_confirmations=2" {
+			t.Errorf("request %s %s", r.Method, r.URL.RequestURI())
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"amount": 3, "confirmed": 2, "spendable": 1, "min_confirmations": 2})
+	}))
+	defer srv.Close()
+
+	amount, err := NewWalletWithConfig(srv.URL, testConfig).Amount(context.Background(), "1abc", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount.Amount != 3 || amount.Confirmed != 2 || amount.Spendable != 1 || amount.MinConfirmations != 2 {
+		t.Errorf("amount %+v", amount)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is returned when a server answers with an unexpected status code.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the "message" field of the response body, if any.
+	Message string
+}
+
+func (e *Error) Error() string {
+	status := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.Path, status)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.Path, status, e.Message)
+}
+
+// Temporary() reports whether the request may succeed when retried.
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// StatusCode() returns the HTTP status of an *Error, or 0 for any other error.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound() reports whether the server answered 404 Not Found.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// RPCError is an error object returned by the JSON-RPC endpoint.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+var rpcID int64
+
+// Call() invokes a method of the node's JSON-RPC endpoint with params given by name or position,
+// and decodes its result into out. Only read methods, whose names start with "get", are retried.
+func (c *Client) Call(ctx context.Context, method string, params, out interface{}) error {
+	req := struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+		ID      int64       `json:"id"`
+	}{"2.0", method, params, atomic.AddInt64(&rpcID, 1)}
+
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+
+	retry := strings.HasPrefix(method, "get")
+	if err := c.send(ctx, http.MethodPost, "/rpc", &req, &res, http.StatusOK, retry); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, out)
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the time a single attempt may take before it is abandoned.
+	DefaultTimeout = 30 * time.Second
+	// DefaultRetries is the number of times a failed idempotent request is retried.
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the wait before the first retry; it doubles with every retry.
+	DefaultRetryBackoff = 250 * time.Millisecond
+)
+
+// Config holds the settings shared by the clients.
+type Config struct {
+	Timeout      time.Duration
+	Retries      int
+	RetryBackoff time.Duration
+	// HTTPClient is used to send requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// DefaultConfig() returns the settings used by New() and NewWallet().
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      DefaultTimeout,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+}
+
+// transport sends JSON requests to a server and decodes its JSON responses.
+type transport struct {
+	baseURL string
+	config  Config
+}
+
+func newTransport(baseURL string, config Config) transport {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return transport{strings.TrimRight(baseURL, "/"), config}
+}
+
+// idempotent() reports whether a request with the method may be sent twice.
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+// do() sends a request with an optional JSON body, retrying idempotent requests on network errors
+// and temporary failures, and decodes the JSON response into out when the status is the expected one.
+func (t *transport) do(ctx context.Context, method, path string, body, out interface{}, expected int) error {
+	return t.send(ctx, method, path, body, out, expected, idempotent(method))
+}
+
+// send() is do() with an explicit choice of whether the request may be retried.
+func (t *transport) send(ctx context.Context, method, path string, body, out interface{}, expected int, retry bool) error {
+	var payload []byte
+	if body != nil {
+		m, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = m
+	}
+
+	retries := 0
+	if retry {
+		retries = t.config.Retries
+	}
+	backoff := t.config.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := t.attempt(ctx, method, path, payload, out, expected)
+		if err == nil || attempt >= retries || !retryable(ctx, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable() reports whether a failed attempt should be retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Temporary()
+	}
+	// network errors, including timeouts of a single attempt
+	return true
+}
+
+func (t *transport) attempt(ctx context.Context, method, path string, payload []byte, out interface{}, expected int) error {
+	if t.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := t.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != expected {
+		e := &Error{Method: method, Path: path, StatusCode: res.StatusCode}
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(res.Body).Decode(&status) == nil {
+			e.Message = status.Message
+		}
+		return e
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// Key is the public part of a key in the wallet server's keystore.
+type Key struct {
+	ID              string      `json:"id"`
+	Address         string      `json:"address"`
+	SignatureScheme keys.Scheme `json:"signature_scheme"`
+	PublicKey       string      `json:"public_key"`
+	CreatedAt       time.Time   `json:"created_at"`
+	Unlocked        bool        `json:"unlocked"`
+}
+
+// BuildRequest describes the transaction to build for a sender who signs it themselves.
+type BuildRequest struct {
+	SenderPublicKey  string      `json:"sender_public_key"`
+	SignatureScheme  keys.Scheme `json:"signature_scheme"`
+	RecipientAddress string      `json:"recipient_address"`
+	Amount           string      `json:"amount"`
+}
+
+// WalletClient is a client for the wallet server API.
+type WalletClient struct {
+	transport
+}
+
+// NewWallet() returns a client for the wallet server at baseURL, such as http://localhost:8080.
+func NewWallet(baseURL string) *WalletClient {
+	return NewWalletWithConfig(baseURL, DefaultConfig())
+}
+
+// NewWalletWithConfig() returns a client for the wallet server at baseURL with custom timeouts and retries.
+func NewWalletWithConfig(baseURL string, config Config) *WalletClient {
+	return &WalletClient{newTransport(baseURL, config)}
+}
+
+func (c *WalletClient) BaseURL() string {
+	return c.baseURL
+}
+
+// Amount() returns the confirmed, unconfirmed and spendable amounts of the address, with funds
+// counting as confirmed from minConfirmations confirmations.
+func (c *WalletClient) Amount(ctx context.Context, bcAddress string, minConfirmations int) (*blockchain.AmountResponse, error) {
+	query := url.Values{
+		"blockchain_address": {bcAddress},
+		"min_confirmations":  {strconv.Itoa(minConfirmations)},
+	}
+	var amount blockchain.AmountResponse
+	if err := c.do(ctx, http.MethodGet, "/wallet/amount?"+query.Encode(), nil, &amount, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// SendTransaction() makes the server sign a transaction, with a keystore key or a raw key, and broadcast it.
+// It returns the ID of the transaction.
+func (c *WalletClient) SendTransaction(ctx context.Context, tr *wallet.TransactionRequest) (string, error) {
+	var res blockchain.SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/transaction", tr, &res, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// BuildTransaction() returns an unsigned transaction and the payload the sender must sign.
+func (c *WalletClient) BuildTransaction(ctx context.Context, req *BuildRequest) (*wallet.UnsignedTransaction, error) {
+	var ut wallet.UnsignedTransaction
+	if err := c.do(ctx, http.MethodPost, "/transaction/build", req, &ut, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &ut, nil
+}
+
+// BroadcastTransaction() forwards a transaction signed by the client to the blockchain and returns its ID.
+func (c *WalletClient) BroadcastTransaction(ctx context.Context, st *wallet.SignedTransaction) (string, error) {
+	var res blockchain.SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/transaction/broadcast", st, &res, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// Keys() lists the keys in the keystore.
+func (c *WalletClient) Keys(ctx context.Context) ([]*Key, error) {
+	var res struct {
+		Keys []*Key `json:"keys"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/keystore/keys", nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return res.Keys, nil
+}
+
+// CreateKey() generates a key of the scheme in the keystore.
+func (c *WalletClient) CreateKey(ctx context.Context, scheme keys.Scheme, passphrase string) (*Key, error) {
+	req := struct {
+		Passphrase string      `json:"passphrase"`
+		Scheme     keys.Scheme `json:"signature_scheme"`
+	}{passphrase, scheme}
+
+	var k Key
+	if err := c.do(ctx, http.MethodPost, "/keystore/keys", &req, &k, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ImportPrivateKey() stores a raw private key in the keystore, encrypted with the passphrase.
+func (c *WalletClient) ImportPrivateKey(ctx context.Context, scheme keys.Scheme, privateKey, passphrase string) (*Key, error) {
+	req := struct {
+		PrivateKey string      `json:"private_key"`
+		Scheme     keys.Scheme `json:"signature_scheme"`
+		Passphrase string      `json:"passphrase"`
+	}{privateKey, scheme, passphrase}
+
+	var k Key
+	if err := c.do(ctx, http.MethodPost, "/keystore/import", &req, &k, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ImportKeyFile() stores a key file exported from another keystore.
+func (c *WalletClient) ImportKeyFile(ctx context.Context, keyFile json.RawMessage) (*Key, error) {
+	req := struct {
+		KeyFile json.RawMessage `json:"key_file"`
+	}{keyFile}
+
+	var k Key
+	if err := c.do(ctx, http.MethodPost, "/keystore/import", &req, &k, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ExportKey() returns the encrypted key file with the id.
+func (c *WalletClient) ExportKey(ctx context.Context, id string) (json.RawMessage, error) {
+	var keyFile json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/keystore/export?id="+url.QueryEscape(id), nil, &keyFile, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return keyFile, nil
+}
+
+// UnlockKey() decrypts a key so the server can sign with it until the timeout; 0 uses the server's default.
+func (c *WalletClient) UnlockKey(ctx context.Context, id, passphrase string, timeout time.Duration) error {
+	req := struct {
+		ID         string `json:"id"`
+		Passphrase string `json:"passphrase"`
+		TimeoutSec int    `json:"timeout_sec"`
+	}{id, passphrase, int(timeout / time.Second)}
+	return c.do(ctx, http.MethodPost, "/keystore/unlock", &req, nil, http.StatusOK)
+}
+
+// LockKey() forgets a decrypted key.
+func (c *WalletClient) LockKey(ctx context.Context, id string) error {
+	req := struct {
+		ID string `json:"id"`
+	}{id}
+	return c.do(ctx, http.MethodPost, "/keystore/lock", &req, nil, http.StatusOK)
+}
+
+// ChangePassphrase() re-encrypts a key with a new passphrase.
+func (c *WalletClient) ChangePassphrase(ctx context.Context, id, oldPassphrase, newPassphrase string) error {
+	req := struct {
+		ID            string `json:"id"`
+		OldPassphrase string `json:"old_passphrase"`
+		NewPassphrase string `json:"new_passphrase"`
+	}{id, oldPassphrase, newPassphrase}
+	return c.do(ctx, http.MethodPost, "/keystore/passphrase", &req, nil, http.StatusOK)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+)
+
+// runInfo prints a summary of the node's chain.
+func runInfo(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("info")
+	fs.Parse(args)
+
+	info, err := cf.Client().Info(ctx)
+	if err != nil {
+		return err
+	}
+	return cf.output(info, func(w io.Writer) {
+		fmt.Fprintf(w, "Network:\t%s\n", info.Network)
+		fmt.Fprintf(w, "Height:\t%d\n", info.Height)
+		fmt.Fprintf(w, "Last hash:\t%s\n", info.LastHash)
+		fmt.Fprintf(w, "Genesis hash:\t%s\n", info.GenesisHash)
+		fmt.Fprintf(w, "Consensus:\t%s\n", info.Consensus)
+		for i, v := range info.Validators {
+			fmt.Fprintf(w, "Validator %d:\t%s\n", i+1, v)
+		}
+		fmt.Fprintf(w, "Difficulty:\t%d\n", info.Difficulty)
+		fmt.Fprintf(w, "Pending transactions:\t%d\n", info.PendingTransactions)
+		fmt.Fprintf(w, "Mining address:\t%s\n", info.MiningAddress)
+		fmt.Fprintf(w, "Mining:\t%t\n", info.Mining)
+		fmt.Fprintf(w, "Peers:\t%d\n", info.Peers)
+		fmt.Fprintf(w, "Transaction index:\t%t\n", info.TxIndex)
+	})
+}
+
+// printTransactions() writes a table of transactions.
+func printTransactions(w io.Writer, transactions []*blockchain.TransactionView) {
+	fmt.Fprintln(w, "ID\tSENDER\tRECIPIENT\tAMOUNT\tFEE\tSCHEME")
+	for _, t := range transactions {
+		scheme := t.SignatureScheme
+		if scheme == "" {
+			scheme = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%v\t%s\n", t.ID, t.SenderAddress, t.RecipientAddress, t.Amount, t.Fee, scheme)
+	}
+}
+
+// formatTimestamp() formats a block timestamp in nanoseconds.
+func formatTimestamp(timestamp int64) string {
+	return time.Unix(0, timestamp).Format(time.RFC3339)
+}
+
+// runBlock prints the block with a hash or at a height, the last block by default.
+func runBlock(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("block")
+	height := fs.Int("height", -1, "height of the block (default the last block)")
+	hash := fs.String("hash", "", "hash of the block")
+	fs.Parse(args)
+
+	c := cf.Client()
+	var res *blockchain.BlockView
+	var err error
+	switch {
+	case *hash != "":
+		res, err = c.BlockByHash(ctx, *hash)
+	case *height >= 0:
+		res, err = c.Block(ctx, *height)
+	default:
+		var tip *blockchain.ChainTip
+		if tip, err = c.Tip(ctx); err == nil {
+			res, err = c.BlockByHash(ctx, tip.Hash)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return cf.output(res, func(w io.Writer) {
+		fmt.Fprintf(w, "Height:\t%d\n", res.Height)
+		fmt.Fprintf(w, "Hash:\t%s\n", res.Hash)
+		fmt.Fprintf(w, "Previous hash:\t%s\n", res.PrevHash)
+		fmt.Fprintf(w, "Timestamp:\t%s\n", formatTimestamp(res.Timestamp))
+		fmt.Fprintf(w, "Nonce:\t%d\n", res.Nonce)
+		if res.StateRoot != "" {
+			fmt.Fprintf(w, "State root:\t%s\n", res.StateRoot)
+		}
+		if res.Signature != "" {
+			fmt.Fprintf(w, "Signature:\t%s\n", res.Signature)
+		}
+		fmt.Fprintf(w, "Confirmations:\t%d\n", res.Confirmations)
+		fmt.Fprintf(w, "Transactions:\t%d\n\n", res.TransactionCount)
+		printTransactions(w, res.Transactions)
+	})
+}
+
+// runBlocks lists a page of blocks, newest first unless -asc is set.
+func runBlocks(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("blocks")
+	cursor := fs.String("cursor", "", "cursor of the page, as printed after the previous one")
+	limit := fs.Int("limit", 0, "number of blocks (default the node's page size)")
+	ascending := fs.Bool("asc", false, "list from genesis up")
+	fs.Parse(args)
+
+	page, err := cf.Client().Blocks(ctx, *cursor, *limit, *ascending)
+	if err != nil {
+		return err
+	}
+	return cf.output(page, func(w io.Writer) {
+		fmt.Fprintln(w, "HEIGHT\tHASH\tTIMESTAMP\tTRANSACTIONS")
+		for _, b := range page.Blocks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", b.Height, b.Hash, formatTimestamp(b.Timestamp), b.TransactionCount)
+		}
+		if page.NextCursor != "" {
+			fmt.Fprintf(w, "\nNext cursor:\t%s\n", page.NextCursor)
+		}
+	})
+}
+
+// runTransaction prints a pending or confirmed transaction and its confirmations.
+func runTransaction(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("tx")
+	id := fs.String("id", "", "id of the transaction")
+	fs.Parse(args)
+
+	t, err := cf.Client().Transaction(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return cf.output(t, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", t.ID)
+		fmt.Fprintf(w, "Sender:\t%s\n", t.SenderAddress)
+		fmt.Fprintf(w, "Recipient:\t%s\n", t.RecipientAddress)
+		fmt.Fprintf(w, "Amount:\t%v\n", t.Amount)
+		fmt.Fprintf(w, "Fee:\t%v\n", t.Fee)
+		if t.Locktime != 0 {
+			fmt.Fprintf(w, "Lock time:\t%d\n", t.Locktime)
+		}
+		if t.LockScript != nil {
+			fmt.Fprintf(w, "Locking script:\t%s\n", t.LockScript)
+			fmt.Fprintf(w, "Unlocking script:\t%s\n", t.UnlockScript)
+		}
+		if t.Code != nil {
+			fmt.Fprintf(w, "Contract code:\t%s\n", t.Code)
+		}
+		for i, arg := range t.Input {
+			fmt.Fprintf(w, "Argument %d:\t%s\n", i, arg)
+		}
+		if t.GasLimit != 0 {
+			fmt.Fprintf(w, "Gas limit:\t%d\n", t.GasLimit)
+		}
+		if t.Token != "" {
+			fmt.Fprintf(w, "Token:\t%s %s\n", t.TokenAction, t.Token)
+			if t.Mintable {
+				fmt.Fprintln(w, "Mintable:\ttrue")
+			}
+		}
+		fmt.Fprintf(w, "Status:\t%s\n", t.Status)
+		if t.BlockHeight != nil {
+			fmt.Fprintf(w, "Block:\t%d %s\n", *t.BlockHeight, t.BlockHash)
+		}
+		fmt.Fprintf(w, "Confirmations:\t%d\n", t.Confirmations)
+	})
+}
+
+// runMempool lists the transactions waiting to be mined, or drops them with -clear.
+func runMempool(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("mempool")
+	clearPool := fs.Bool("clear", false, "drop every pending transaction")
+	fs.Parse(args)
+
+	c := cf.Client()
+	if *clearPool {
+		if err := c.ClearMempool(ctx); err != nil {
+			return err
+		}
+		return cf.printStatus("Transactions pool cleared")
+	}
+
+	transactions, err := c.Mempool(ctx)
+	if err != nil {
+		return err
+	}
+	return cf.output(blockchain.TransactionsResponse{Transactions: transactions}, func(w io.Writer) {
+		printTransactions(w, transactions)
+	})
+}
+
+// runBalance prints the balances of an address and what it received and sent.
+func runBalance(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("balance")
+	bcAddress := fs.String("address", "", "address to query")
+	minConfirmations := fs.Int("min-conf", blockchain.DEFAULT_MIN_CONFIRMATIONS, "confirmations before funds count as confirmed")
+	fs.Parse(args)
+
+	c := cf.Client()
+	summary, err := c.Address(ctx, *bcAddress)
+	if err != nil {
+		return err
+	}
+	amount, err := c.Balance(ctx, *bcAddress, *minConfirmations)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		*blockchain.AddressSummary
+		Amounts *blockchain.AmountResponse `json:"amounts"`
+	}{summary, amount}
+	return cf.output(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Address:\t%s\n", summary.Address)
+		fmt.Fprintf(w, "Confirmed:\t%v\n", amount.Confirmed)
+		fmt.Fprintf(w, "Unconfirmed incoming:\t%v\n", amount.UnconfirmedIncoming)
+		fmt.Fprintf(w, "Unconfirmed outgoing:\t%v\n", amount.UnconfirmedOutgoing)
+		fmt.Fprintf(w, "Spendable:\t%v\n", amount.Spendable)
+		fmt.Fprintf(w, "Min confirmations:\t%d\n", amount.MinConfirmations)
+		fmt.Fprintf(w, "Received:\t%v\n", summary.Received)
+		fmt.Fprintf(w, "Sent:\t%v\n", summary.Sent)
+		fmt.Fprintf(w, "Transactions:\t%d\n", summary.TransactionCount)
+		symbols := make([]string, 0, len(summary.Tokens))
+		for symbol := range summary.Tokens {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		for _, symbol := range symbols {
+			fmt.Fprintf(w, "Token %s:\t%v\n", symbol, summary.Tokens[symbol])
+		}
+	})
+}
+
+// runHistory lists the confirmed transactions of an address a page at a time, newest first.
+func runHistory(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("history")
+	bcAddress := fs.String("address", "", "address to query")
+	cursor := fs.String("cursor", "", "cursor of the page, as printed after the previous one")
+	limit := fs.Int("limit", 0, "number of transactions (default the node's page size)")
+	fs.Parse(args)
+
+	page, err := cf.Client().AddressHistory(ctx, *bcAddress, *cursor, *limit)
+	if err != nil {
+		return err
+	}
+	return cf.output(page, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tHEIGHT\tCONFIRMATIONS\tSENDER\tRECIPIENT\tAMOUNT\tFEE")
+		for _, t := range page.Transactions {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%v\t%v\n", t.ID, *t.BlockHeight, t.Confirmations, t.SenderAddress, t.RecipientAddress, t.Amount, t.Fee)
+		}
+		if page.NextCursor != "" {
+			fmt.Fprintf(w, "\nNext cursor:\t%s\n", page.NextCursor)
+		}
+	})
+}
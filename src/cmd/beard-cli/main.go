@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// command is a beard-cli subcommand.
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]*command{
+	"balance":   {"print the balance and totals of an address", runBalance},
+	"block":     {"print a block by height or hash", runBlock},
+	"blocks":    {"list the blocks a page at a time", runBlocks},
+	"consensus": {"make the node adopt the longest valid chain of its peers", runConsensus},
+	"history":   {"list the transactions of an address", runHistory},
+	"info":      {"print a summary of the chain", runInfo},
+	"mempool":   {"list or clear the pending transactions", runMempool},
+	"mine":      {"mine a block, or start periodic mining with -start", runMine},
+	"peers":     {"list the peers of the node", runPeers},
+	"tx":        {"print a transaction and its confirmations", runTransaction},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-cli <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'beard-cli <command> -h' for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-cli: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	// cancel requests in flight on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "beard-cli:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+)
+
+// runPeers lists the neighbors the node relays to.
+func runPeers(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("peers")
+	fs.Parse(args)
+
+	peers, err := cf.Client().Peers(ctx)
+	if err != nil {
+		return err
+	}
+	return cf.output(blockchain.PeersResponse{Peers: peers}, func(w io.Writer) {
+		fmt.Fprintln(w, "PEER")
+		for _, peer := range peers {
+			fmt.Fprintln(w, peer)
+		}
+	})
+}
+
+// runMine mines a single block, or starts periodic mining with -start.
+func runMine(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("mine")
+	start := fs.Bool("start", false, "mine periodically instead of once")
+	fs.Parse(args)
+
+	c := cf.Client()
+	if *start {
+		if err := c.StartMining(ctx); err != nil {
+			return err
+		}
+		return cf.printStatus("Mining started")
+	}
+	if err := c.Mine(ctx); err != nil {
+		return err
+	}
+	return cf.printStatus("Mining successful")
+}
+
+// runConsensus makes the node adopt the longest valid chain among its peers.
+func runConsensus(ctx context.Context, args []string) error {
+	fs, cf := newFlagSet("consensus")
+	fs.Parse(args)
+
+	if err := cf.Client().ResolveConflicts(ctx); err != nil {
+		return err
+	}
+	return cf.printStatus("Consensus resolved")
+}
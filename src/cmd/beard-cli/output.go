@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Rha02/block-beard/src/client"
+)
+
+// defaultNode is the blockchain server used when -node is not given.
+const defaultNode = "http://localhost:3000"
+
+// commonFlags are the flags shared by every command.
+type commonFlags struct {
+	node    *string
+	timeout *time.Duration
+	retries *int
+	asJSON  *bool
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return fs, &commonFlags{
+		node:    fs.String("node", defaultNode, "address of the blockchain server"),
+		timeout: fs.Duration("timeout", client.DefaultTimeout, "time a request may take"),
+		retries: fs.Int("retries", client.DefaultRetries, "times a failed read-only request is retried"),
+		asJSON:  fs.Bool("json", false, "print the result as JSON"),
+	}
+}
+
+func (cf *commonFlags) Client() *client.Client {
+	config := client.DefaultConfig()
+	config.Timeout = *cf.timeout
+	config.Retries = *cf.retries
+	return client.NewWithConfig(*cf.node, config)
+}
+
+// output() prints v as JSON when -json is set, and otherwise lets text write a table.
+func (cf *commonFlags) output(v interface{}, text func(w io.Writer)) error {
+	if *cf.asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	text(tw)
+	return tw.Flush()
+}
+
+// status is the JSON output of commands that only report success.
+type status struct {
+	Message string `json:"message"`
+}
+
+func (cf *commonFlags) printStatus(message string) error {
+	return cf.output(status{message}, func(w io.Writer) {
+		io.WriteString(w, message+"\n")
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+// addressInfo is the output of the address command.
+type addressInfo struct {
+	Address         string      `json:"address"`
+	Network         string      `json:"network"`
+	SignatureScheme keys.Scheme `json:"signature_scheme"`
+	PublicKey       string      `json:"public_key"`
+}
+
+// runAddress prints the address of a key without decrypting it.
+func runAddress(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("address", flag.ExitOnError)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	publicKey, err := kf.PublicKey()
+	if err != nil {
+		return err
+	}
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+
+	info := addressInfo{
+		Address:         address.FromPublicKey(publicKey, network),
+		Network:         network.Name,
+		SignatureScheme: publicKey.Scheme(),
+		PublicKey:       keys.ToString(publicKey),
+	}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintln(w, info.Address)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math"
+	"strconv"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// parseAmount() converts a decimal amount string to a positive amount.
+func parseAmount(s string) (float32, error) {
+	amount, err := strconv.ParseFloat(s, 32)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, errors.New("invalid amount: " + s)
+	}
+	if amount <= 0 {
+		return 0, errors.New("amount must be positive")
+	}
+	return float32(amount), nil
+}
+
+// addNonceFlag() adds the flag giving the nonce of a transaction built offline.
+func addNonceFlag(fs *flag.FlagSet) *uint64 {
+	return fs.Uint64("nonce", 0, "number of transactions the sender has sent, shown by balance as the next nonce")
+}
+
+// buildTransaction() builds the unsigned transaction described by the flags.
+func buildTransaction(kf *keyFlags, recipient, amount, fee string, nonce uint64) (*wallet.UnsignedTransaction, error) {
+	network, err := kf.Network()
+	if err != nil {
+		return nil, err
+	}
+	if err := address.Validate(recipient, network); err != nil {
+		return nil, err
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	feeValue, err := wallet.ParseFee(fee)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := kf.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return wallet.NewUnsignedTransaction(publicKey, network, recipient, value, feeValue, nonce), nil
+}
+
+// runBuild builds an unsigned transaction offline, ready to be signed with sign.
+func runBuild(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount to send")
+	fee := fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount")
+	nonce := addNonceFlag(fs)
+	out := fs.String("out", "-", "file to write the unsigned transaction to")
+	kf := addKeyFlags(fs)
+	fs.Parse(args)
+
+	ut, err := buildTransaction(kf, *to, *amount, *fee, *nonce)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, ut)
+}
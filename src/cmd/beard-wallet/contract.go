@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/vm"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// DEFAULT_CALL_GAS is the gas limit of a contract call when -gas is not given.
+const DEFAULT_CALL_GAS = 10000
+
+// contractCommands are the subcommands of the contract command, which deploy contracts, call them
+// with transactions and query them without changing their state.
+var contractCommands = map[string]*command{
+	"assemble": {"assemble contract code and print it as hex", runContractAssemble},
+	"deploy":   {"deploy a contract from a key", runContractDeploy},
+	"call":     {"call a contract with a transaction", runContractCall},
+	"query":    {"run a contract on a node without changing its state", runContractQuery},
+	"info":     {"print the code and storage of a contract", runContractInfo},
+	"receipt":  {"print the outcome of a confirmed contract transaction", runContractReceipt},
+}
+
+func contractUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-wallet contract <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(contractCommands))
+	for name := range contractCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, contractCommands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Code is written as opcode names, labels ending in a colon, @label jump targets and")
+	fmt.Fprintln(os.Stderr, "data: decimal numbers, <hex> or 'text'. Arguments are written as data or plain text, e.g.")
+	fmt.Fprintln(os.Stderr, "  0 ARG DUP SLOAD SIZE ISZERO ASSERT CALLER SSTORE STOP")
+}
+
+// runContract dispatches to a contract subcommand.
+func runContract(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		contractUsage()
+		os.Exit(2)
+	}
+	cmd, ok := contractCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-wallet: unknown contract command %q\n\n", args[0])
+		contractUsage()
+		os.Exit(2)
+	}
+	return cmd.run(ctx, args[1:])
+}
+
+// argsFlag collects the arguments of a call given by repeating -arg.
+type argsFlag [][]byte
+
+func (a *argsFlag) String() string {
+	return fmt.Sprint(len(*a), " arguments")
+}
+
+func (a *argsFlag) Set(s string) error {
+	data, ok, err := vm.ParseData(s)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		data = []byte(s)
+	}
+	*a = append(*a, data)
+	return nil
+}
+
+func addArgsFlag(fs *flag.FlagSet) *argsFlag {
+	var args argsFlag
+	fs.Var(&args, "arg", "argument of the call: a number, <hex>, 'text' or plain text (repeatable)")
+	return &args
+}
+
+// readCode() assembles the code given to -code, or read from the -file.
+func readCode(asm, file string) (vm.Code, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		asm = string(b)
+	}
+	if strings.TrimSpace(asm) == "" {
+		return nil, errors.New("-code or -file is required")
+	}
+	return vm.Assemble(asm)
+}
+
+// contractFee() returns the fee given to -fee, or the least fee the gas limit requires.
+func contractFee(fee string, gasLimit uint64) (float32, error) {
+	if fee == "" {
+		return blockchain.MinContractFee(gasLimit), nil
+	}
+	return wallet.ParseFee(fee)
+}
+
+// contractInfo is the output of the contract assemble command.
+type contractInfo struct {
+	Code    vm.Code `json:"code"`
+	Asm     string  `json:"asm"`
+	Size    int     `json:"size"`
+	Gas     uint64  `json:"deploy_gas"`
+	Address string  `json:"address,omitempty"`
+}
+
+// runContractAssemble prints assembled code, and the address it deploys to from a key.
+func runContractAssemble(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract assemble", flag.ExitOnError)
+	asm := fs.String("code", "", "contract code")
+	file := fs.String("file", "", "file holding the contract code")
+	deployer := fs.String("deployer", "", "address of the deployer, to print the address of the contract")
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	code, err := readCode(*asm, *file)
+	if err != nil {
+		return err
+	}
+	info := contractInfo{code, code.String(), len(code), vm.DeployGas(code), ""}
+	if *deployer != "" {
+		if err := address.Validate(*deployer, network); err != nil {
+			return err
+		}
+		info.Address = vm.ContractAddress(*deployer, code, network)
+	}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "Code:\t%s\n", hex.EncodeToString(code))
+		fmt.Fprintf(w, "Size:\t%d bytes\n", info.Size)
+		fmt.Fprintf(w, "Deploy gas:\t%d\n", info.Gas)
+		if info.Address != "" {
+			fmt.Fprintf(w, "Address:\t%s\n", info.Address)
+		}
+	})
+}
+
+// contractResult is the output of the contract deploy and call commands.
+type contractResult struct {
+	Message  string  `json:"message"`
+	ID       string  `json:"id"`
+	Contract string  `json:"contract"`
+	GasLimit uint64  `json:"gas_limit"`
+	Fee      float32 `json:"fee"`
+}
+
+// sendContractTransaction() signs a transaction deploying the code or calling the contract with the
+// input, and submits it to the node.
+func sendContractTransaction(
+	ctx context.Context, node string, signer keys.Signer, sender, contract string,
+	code vm.Code, input [][]byte, gasLimit uint64, fee float32,
+) (string, error) {
+	nonce, err := nextNonce(ctx, node, sender)
+	if err != nil {
+		return "", err
+	}
+	t := wallet.NewTransaction(signer, sender, contract, 0, fee)
+	t.SetNonce(nonce)
+	t.SetContract(code, input, gasLimit)
+	signature := hex.EncodeToString(t.GenerateSignature())
+
+	var amount float32
+	publicKey := keys.ToString(signer.Public())
+	scheme := string(signer.Scheme())
+	return client.New(node).SendTransaction(ctx, &blockchain.TransactionRequest{
+		SenderAddress:    &sender,
+		RecipientAddress: &contract,
+		Amount:           &amount,
+		Fee:              &fee,
+		Nonce:            &nonce,
+		SenderPublicKey:  &publicKey,
+		Signature:        &signature,
+		SignatureScheme:  &scheme,
+		Code:             code,
+		Input:            blockchain.EncodeInput(input),
+		GasLimit:         &gasLimit,
+	})
+}
+
+func printContractResult(asJSON bool, result contractResult) error {
+	return output(asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Contract:\t%s\n", result.Contract)
+		fmt.Fprintf(w, "Gas limit:\t%d\n", result.GasLimit)
+		fmt.Fprintf(w, "Fee:\t%v\n", result.Fee)
+		fmt.Fprintf(w, "Transaction ID:\t%s\n", result.ID)
+	})
+}
+
+// runContractDeploy deploys a contract from the address of a key.
+func runContractDeploy(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract deploy", flag.ExitOnError)
+	asm := fs.String("code", "", "contract code")
+	file := fs.String("file", "", "file holding the contract code")
+	gas := fs.Uint64("gas", 0, "gas limit (default the gas deploying the code costs)")
+	fee := fs.String("fee", "", "fee paid to the miner (default the least the gas limit requires)")
+	node := addNodeFlag(fs)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	code, err := readCode(*asm, *file)
+	if err != nil {
+		return err
+	}
+	gasLimit := *gas
+	if gasLimit == 0 {
+		gasLimit = vm.DeployGas(code)
+	}
+	feeValue, err := contractFee(*fee, gasLimit)
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+
+	sender := address.FromPublicKey(signer.Public(), network)
+	contract := vm.ContractAddress(sender, code, network)
+	id, err := sendContractTransaction(ctx, *node, signer, sender, contract, code, nil, gasLimit, feeValue)
+	if err != nil {
+		return err
+	}
+	return printContractResult(*asJSON, contractResult{"Contract deployment posted to blockchain", id, contract, gasLimit, feeValue})
+}
+
+// runContractCall calls a contract with a transaction from the address of a key.
+func runContractCall(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract call", flag.ExitOnError)
+	contract := fs.String("contract", "", "address of the contract")
+	input := addArgsFlag(fs)
+	gas := fs.Uint64("gas", DEFAULT_CALL_GAS, "gas limit")
+	fee := fs.String("fee", "", "fee paid to the miner (default the least the gas limit requires)")
+	node := addNodeFlag(fs)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	if err := address.Validate(*contract, network); err != nil {
+		return err
+	}
+	if *gas == 0 {
+		return errors.New("-gas must be positive")
+	}
+	feeValue, err := contractFee(*fee, *gas)
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+
+	sender := address.FromPublicKey(signer.Public(), network)
+	id, err := sendContractTransaction(ctx, *node, signer, sender, *contract, nil, *input, *gas, feeValue)
+	if err != nil {
+		return err
+	}
+	return printContractResult(*asJSON, contractResult{"Contract call posted to blockchain", id, *contract, *gas, feeValue})
+}
+
+// printReturn() prints what a contract returned as hex, and as text when it is printable.
+func printReturn(w io.Writer, ret string) {
+	fmt.Fprintf(w, "Returned:\t%s\n", ret)
+	b, err := hex.DecodeString(ret)
+	if err != nil || len(b) == 0 {
+		return
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return
+		}
+	}
+	fmt.Fprintf(w, "As text:\t%s\n", b)
+}
+
+// runContractQuery runs a contract on a node as if called by the caller, without a transaction.
+func runContractQuery(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract query", flag.ExitOnError)
+	contract := fs.String("contract", "", "address of the contract")
+	caller := fs.String("caller", "", "address the call is made from")
+	input := addArgsFlag(fs)
+	gas := fs.Uint64("gas", 0, "gas limit (default the most a transaction may use)")
+	node := addNodeFlag(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	res, err := client.New(*node).CallContract(ctx, &blockchain.CallRequest{
+		Address:  *contract,
+		Caller:   *caller,
+		Input:    blockchain.EncodeInput(*input),
+		GasLimit: *gas,
+	})
+	if err != nil {
+		return err
+	}
+	return output(*asJSON, res, func(w io.Writer) {
+		if res.Success {
+			fmt.Fprintln(w, "Result:\tsucceeded")
+			printReturn(w, res.Return)
+		} else {
+			fmt.Fprintf(w, "Result:\tfailed: %s\n", res.Error)
+		}
+		fmt.Fprintf(w, "Gas used:\t%d\n", res.GasUsed)
+	})
+}
+
+// runContractInfo prints the code and storage of a contract.
+func runContractInfo(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract info", flag.ExitOnError)
+	contract := fs.String("contract", "", "address of the contract")
+	node := addNodeFlag(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	c, err := client.New(*node).Contract(ctx, *contract)
+	if err != nil {
+		return err
+	}
+	return output(*asJSON, c, func(w io.Writer) {
+		fmt.Fprintf(w, "Address:\t%s\n", c.Address)
+		fmt.Fprintf(w, "Deployer:\t%s\n", c.Deployer)
+		fmt.Fprintf(w, "Deployed at:\t%d\n", c.Height)
+		fmt.Fprintf(w, "Code:\t%s\n", c.Asm)
+		keys := make([]string, 0, len(c.Storage))
+		for key := range c.Storage {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(w, "Storage:\t%d keys\n", len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s\t%s\n", key, c.Storage[key])
+		}
+	})
+}
+
+// runContractReceipt prints the outcome of a confirmed deployment or call.
+func runContractReceipt(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("contract receipt", flag.ExitOnError)
+	id := fs.String("id", "", "id of the transaction")
+	node := addNodeFlag(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	r, err := client.New(*node).Receipt(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return output(*asJSON, r, func(w io.Writer) {
+		fmt.Fprintf(w, "Transaction:\t%s\n", r.TransactionID)
+		fmt.Fprintf(w, "Contract:\t%s\n", r.Contract)
+		fmt.Fprintf(w, "Height:\t%d\n", r.Height)
+		if r.Success {
+			fmt.Fprintln(w, "Result:\tsucceeded")
+			if r.Return != "" {
+				printReturn(w, r.Return)
+			}
+		} else {
+			fmt.Fprintf(w, "Result:\tfailed: %s\n", r.Error)
+		}
+		fmt.Fprintf(w, "Gas used:\t%d\n", r.GasUsed)
+	})
+}
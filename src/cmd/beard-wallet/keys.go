@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/keystore"
+)
+
+// passphraseEnv is the environment variable read when no passphrase file is given.
+const passphraseEnv = "BEARD_WALLET_PASSPHRASE"
+
+// keyFlags are the flags selecting the private key a command signs with.
+type keyFlags struct {
+	network        *string
+	keystoreDir    *string
+	keyID          *string
+	passphraseFile *string
+	privateKey     *string
+	publicKey      *string
+	scheme         *string
+}
+
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	return &keyFlags{
+		network:        fs.String("network", address.MainNet.Name, "network whose address prefixes to use"),
+		keystoreDir:    fs.String("keystore", defaultKeystoreDir(), "directory holding encrypted key files"),
+		keyID:          fs.String("key-id", "", "id of the keystore key to sign with"),
+		passphraseFile: fs.String("passphrase-file", "", "file containing the keystore passphrase (default $"+passphraseEnv+" or prompt)"),
+		privateKey:     fs.String("private-key", "", "hex encoded private key to sign with instead of a keystore key"),
+		publicKey:      fs.String("public-key", "", "hex encoded public key, for commands that do not sign"),
+		scheme:         fs.String("scheme", string(keys.DefaultScheme), "signature scheme of -private-key"),
+	}
+}
+
+// defaultKeystoreDir() returns ~/.beard-wallet/keystore, or ./keystore without a home directory.
+func defaultKeystoreDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "keystore"
+	}
+	return home + "/.beard-wallet/keystore"
+}
+
+func (kf *keyFlags) Network() (*address.Network, error) {
+	return address.ParseNetwork(*kf.network)
+}
+
+func (kf *keyFlags) Keystore() (*keystore.Keystore, error) {
+	network, err := kf.Network()
+	if err != nil {
+		return nil, err
+	}
+	return keystore.New(*kf.keystoreDir, network)
+}
+
+// readPassphrase() returns the passphrase from -passphrase-file, the environment or standard input.
+func readPassphrase(file, prompt string) (string, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	if p, ok := os.LookupEnv(passphraseEnv); ok {
+		return p, nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no passphrase given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Signer() loads the private key selected by the flags.
+func (kf *keyFlags) Signer() (keys.Signer, error) {
+	if *kf.privateKey != "" {
+		scheme, err := keys.ParseScheme(*kf.scheme)
+		if err != nil {
+			return nil, err
+		}
+		return keys.PrivateKeyFromString(scheme, *kf.privateKey)
+	}
+	if *kf.keyID == "" {
+		return nil, errors.New("either -key-id or -private-key is required")
+	}
+
+	ks, err := kf.Keystore()
+	if err != nil {
+		return nil, err
+	}
+	file, err := ks.Get(*kf.keyID)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase(*kf.passphraseFile, "Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return file.Decrypt(passphrase)
+}
+
+// PublicKey() loads the public key selected by the flags without asking for a passphrase.
+func (kf *keyFlags) PublicKey() (keys.Verifier, error) {
+	scheme, err := keys.ParseScheme(*kf.scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case *kf.publicKey != "":
+		return keys.PublicKeyFromString(scheme, *kf.publicKey)
+	case *kf.privateKey != "":
+		signer, err := keys.PrivateKeyFromString(scheme, *kf.privateKey)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	case *kf.keyID != "":
+		ks, err := kf.Keystore()
+		if err != nil {
+			return nil, err
+		}
+		file, err := ks.Get(*kf.keyID)
+		if err != nil {
+			return nil, err
+		}
+		return keys.PublicKeyFromString(file.Scheme, file.PublicKey)
+	}
+	return nil, errors.New("one of -key-id, -public-key or -private-key is required")
+}
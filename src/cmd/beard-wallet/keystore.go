@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/keystore"
+)
+
+// keyInfo is the public part of a keystore key as printed by the key commands.
+type keyInfo struct {
+	ID              string      `json:"id"`
+	Address         string      `json:"address"`
+	SignatureScheme keys.Scheme `json:"signature_scheme"`
+	PublicKey       string      `json:"public_key"`
+	CreatedAt       string      `json:"created_at"`
+}
+
+func newKeyInfo(kf *keystore.KeyFile) keyInfo {
+	return keyInfo{
+		ID:              kf.ID,
+		Address:         kf.Address,
+		SignatureScheme: kf.Scheme,
+		PublicKey:       kf.PublicKey,
+		CreatedAt:       kf.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// printKey() prints a single key as JSON or as a list of fields.
+func printKey(asJSON bool, info keyInfo) error {
+	return output(asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", info.ID)
+		fmt.Fprintf(w, "Address:\t%s\n", info.Address)
+		fmt.Fprintf(w, "Scheme:\t%s\n", info.SignatureScheme)
+		fmt.Fprintf(w, "Public key:\t%s\n", info.PublicKey)
+	})
+}
+
+// newPassphrase() reads the passphrase a new key is encrypted with, refusing an empty one.
+func newPassphrase(file string) (string, error) {
+	passphrase, err := readPassphrase(file, "New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return passphrase, nil
+}
+
+// runCreate generates a new key and stores it in the keystore.
+func runCreate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	scheme, err := keys.ParseScheme(*kf.scheme)
+	if err != nil {
+		return err
+	}
+	ks, err := kf.Keystore()
+	if err != nil {
+		return err
+	}
+	passphrase, err := newPassphrase(*kf.passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	file, err := ks.Create(scheme, passphrase)
+	if err != nil {
+		return err
+	}
+	return printKey(*asJSON, newKeyInfo(file))
+}
+
+// runImport stores a raw private key or an exported key file in the keystore.
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	keyFile := fs.String("file", "", "encrypted key file exported from another keystore")
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	ks, err := kf.Keystore()
+	if err != nil {
+		return err
+	}
+
+	var file *keystore.KeyFile
+	switch {
+	case *keyFile != "":
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		if file, err = ks.ImportFile(data); err != nil {
+			return err
+		}
+	case *kf.privateKey != "":
+		signer, err := kf.Signer()
+		if err != nil {
+			return err
+		}
+		passphrase, err := newPassphrase(*kf.passphraseFile)
+		if err != nil {
+			return err
+		}
+		if file, err = ks.Import(signer, passphrase); err != nil {
+			return err
+		}
+	default:
+		return errors.New("either -file or -private-key is required")
+	}
+	return printKey(*asJSON, newKeyInfo(file))
+}
+
+// runList prints the keys in the keystore.
+func runList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	ks, err := kf.Keystore()
+	if err != nil {
+		return err
+	}
+	files, err := ks.List()
+	if err != nil {
+		return err
+	}
+
+	infos := make([]keyInfo, 0, len(files))
+	for _, file := range files {
+		infos = append(infos, newKeyInfo(file))
+	}
+	return output(*asJSON, infos, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tSCHEME\tADDRESS\tCREATED")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.ID, info.SignatureScheme, info.Address, info.CreatedAt)
+		}
+	})
+}
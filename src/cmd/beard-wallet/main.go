@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// command is a beard-wallet subcommand.
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]*command{
+	"address":   {"print the address of a key", runAddress},
+	"contract":  {"deploy, call and query contracts", runContract},
+	"balance":   {"query the balance of an address from a node", runBalance},
+	"broadcast": {"submit a signed transaction to a node", runBroadcast},
+	"build":     {"build an unsigned transaction offline", runBuild},
+	"create":    {"generate a new key in the keystore", runCreate},
+	"import":    {"import a private key or key file into the keystore", runImport},
+	"list":      {"list the keys in the keystore", runList},
+	"multisig":  {"create and co-sign spends from m-of-n addresses", runMultisig},
+	"script":    {"spend from addresses locked by scripts", runScript},
+	"send":      {"build, sign and broadcast a transaction", runSend},
+	"sign":      {"sign an unsigned transaction offline", runSign},
+	"token":     {"issue, mint, transfer and burn tokens", runToken},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-wallet <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'beard-wallet <command> -h' for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-wallet: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	// cancel requests in flight on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "beard-wallet:", err)
+		os.Exit(1)
+	}
+}
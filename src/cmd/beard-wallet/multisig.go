@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/multisig"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// multisigCommands are the subcommands of the multisig command, which together implement
+// the shared custody flow: derive the address, build a spend, collect signatures and broadcast.
+var multisigCommands = map[string]*command{
+	"address":   {"derive an m-of-n address from public keys", runMultisigAddress},
+	"build":     {"build an unsigned spend from a multisig address", runMultisigBuild},
+	"sign":      {"add a co-signer's signature to a partial transaction", runMultisigSign},
+	"combine":   {"merge the signatures of partial transactions", runMultisigCombine},
+	"broadcast": {"submit a fully signed multisig spend to a node", runMultisigBroadcast},
+}
+
+func multisigUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-wallet multisig <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(multisigCommands))
+	for name := range multisigCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, multisigCommands[name].summary)
+	}
+}
+
+// runMultisig dispatches to a multisig subcommand.
+func runMultisig(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		multisigUsage()
+		os.Exit(2)
+	}
+	cmd, ok := multisigCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-wallet: unknown multisig command %q\n\n", args[0])
+		multisigUsage()
+		os.Exit(2)
+	}
+	return cmd.run(ctx, args[1:])
+}
+
+// parsePublicKeys() parses a comma separated list of [scheme:]hex public keys.
+func parsePublicKeys(list string) ([]keys.Verifier, error) {
+	var res []keys.Verifier
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		schemeName, key := "", item
+		if i := strings.IndexByte(item, ':'); i >= 0 {
+			schemeName, key = item[:i], item[i+1:]
+		}
+		scheme, err := keys.ParseScheme(schemeName)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := keys.PublicKeyFromString(scheme, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %s: %v", key, err)
+		}
+		res = append(res, publicKey)
+	}
+	return res, nil
+}
+
+// multisigInfo is the output of the multisig address command.
+type multisigInfo struct {
+	Address  string           `json:"address"`
+	Network  string           `json:"network"`
+	Multisig *multisig.Policy `json:"multisig"`
+}
+
+// runMultisigAddress prints the address of an m-of-n policy and optionally saves the policy for build.
+func runMultisigAddress(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("multisig address", flag.ExitOnError)
+	m := fs.Int("m", 0, "number of signatures required to spend")
+	publicKeys := fs.String("keys", "", "comma separated public keys, each optionally prefixed with its scheme as scheme:hex")
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	out := fs.String("out", "", "file to save the policy to")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	verifiers, err := parsePublicKeys(*publicKeys)
+	if err != nil {
+		return err
+	}
+	policy, err := multisig.NewPolicy(*m, verifiers)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		if err := writeJSON(*out, policy); err != nil {
+			return err
+		}
+	}
+
+	info := multisigInfo{policy.Address(network), network.Name, policy}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintln(w, info.Address)
+	})
+}
+
+// runMultisigBuild builds an unsigned spend from the address of a saved policy.
+func runMultisigBuild(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("multisig build", flag.ExitOnError)
+	policyFile := fs.String("policy", "", "policy file written by multisig address")
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount to send")
+	fee := fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount")
+	nonce := addNonceFlag(fs)
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	out := fs.String("out", "-", "file to write the partial transaction to")
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	if *policyFile == "" {
+		return errors.New("-policy is required")
+	}
+	var policy multisig.Policy
+	if err := readJSON(*policyFile, &policy); err != nil {
+		return err
+	}
+	if err := address.Validate(*to, network); err != nil {
+		return err
+	}
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	feeValue, err := wallet.ParseFee(*fee)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(*out, wallet.NewPartialTransaction(&policy, network, *to, value, feeValue, *nonce))
+}
+
+// runMultisigSign adds the signature of one co-signer, offline.
+func runMultisigSign(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("multisig sign", flag.ExitOnError)
+	in := fs.String("in", "-", "partial transaction JSON file")
+	out := fs.String("out", "-", "file to write the partial transaction to")
+	kf := addKeyFlags(fs)
+	fs.Parse(args)
+
+	var pt wallet.PartialTransaction
+	if err := readJSON(*in, &pt); err != nil {
+		return err
+	}
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+	if err := pt.Sign(network, signer); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d of %d required signatures\n", pt.SignatureCount(), pt.Multisig.M)
+	return writeJSON(*out, &pt)
+}
+
+// runMultisigCombine merges partial transactions signed by different co-signers.
+func runMultisigCombine(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("multisig combine", flag.ExitOnError)
+	out := fs.String("out", "-", "file to write the combined transaction to")
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		return errors.New("usage: beard-wallet multisig combine [-out file] <partial.json> <partial.json>...")
+	}
+
+	var combined wallet.PartialTransaction
+	if err := readJSON(fs.Arg(0), &combined); err != nil {
+		return err
+	}
+	for _, path := range fs.Args()[1:] {
+		var pt wallet.PartialTransaction
+		if err := readJSON(path, &pt); err != nil {
+			return err
+		}
+		if err := combined.Combine(&pt); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d of %d required signatures\n", combined.SignatureCount(), combined.Multisig.M)
+	return writeJSON(*out, &combined)
+}
+
+// runMultisigBroadcast submits a multisig spend once it carries enough signatures.
+func runMultisigBroadcast(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("multisig broadcast", flag.ExitOnError)
+	in := fs.String("in", "-", "partial transaction JSON file")
+	node := addNodeFlag(fs)
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	var pt wallet.PartialTransaction
+	if err := readJSON(*in, &pt); err != nil {
+		return err
+	}
+	if !pt.IsComplete() {
+		return fmt.Errorf("transaction has %d of %d required signatures", pt.SignatureCount(), pt.Multisig.M)
+	}
+	if err := pt.Verify(network); err != nil {
+		return err
+	}
+
+	id, err := client.New(*node).SendTransaction(ctx, &blockchain.TransactionRequest{
+		SenderAddress:    &pt.SenderAddress,
+		RecipientAddress: &pt.RecipientAddress,
+		Amount:           &pt.Amount,
+		Fee:              &pt.Fee,
+		Nonce:            &pt.Nonce,
+		Multisig:         pt.Multisig,
+		Signatures:       pt.Signatures,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Message     string                     `json:"message"`
+		ID          string                     `json:"id"`
+		Transaction *wallet.PartialTransaction `json:"transaction"`
+	}{"Transaction posted to blockchain", id, &pt}
+	return output(*asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Sent %v from %s to %s\n", pt.Amount, pt.SenderAddress, pt.RecipientAddress)
+		fmt.Fprintf(w, "Transaction ID: %s\n", id)
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// defaultNode is the blockchain server queried when -node is not given.
+const defaultNode = "http://localhost:3000"
+
+func addNodeFlag(fs *flag.FlagSet) *string {
+	return fs.String("node", defaultNode, "address of the blockchain server")
+}
+
+// nextNonce() asks the node for the nonce the next transaction from the address must carry.
+func nextNonce(ctx context.Context, node, bcAddress string) (uint64, error) {
+	amount, err := client.New(node).Balance(ctx, bcAddress, 0)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Nonce, nil
+}
+
+// postTransaction() submits a signed transaction to the node's transaction pool and returns its ID.
+func postTransaction(ctx context.Context, node string, st *wallet.SignedTransaction) (string, error) {
+	scheme := string(st.SignatureScheme)
+	return client.New(node).SendTransaction(ctx, &blockchain.TransactionRequest{
+		SenderPublicKey:  &st.SenderPublicKey,
+		SenderAddress:    &st.SenderAddress,
+		RecipientAddress: &st.RecipientAddress,
+		Amount:           &st.Amount,
+		Fee:              &st.Fee,
+		Nonce:            &st.Nonce,
+		Signature:        &st.Signature,
+		SignatureScheme:  &scheme,
+	})
+}
+
+// broadcastResult is the output of the broadcast and send commands.
+type broadcastResult struct {
+	Message     string                    `json:"message"`
+	ID          string                    `json:"id"`
+	Transaction *wallet.SignedTransaction `json:"transaction"`
+}
+
+func printBroadcast(asJSON bool, id string, st *wallet.SignedTransaction) error {
+	result := broadcastResult{"Transaction posted to blockchain", id, st}
+	return output(asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Sent %v from %s to %s\n", st.Amount, st.SenderAddress, st.RecipientAddress)
+		if st.Fee > 0 {
+			fmt.Fprintf(w, "Fee: %v\n", st.Fee)
+		}
+		fmt.Fprintf(w, "Transaction ID: %s\n", id)
+	})
+}
+
+// balanceResult is the output of the balance command.
+type balanceResult struct {
+	Address string                     `json:"address"`
+	Balance *blockchain.AmountResponse `json:"balance"`
+}
+
+// runBalance prints the amount held by an address or a key.
+func runBalance(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ExitOnError)
+	bcAddress := fs.String("address", "", "address to query instead of the address of a key")
+	minConfirmations := fs.Int("min-conf", blockchain.DEFAULT_MIN_CONFIRMATIONS, "confirmations before funds count as confirmed")
+	node := addNodeFlag(fs)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	if *bcAddress == "" {
+		publicKey, err := kf.PublicKey()
+		if err != nil {
+			return err
+		}
+		*bcAddress = address.FromPublicKey(publicKey, network)
+	}
+	if err := address.Validate(*bcAddress, network); err != nil {
+		return err
+	}
+
+	amount, err := client.New(*node).Balance(ctx, *bcAddress, *minConfirmations)
+	if err != nil {
+		return err
+	}
+	result := balanceResult{*bcAddress, amount}
+	return output(*asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Address:\t%s\n", result.Address)
+		fmt.Fprintf(w, "Confirmed:\t%v\n", amount.Confirmed)
+		fmt.Fprintf(w, "Unconfirmed incoming:\t%v\n", amount.UnconfirmedIncoming)
+		fmt.Fprintf(w, "Unconfirmed outgoing:\t%v\n", amount.UnconfirmedOutgoing)
+		fmt.Fprintf(w, "Spendable:\t%v\n", amount.Spendable)
+		fmt.Fprintf(w, "Min confirmations:\t%d\n", amount.MinConfirmations)
+		fmt.Fprintf(w, "Next nonce:\t%d\n", amount.Nonce)
+	})
+}
+
+// runBroadcast submits a transaction signed with sign to a node.
+func runBroadcast(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("broadcast", flag.ExitOnError)
+	in := fs.String("in", "-", "signed transaction JSON file")
+	node := addNodeFlag(fs)
+	network := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	n, err := address.ParseNetwork(*network)
+	if err != nil {
+		return err
+	}
+	var st wallet.SignedTransaction
+	if err := readJSON(*in, &st); err != nil {
+		return err
+	}
+	if st.Signature == "" {
+		return errors.New("transaction is not signed")
+	}
+	if err := st.Verify(n); err != nil {
+		return err
+	}
+
+	id, err := postTransaction(ctx, *node, &st)
+	if err != nil {
+		return err
+	}
+	return printBroadcast(*asJSON, id, &st)
+}
+
+// runSend builds, signs and broadcasts a transaction in one step.
+func runSend(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount to send")
+	fee := fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount")
+	node := addNodeFlag(fs)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	nonce, err := nextNonce(ctx, *node, address.FromPublicKey(signer.Public(), network))
+	if err != nil {
+		return err
+	}
+	ut, err := buildTransaction(kf, *to, *amount, *fee, nonce)
+	if err != nil {
+		return err
+	}
+	st, err := ut.Sign(signer)
+	if err != nil {
+		return err
+	}
+
+	id, err := postTransaction(ctx, *node, st)
+	if err != nil {
+		return err
+	}
+	return printBroadcast(*asJSON, id, st)
+}
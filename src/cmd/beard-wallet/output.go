@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// readJSON() decodes JSON from the file, or from standard input when the path is "-".
+func readJSON(path string, v interface{}) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+// writeJSON() writes indented JSON to the file, or to standard output when the path is "-".
+func writeJSON(path string, v interface{}) error {
+	m, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	m = append(m, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(m)
+		return err
+	}
+	return os.WriteFile(path, m, 0600)
+}
+
+// addJSONFlag() adds the -json flag switching a command to machine readable output.
+func addJSONFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("json", false, "print the result as JSON")
+}
+
+// output() prints v as JSON when asJSON is set, and otherwise lets text write a human readable form.
+func output(asJSON bool, v interface{}, text func(w io.Writer)) error {
+	if asJSON {
+		return writeJSON("-", v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	text(tw)
+	return tw.Flush()
+}
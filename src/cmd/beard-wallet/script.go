@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/script"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// scriptCommands are the subcommands of the script command, which spend from addresses locked
+// by scripts: derive the address of a locking script, sign a spend and broadcast it with the
+// script that unlocks it. The secret, htlc and preimage commands cover the steps of an atomic swap.
+var scriptCommands = map[string]*command{
+	"key":       {"print the script encoding of a public key and its hash", runScriptKey},
+	"address":   {"print the address of a locking script", runScriptAddress},
+	"sign":      {"sign a spend from the address of a locking script", runScriptSign},
+	"broadcast": {"submit a spend with the script unlocking it to a node", runScriptBroadcast},
+	"secret":    {"generate a random secret and its hash for a hashed time lock", runScriptSecret},
+	"htlc":      {"print the locking script and address of a hashed time lock contract", runScriptHTLC},
+	"preimage":  {"find the secret revealed by a claim from a hashed time lock address", runScriptPreimage},
+}
+
+func scriptUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-wallet script <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(scriptCommands))
+	for name := range scriptCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, scriptCommands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Scripts are written as opcode names, decimal numbers and <hex> data, e.g.")
+	fmt.Fprintln(os.Stderr, "  DUP HASH160 <key hash> EQUALVERIFY CHECKSIG")
+}
+
+// addLocktimeFlag() adds the flag setting the lock time of a spend.
+func addLocktimeFlag(fs *flag.FlagSet) *int64 {
+	return fs.Int64("locktime", 0, "block height, or unix time from 500000000 on, before which the spend cannot be mined")
+}
+
+// runScript dispatches to a script subcommand.
+func runScript(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		scriptUsage()
+		os.Exit(2)
+	}
+	cmd, ok := scriptCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-wallet: unknown script command %q\n\n", args[0])
+		scriptUsage()
+		os.Exit(2)
+	}
+	return cmd.run(ctx, args[1:])
+}
+
+// scriptKeyInfo is the output of the script key command.
+type scriptKeyInfo struct {
+	Key     string `json:"key"`
+	KeyHash string `json:"key_hash"`
+}
+
+// runScriptKey prints the encoding scripts push a public key with, and its HASH160.
+func runScriptKey(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script key", flag.ExitOnError)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	publicKey, err := kf.PublicKey()
+	if err != nil {
+		return err
+	}
+	encoded := script.EncodeKey(publicKey)
+	info := scriptKeyInfo{hex.EncodeToString(encoded), hex.EncodeToString(address.Hash160(encoded))}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "Key:      %s\n", info.Key)
+		fmt.Fprintf(w, "Key hash: %s\n", info.KeyHash)
+	})
+}
+
+// parseLockScript() assembles the locking script given to -lock.
+func parseLockScript(asm string) (script.Script, error) {
+	if asm == "" {
+		return nil, errors.New("-lock is required")
+	}
+	return script.Parse(asm)
+}
+
+// scriptInfo is the output of the script address command.
+type scriptInfo struct {
+	Address    string        `json:"address"`
+	Network    string        `json:"network"`
+	LockScript script.Script `json:"lock_script"`
+	Asm        string        `json:"asm"`
+}
+
+// runScriptAddress prints the address funds locked by a script are sent to.
+func runScriptAddress(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script address", flag.ExitOnError)
+	lock := fs.String("lock", "", "locking script")
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	lockScript, err := parseLockScript(*lock)
+	if err != nil {
+		return err
+	}
+
+	info := scriptInfo{lockScript.Address(network), network.Name, lockScript, lockScript.String()}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintln(w, info.Address)
+	})
+}
+
+// runScriptSign prints a signature over a spend from the address of a locking script, to be
+// pushed by the unlocking script.
+func runScriptSign(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script sign", flag.ExitOnError)
+	lock := fs.String("lock", "", "locking script of the address to spend from")
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount to send")
+	fee := fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount")
+	locktime := addLocktimeFlag(fs)
+	nonce := addNonceFlag(fs)
+	kf := addKeyFlags(fs)
+	fs.Parse(args)
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	lockScript, err := parseLockScript(*lock)
+	if err != nil {
+		return err
+	}
+	if err := address.Validate(*to, network); err != nil {
+		return err
+	}
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	feeValue, err := wallet.ParseFee(*fee)
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+
+	t := wallet.NewTransaction(signer, lockScript.Address(network), *to, value, feeValue)
+	t.SetNonce(*nonce)
+	t.SetLocktime(*locktime)
+	fmt.Println(hex.EncodeToString(t.GenerateSignature()))
+	return nil
+}
+
+// runScriptBroadcast submits a spend from the address of a locking script.
+func runScriptBroadcast(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script broadcast", flag.ExitOnError)
+	lock := fs.String("lock", "", "locking script of the address to spend from")
+	unlock := fs.String("unlock", "", "unlocking script, which may only push data")
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount to send")
+	fee := fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount")
+	locktime := addLocktimeFlag(fs)
+	nonce := addNonceFlag(fs)
+	node := addNodeFlag(fs)
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	lockScript, err := parseLockScript(*lock)
+	if err != nil {
+		return err
+	}
+	unlockScript, err := script.Parse(*unlock)
+	if err != nil {
+		return err
+	}
+	if len(unlockScript) == 0 || !unlockScript.IsPushOnly() {
+		return errors.New("-unlock must push at least one item and only push data")
+	}
+	if err := address.Validate(*to, network); err != nil {
+		return err
+	}
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	feeValue, err := wallet.ParseFee(*fee)
+	if err != nil {
+		return err
+	}
+
+	sender := lockScript.Address(network)
+	id, err := client.New(*node).SendTransaction(ctx, &blockchain.TransactionRequest{
+		SenderAddress:    &sender,
+		RecipientAddress: to,
+		Amount:           &value,
+		Fee:              &feeValue,
+		Nonce:            nonce,
+		Locktime:         locktime,
+		LockScript:       lockScript,
+		UnlockScript:     unlockScript,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Message string `json:"message"`
+		ID      string `json:"id"`
+	}{"Transaction posted to blockchain", id}
+	return output(*asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Sent %v from %s to %s\n", value, sender, *to)
+		fmt.Fprintf(w, "Transaction ID: %s\n", id)
+	})
+}
+
+// secretInfo is the output of the script secret command.
+type secretInfo struct {
+	Secret string `json:"secret"`
+	Hash   string `json:"hash"`
+}
+
+// runScriptSecret prints a random secret and its sha256 hash, which the hashed time locks of both
+// sides of a swap are locked with.
+func runScriptSecret(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script secret", flag.ExitOnError)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	hash := sha256.Sum256(secret)
+	info := secretInfo{hex.EncodeToString(secret), hex.EncodeToString(hash[:])}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "Secret: %s\n", info.Secret)
+		fmt.Fprintf(w, "Hash:   %s\n", info.Hash)
+	})
+}
+
+// runScriptHTLC prints the locking script and address of a hashed time lock contract, which the
+// claim key can spend by revealing the secret and the refund key after the lock time.
+func runScriptHTLC(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script htlc", flag.ExitOnError)
+	hash := fs.String("hash", "", "hex encoded sha256 hash of the secret")
+	claimKey := fs.String("claim-key", "", "public key of the recipient, as scheme:hex")
+	refundKey := fs.String("refund-key", "", "public key of the sender, as scheme:hex")
+	locktime := fs.Int64("locktime", 0, "block height, or unix time from 500000000 on, from which the sender can refund")
+	networkName := fs.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		return err
+	}
+	hashBytes, err := hex.DecodeString(*hash)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return errors.New("-hash must be a hex encoded sha256 hash")
+	}
+	if *locktime <= 0 {
+		return errors.New("-locktime is required")
+	}
+	claim, err := parsePublicKeys(*claimKey)
+	if err != nil {
+		return err
+	}
+	refund, err := parsePublicKeys(*refundKey)
+	if err != nil {
+		return err
+	}
+	if len(claim) != 1 || len(refund) != 1 {
+		return errors.New("-claim-key and -refund-key must each be one public key")
+	}
+
+	lockScript := script.HashTimeLock(hashBytes, claim[0], *locktime, refund[0])
+	info := scriptInfo{lockScript.Address(network), network.Name, lockScript, lockScript.String()}
+	return output(*asJSON, info, func(w io.Writer) {
+		fmt.Fprintf(w, "Address: %s\n", info.Address)
+		fmt.Fprintf(w, "Script:  %s\n", info.Asm)
+	})
+}
+
+// runScriptPreimage looks through the spends from a hashed time lock address for an unlocking script
+// revealing the secret of the hash, which the other side of a swap claims its own lock with.
+func runScriptPreimage(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("script preimage", flag.ExitOnError)
+	bcAddress := fs.String("address", "", "address of the hashed time lock")
+	hash := fs.String("hash", "", "hex encoded sha256 hash of the secret")
+	node := addNodeFlag(fs)
+	fs.Parse(args)
+
+	hashBytes, err := hex.DecodeString(*hash)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return errors.New("-hash must be a hex encoded sha256 hash")
+	}
+
+	c := client.New(*node)
+	// pending spends reveal the secret as soon as they are broadcast
+	pending, err := c.Mempool(ctx)
+	if err != nil {
+		return err
+	}
+	candidates := pending
+	for cursor := ""; ; {
+		page, err := c.AddressHistory(ctx, *bcAddress, cursor, 0)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, page.Transactions...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	for _, t := range candidates {
+		if t.SenderAddress != *bcAddress {
+			continue
+		}
+		items, err := t.UnlockScript.PushedData()
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if digest := sha256.Sum256(item); bytes.Equal(digest[:], hashBytes) {
+				fmt.Println(hex.EncodeToString(item))
+				return nil
+			}
+		}
+	}
+	return errors.New("the secret has not been revealed yet")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// runSign signs an unsigned transaction from build or POST /transaction/build without any network access.
+func runSign(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	in := fs.String("in", "-", "unsigned transaction JSON file")
+	out := fs.String("out", "-", "file to write the signed transaction to")
+	kf := addKeyFlags(fs)
+	fs.Parse(args)
+
+	var ut wallet.UnsignedTransaction
+	if err := readJSON(*in, &ut); err != nil {
+		return err
+	}
+
+	signer, err := kf.Signer()
+	if err != nil {
+		return err
+	}
+	st, err := ut.Sign(signer)
+	if err != nil {
+		return err
+	}
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	if err := st.Verify(network); err != nil {
+		return err
+	}
+
+	return writeJSON(*out, st)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+// tokenCommands are the subcommands of the token command, which issue tokens and move them
+// between addresses.
+var tokenCommands = map[string]*command{
+	"issue":    {"issue a new token from a key", runTokenIssue},
+	"mint":     {"mint more of a mintable token issued by a key", runTokenMint},
+	"transfer": {"send an amount of a token", runTokenTransfer},
+	"burn":     {"destroy an amount of a token", runTokenBurn},
+	"balance":  {"query the token balances of an address from a node", runTokenBalance},
+	"info":     {"print the issuer and supply of a token", runTokenInfo},
+	"list":     {"list the tokens issued on the chain", runTokenList},
+}
+
+func tokenUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: beard-wallet token <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(tokenCommands))
+	for name := range tokenCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, tokenCommands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Token symbols are upper case letters and digits starting with a letter, e.g. GOLD.")
+	fmt.Fprintln(os.Stderr, "Fees are paid in coins.")
+}
+
+// runToken dispatches to a token subcommand.
+func runToken(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		tokenUsage()
+		os.Exit(2)
+	}
+	cmd, ok := tokenCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "beard-wallet: unknown token command %q\n\n", args[0])
+		tokenUsage()
+		os.Exit(2)
+	}
+	return cmd.run(ctx, args[1:])
+}
+
+// parseSymbol() checks a token symbol given to -symbol.
+func parseSymbol(symbol string) (string, error) {
+	if symbol == "" {
+		return "", errors.New("-symbol is required")
+	}
+	if !blockchain.ValidTokenSymbol(symbol) {
+		return "", errors.New("invalid token symbol: " + symbol)
+	}
+	return symbol, nil
+}
+
+// tokenResult is the output of the commands sending token transactions.
+type tokenResult struct {
+	Message   string  `json:"message"`
+	ID        string  `json:"id"`
+	Token     string  `json:"token"`
+	Action    string  `json:"action"`
+	Amount    float32 `json:"amount"`
+	Sender    string  `json:"sender_address"`
+	Recipient string  `json:"recipient_address"`
+	Fee       float32 `json:"fee"`
+}
+
+// tokenFlags are the flags shared by the commands sending token transactions.
+type tokenFlags struct {
+	symbol *string
+	fee    *string
+	node   *string
+	kf     *keyFlags
+	asJSON *bool
+}
+
+func addTokenFlags(fs *flag.FlagSet) *tokenFlags {
+	return &tokenFlags{
+		symbol: fs.String("symbol", "", "symbol of the token"),
+		fee:    fs.String("fee", "", "fee paid to the miner: none, economy, normal, priority or an amount"),
+		node:   addNodeFlag(fs),
+		kf:     addKeyFlags(fs),
+		asJSON: addJSONFlag(fs),
+	}
+}
+
+// sendToken() signs a token transaction with the key of the flags, sending the amount to the
+// recipient, or to the sender when it is empty, and submits it to the node.
+func sendToken(ctx context.Context, tf *tokenFlags, action, recipient string, amount float32, mintable bool) error {
+	network, err := tf.kf.Network()
+	if err != nil {
+		return err
+	}
+	symbol, err := parseSymbol(*tf.symbol)
+	if err != nil {
+		return err
+	}
+	fee, err := wallet.ParseFee(*tf.fee)
+	if err != nil {
+		return err
+	}
+	signer, err := tf.kf.Signer()
+	if err != nil {
+		return err
+	}
+
+	sender := address.FromPublicKey(signer.Public(), network)
+	if recipient == "" {
+		recipient = sender
+	}
+	if err := address.Validate(recipient, network); err != nil {
+		return err
+	}
+
+	nonce, err := nextNonce(ctx, *tf.node, sender)
+	if err != nil {
+		return err
+	}
+	t := wallet.NewTransaction(signer, sender, recipient, amount, fee)
+	t.SetNonce(nonce)
+	t.SetToken(symbol, action, mintable)
+	signature := hex.EncodeToString(t.GenerateSignature())
+
+	publicKey := keys.ToString(signer.Public())
+	scheme := string(signer.Scheme())
+	id, err := client.New(*tf.node).SendTransaction(ctx, &blockchain.TransactionRequest{
+		SenderAddress:    &sender,
+		RecipientAddress: &recipient,
+		Amount:           &amount,
+		Fee:              &fee,
+		Nonce:            &nonce,
+		SenderPublicKey:  &publicKey,
+		Signature:        &signature,
+		SignatureScheme:  &scheme,
+		Token:            symbol,
+		TokenAction:      action,
+		Mintable:         mintable,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := tokenResult{"Token transaction posted to blockchain", id, symbol, action, amount, sender, recipient, fee}
+	return output(*tf.asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Token:\t%s\n", result.Token)
+		fmt.Fprintf(w, "Action:\t%s\n", result.Action)
+		fmt.Fprintf(w, "Amount:\t%v\n", result.Amount)
+		fmt.Fprintf(w, "From:\t%s\n", result.Sender)
+		if action != blockchain.TokenBurn {
+			fmt.Fprintf(w, "To:\t%s\n", result.Recipient)
+		}
+		if result.Fee > 0 {
+			fmt.Fprintf(w, "Fee:\t%v\n", result.Fee)
+		}
+		fmt.Fprintf(w, "Transaction ID:\t%s\n", result.ID)
+	})
+}
+
+// runTokenIssue issues a token from the address of a key, which becomes its issuer.
+func runTokenIssue(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token issue", flag.ExitOnError)
+	supply := fs.String("supply", "", "initial supply of the token")
+	mintable := fs.Bool("mintable", false, "let the issuer mint more of the token later")
+	to := fs.String("to", "", "address receiving the initial supply (default the issuer)")
+	tf := addTokenFlags(fs)
+	fs.Parse(args)
+
+	var amount float32
+	if *supply != "" || !*mintable {
+		var err error
+		if amount, err = parseAmount(*supply); err != nil {
+			return err
+		}
+	}
+	return sendToken(ctx, tf, blockchain.TokenIssue, *to, amount, *mintable)
+}
+
+// runTokenMint mints more of a mintable token from the address of its issuer.
+func runTokenMint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token mint", flag.ExitOnError)
+	amount := fs.String("amount", "", "amount to mint")
+	to := fs.String("to", "", "address receiving the minted tokens (default the issuer)")
+	tf := addTokenFlags(fs)
+	fs.Parse(args)
+
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	return sendToken(ctx, tf, blockchain.TokenMint, *to, value, false)
+}
+
+// runTokenTransfer sends an amount of a token from the address of a key.
+func runTokenTransfer(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token transfer", flag.ExitOnError)
+	amount := fs.String("amount", "", "amount to send")
+	to := fs.String("to", "", "recipient address")
+	tf := addTokenFlags(fs)
+	fs.Parse(args)
+
+	if *to == "" {
+		return errors.New("-to is required")
+	}
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	return sendToken(ctx, tf, blockchain.TokenTransfer, *to, value, false)
+}
+
+// runTokenBurn destroys an amount of a token held by the address of a key.
+func runTokenBurn(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token burn", flag.ExitOnError)
+	amount := fs.String("amount", "", "amount to burn")
+	tf := addTokenFlags(fs)
+	fs.Parse(args)
+
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return err
+	}
+	return sendToken(ctx, tf, blockchain.TokenBurn, "", value, false)
+}
+
+// tokenBalanceResult is the output of the token balance command for all the tokens of an address.
+type tokenBalanceResult struct {
+	Address string             `json:"address"`
+	Tokens  map[string]float32 `json:"tokens"`
+}
+
+// runTokenBalance prints the balance of an address or a key in one token, or its confirmed
+// balances in every token it holds.
+func runTokenBalance(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token balance", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "symbol of the token (default every token held)")
+	bcAddress := fs.String("address", "", "address to query instead of the address of a key")
+	node := addNodeFlag(fs)
+	kf := addKeyFlags(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	network, err := kf.Network()
+	if err != nil {
+		return err
+	}
+	if *bcAddress == "" {
+		publicKey, err := kf.PublicKey()
+		if err != nil {
+			return err
+		}
+		*bcAddress = address.FromPublicKey(publicKey, network)
+	}
+	if err := address.Validate(*bcAddress, network); err != nil {
+		return err
+	}
+	c := client.New(*node)
+
+	if *symbol != "" {
+		if _, err := parseSymbol(*symbol); err != nil {
+			return err
+		}
+		b, err := c.TokenBalance(ctx, *bcAddress, *symbol)
+		if err != nil {
+			return err
+		}
+		return output(*asJSON, b, func(w io.Writer) {
+			fmt.Fprintf(w, "Address:\t%s\n", b.Address)
+			fmt.Fprintf(w, "Token:\t%s\n", b.Token)
+			fmt.Fprintf(w, "Confirmed:\t%v\n", b.Confirmed)
+			fmt.Fprintf(w, "Unconfirmed incoming:\t%v\n", b.UnconfirmedIncoming)
+			fmt.Fprintf(w, "Unconfirmed outgoing:\t%v\n", b.UnconfirmedOutgoing)
+			fmt.Fprintf(w, "Spendable:\t%v\n", b.Spendable)
+		})
+	}
+
+	summary, err := c.Address(ctx, *bcAddress)
+	if err != nil {
+		return err
+	}
+	result := tokenBalanceResult{*bcAddress, summary.Tokens}
+	if result.Tokens == nil {
+		result.Tokens = map[string]float32{}
+	}
+	return output(*asJSON, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Address:\t%s\n", result.Address)
+		symbols := make([]string, 0, len(result.Tokens))
+		for symbol := range result.Tokens {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		fmt.Fprintf(w, "Tokens:\t%d\n", len(symbols))
+		for _, symbol := range symbols {
+			fmt.Fprintf(w, "  %s\t%v\n", symbol, result.Tokens[symbol])
+		}
+	})
+}
+
+// runTokenInfo prints the issuer and supply of a token.
+func runTokenInfo(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token info", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "symbol of the token")
+	node := addNodeFlag(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	if _, err := parseSymbol(*symbol); err != nil {
+		return err
+	}
+	tk, err := client.New(*node).Token(ctx, *symbol)
+	if err != nil {
+		return err
+	}
+	return output(*asJSON, tk, func(w io.Writer) {
+		fmt.Fprintf(w, "Token:\t%s\n", tk.Symbol)
+		fmt.Fprintf(w, "Issuer:\t%s\n", tk.Issuer)
+		fmt.Fprintf(w, "Issued at:\t%d\n", tk.Height)
+		fmt.Fprintf(w, "Supply:\t%v\n", tk.Supply)
+		fmt.Fprintf(w, "Mintable:\t%t\n", tk.Mintable)
+		fmt.Fprintf(w, "Holders:\t%d\n", tk.Holders)
+	})
+}
+
+// runTokenList prints the tokens issued on the chain.
+func runTokenList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("token list", flag.ExitOnError)
+	node := addNodeFlag(fs)
+	asJSON := addJSONFlag(fs)
+	fs.Parse(args)
+
+	tokens, err := client.New(*node).Tokens(ctx)
+	if err != nil {
+		return err
+	}
+	return output(*asJSON, tokens, func(w io.Writer) {
+		fmt.Fprintln(w, "SYMBOL\tSUPPLY\tMINTABLE\tHOLDERS\tISSUER")
+		for _, tk := range tokens {
+			fmt.Fprintf(w, "%s\t%v\t%t\t%d\t%s\n", tk.Symbol, tk.Supply, tk.Mintable, tk.Holders, tk.Issuer)
+		}
+	})
+}
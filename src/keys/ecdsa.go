@@ -0,0 +1,211 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"math/big"
+
+	"github.com/Rha02/block-beard/src/utils"
+)
+
+type ecdsaPrivateKey struct {
+	scheme Scheme
+	key    *ecdsa.PrivateKey
+}
+
+type ecdsaPublicKey struct {
+	scheme Scheme
+	key    *ecdsa.PublicKey
+}
+
+// curveOf() returns the curve of an ECDSA scheme.
+func curveOf(scheme Scheme) elliptic.Curve {
+	if scheme == Secp256k1 {
+		return S256()
+	}
+	return elliptic.P256()
+}
+
+// randomScalar() returns a uniformly random integer in [1, n-1].
+func randomScalar(n *big.Int) (*big.Int, error) {
+	b := make([]byte, (n.BitLen()+7)/8+8)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, new(big.Int).Sub(n, big.NewInt(1)))
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+func generateECDSAKey(scheme Scheme) (Signer, error) {
+	n := curveOf(scheme).Params().N
+	d, err := randomScalar(n)
+	if err != nil {
+		return nil, err
+	}
+	return parseECDSAPrivateKey(scheme, d.FillBytes(make([]byte, (n.BitLen()+7)/8)))
+}
+
+func parseECDSAPrivateKey(scheme Scheme, b []byte) (Signer, error) {
+	key, err := utils.ParsePrivateKey(curveOf(scheme), b)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsaPrivateKey{scheme, key}, nil
+}
+
+func parseECDSAPublicKey(scheme Scheme, b []byte) (Verifier, error) {
+	key, err := utils.ParsePublicKey(curveOf(scheme), b)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsaPublicKey{scheme, key}, nil
+}
+
+// hashToInt() converts a message digest to an integer no longer than the curve order.
+func hashToInt(digest []byte, n *big.Int) *big.Int {
+	orderBytes := (n.BitLen() + 7) / 8
+	if len(digest) > orderBytes {
+		digest = digest[:orderBytes]
+	}
+	e := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	return e
+}
+
+func (k *ecdsaPrivateKey) Scheme() Scheme {
+	return k.scheme
+}
+
+func (k *ecdsaPrivateKey) Bytes() []byte {
+	return utils.PrivateKeyBytes(k.key)
+}
+
+func (k *ecdsaPrivateKey) Public() Verifier {
+	return &ecdsaPublicKey{k.scheme, &k.key.PublicKey}
+}
+
+// Sign() returns the compact signature of the SHA256 hash of the message using a
+// deterministic RFC 6979 nonce, so the same key and message always give the same signature.
+func (k *ecdsaPrivateKey) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	n := k.key.Curve.Params().N
+
+	for attempt := 0; ; attempt++ {
+		if sig := k.sign(digest[:], nonceRFC6979(k.key.D, n, digest[:], attempt)); sig != nil {
+			return sig, nil
+		}
+	}
+}
+
+// SignRandomized() returns the compact signature of the SHA256 hash of the message using a random nonce.
+func (k *ecdsaPrivateKey) SignRandomized(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	n := k.key.Curve.Params().N
+
+	for {
+		nonce, err := randomScalar(n)
+		if err != nil {
+			return nil, err
+		}
+		if sig := k.sign(digest[:], nonce); sig != nil {
+			return sig, nil
+		}
+	}
+}
+
+// sign() returns the low-S compact signature of a digest for the nonce, or nil if
+// the nonce yields a zero r or s.
+func (k *ecdsaPrivateKey) sign(digest []byte, nonce *big.Int) []byte {
+	n := k.key.Curve.Params().N
+	e := hashToInt(digest, n)
+
+	x, _ := k.key.Curve.ScalarBaseMult(int2octets(nonce, n))
+	r := new(big.Int).Mod(x, n)
+	if r.Sign() == 0 {
+		return nil
+	}
+
+	s := new(big.Int).Mul(r, k.key.D)
+	s.Add(s, e)
+	s.Mul(s, new(big.Int).ModInverse(nonce, n))
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil
+	}
+
+	// Use the lower of s and n - s so that every signature has a single valid encoding
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+
+	return (&utils.Signature{R: r, S: s}).Compact()
+}
+
+func (k *ecdsaPublicKey) Scheme() Scheme {
+	return k.scheme
+}
+
+// Bytes() returns the compressed SEC1 encoding of the key.
+func (k *ecdsaPublicKey) Bytes() []byte {
+	return utils.PublicKeyBytes(k.key, true)
+}
+
+// Raw() returns the fixed-width X||Y coordinates of the key.
+func (k *ecdsaPublicKey) Raw() []byte {
+	return utils.PublicKeyBytes(k.key, false)[1:]
+}
+
+// Verify() checks a compact signature of the SHA256 hash of the message. DER signatures and
+// signatures with a high S value are rejected as malleated: transaction IDs hash the signature
+// bytes, so every signature must have a single valid encoding.
+func (k *ecdsaPublicKey) Verify(message, signature []byte) bool {
+	sig, err := utils.SignatureFromCompact(signature)
+	if err != nil {
+		return false
+	}
+
+	curve := k.key.Curve
+	n := curve.Params().N
+	if sig.R.Cmp(n) >= 0 || sig.S.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		return false
+	}
+
+	digest := sha256.Sum256(message)
+	e := hashToInt(digest[:], n)
+
+	w := new(big.Int).ModInverse(sig.S, n)
+	u1 := new(big.Int).Mul(e, w)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(sig.R, w)
+	u2.Mod(u2, n)
+
+	size := (n.BitLen() + 7) / 8
+	x1, y1 := curve.ScalarBaseMult(u1.FillBytes(make([]byte, size)))
+	x2, y2 := curve.ScalarMult(k.key.X, k.key.Y, u2.FillBytes(make([]byte, size)))
+	x, y := addPoints(curve, x1, y1, x2, y2)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+
+	return x.Mod(x, n).Cmp(sig.R) == 0
+}
+
+// addPoints() adds two affine points, treating (0, 0) as the point at infinity.
+func addPoints(curve elliptic.Curve, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1.Sign() == 0 && y1.Sign() == 0 {
+		return x2, y2
+	}
+	if x2.Sign() == 0 && y2.Sign() == 0 {
+		return x1, y1
+	}
+	if x1.Cmp(x2) == 0 && y1.Cmp(y2) == 0 {
+		return curve.Double(x1, y1)
+	}
+	return curve.Add(x1, y1, x2, y2)
+}
@@ -0,0 +1,70 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+)
+
+type ed25519PrivateKey struct {
+	key ed25519.PrivateKey
+}
+
+type ed25519PublicKey struct {
+	key ed25519.PublicKey
+}
+
+func generateEd25519Key() (Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ed25519PrivateKey{key}, nil
+}
+
+// parseEd25519PrivateKey() accepts a 32-byte seed.
+func parseEd25519PrivateKey(b []byte) (Signer, error) {
+	if len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid private key: unexpected length %d", len(b))
+	}
+	return &ed25519PrivateKey{ed25519.NewKeyFromSeed(b)}, nil
+}
+
+func parseEd25519PublicKey(b []byte) (Verifier, error) {
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: unexpected length %d", len(b))
+	}
+	return &ed25519PublicKey{ed25519.PublicKey(append([]byte{}, b...))}, nil
+}
+
+func (k *ed25519PrivateKey) Scheme() Scheme {
+	return Ed25519
+}
+
+func (k *ed25519PrivateKey) Bytes() []byte {
+	return k.key.Seed()
+}
+
+func (k *ed25519PrivateKey) Public() Verifier {
+	return &ed25519PublicKey{k.key.Public().(ed25519.PublicKey)}
+}
+
+func (k *ed25519PrivateKey) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(k.key, message), nil
+}
+
+func (k *ed25519PublicKey) Scheme() Scheme {
+	return Ed25519
+}
+
+func (k *ed25519PublicKey) Bytes() []byte {
+	return append([]byte{}, k.key...)
+}
+
+func (k *ed25519PublicKey) Raw() []byte {
+	return k.Bytes()
+}
+
+func (k *ed25519PublicKey) Verify(message, signature []byte) bool {
+	return len(signature) == ed25519.SignatureSize && ed25519.Verify(k.key, message, signature)
+}
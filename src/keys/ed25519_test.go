@@ -0,0 +1,86 @@
+package keys
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The Ed25519 vectors of RFC 8032 section 7.1.
+var ed25519Vectors = []struct {
+	name, secret, public, message, signature string
+}{
+	{
+		name:      "TEST 1",
+		secret:    "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		public:    "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		message:   "",
+		signature: "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		name:      "TEST 2",
+		secret:    "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		public:    "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		message:   "72",
+		signature: "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+	{
+		name:      "TEST 3",
+		secret:    "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+		public:    "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+		message:   "af82",
+		signature: "6291d657deec24024827e69c3abe01a30ce548a284743a445e3680d7db5ac3ac18ff9b538d16f290ae67f760984dc6594a7c15e9716ed28dc027beceea1ec40a",
+	},
+}
+
+func TestEd25519RFC8032(t *testing.T) {
+	for _, v := range ed25519Vectors {
+		k, err := PrivateKeyFromString(Ed25519, v.secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ToString(k.Public()); got != v.public {
+			t.Errorf("%s: public key = %s, want %s", v.name, got, v.public)
+		}
+		if got := ToString(k); got != v.secret {
+			t.Errorf("%s: private key = %s, want %s", v.name, got, v.secret)
+		}
+
+		message, _ := hex.DecodeString(v.message)
+		sig, err := k.Sign(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(sig); got != v.signature {
+			t.Errorf("%s: signature = %s, want %s", v.name, got, v.signature)
+		}
+
+		pub, err := PublicKeyFromString(Ed25519, v.public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := hex.DecodeString(v.signature)
+		if !pub.Verify(message, want) {
+			t.Errorf("%s: signature does not verify", v.name)
+		}
+		if pub.Verify(append(message, 0), want) {
+			t.Errorf("%s: signature verifies for another message", v.name)
+		}
+		if pub.Verify(message, want[:len(want)-1]) {
+			t.Errorf("%s: truncated signature verifies", v.name)
+		}
+	}
+}
+
+func TestEd25519InvalidKeys(t *testing.T) {
+	for name, b := range map[string]string{
+		"short seed": "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f",
+		"long seed":  "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f6000",
+	} {
+		if _, err := PrivateKeyFromString(Ed25519, b); err == nil {
+			t.Errorf("private key with a %s parsed", name)
+		}
+		if _, err := PublicKeyFromString(Ed25519, b); err == nil {
+			t.Errorf("public key with a %s parsed", name)
+		}
+	}
+}
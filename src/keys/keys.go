@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Scheme identifies the signature algorithm of a key pair.
+type Scheme string
+
+const (
+	P256      Scheme = "p256"
+	Secp256k1 Scheme = "secp256k1"
+	Ed25519   Scheme = "ed25519"
+
+	// DefaultScheme is the scheme assumed when a transaction does not name one.
+	DefaultScheme = P256
+)
+
+var ErrUnknownScheme = errors.New("unknown signature scheme")
+
+// Verifier is a public key that checks signatures produced by its private key.
+type Verifier interface {
+	// Scheme() returns the signature scheme of the key.
+	Scheme() Scheme
+	// Bytes() returns the canonical encoding of the key.
+	Bytes() []byte
+	// Raw() returns the key material hashed into addresses.
+	Raw() []byte
+	// Verify() reports whether the signature over the message is valid.
+	Verify(message, signature []byte) bool
+}
+
+// Signer is a private key that signs messages.
+type Signer interface {
+	// Scheme() returns the signature scheme of the key.
+	Scheme() Scheme
+	// Bytes() returns the canonical encoding of the key.
+	Bytes() []byte
+	// Public() returns the public key of the signer.
+	Public() Verifier
+	// Sign() returns a deterministic signature over the message.
+	Sign(message []byte) ([]byte, error)
+}
+
+// RandomizedSigner is implemented by signers that can also sign with a random
+// nonce instead of their default deterministic one.
+type RandomizedSigner interface {
+	Signer
+	SignRandomized(message []byte) ([]byte, error)
+}
+
+// ParseScheme() converts a scheme name to a scheme, treating the empty name as the default scheme.
+func ParseScheme(s string) (Scheme, error) {
+	switch Scheme(s) {
+	case "":
+		return DefaultScheme, nil
+	case P256, Secp256k1, Ed25519:
+		return Scheme(s), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownScheme, s)
+}
+
+// GenerateKey() returns a new random private key of the scheme.
+func GenerateKey(scheme Scheme) (Signer, error) {
+	switch scheme {
+	case P256, Secp256k1:
+		return generateECDSAKey(scheme)
+	case Ed25519:
+		return generateEd25519Key()
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, scheme)
+}
+
+// ParsePrivateKey() decodes a private key of the scheme.
+func ParsePrivateKey(scheme Scheme, b []byte) (Signer, error) {
+	switch scheme {
+	case P256, Secp256k1:
+		return parseECDSAPrivateKey(scheme, b)
+	case Ed25519:
+		return parseEd25519PrivateKey(b)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, scheme)
+}
+
+// ParsePublicKey() decodes a public key of the scheme.
+func ParsePublicKey(scheme Scheme, b []byte) (Verifier, error) {
+	switch scheme {
+	case P256, Secp256k1:
+		return parseECDSAPublicKey(scheme, b)
+	case Ed25519:
+		return parseEd25519PublicKey(b)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, scheme)
+}
+
+// PrivateKeyFromString() decodes a hex encoded private key of the scheme.
+func PrivateKeyFromString(scheme Scheme, s string) (Signer, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	return ParsePrivateKey(scheme, b)
+}
+
+// PublicKeyFromString() decodes a hex encoded public key of the scheme.
+func PublicKeyFromString(scheme Scheme, s string) (Verifier, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	return ParsePublicKey(scheme, b)
+}
+
+// ToString() returns the hex encoding of a key's canonical bytes.
+func ToString(key interface{ Bytes() []byte }) string {
+	return hex.EncodeToString(key.Bytes())
+}
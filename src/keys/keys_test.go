@@ -0,0 +1,54 @@
+package keys
+
+import (
+	"testing"
+)
+
+// TestVerifyRejectsOtherSchemes signs with the same 32 bytes as the private key of every scheme, and
+// checks that no verifier accepts a signature made under another scheme.
+func TestVerifyRejectsOtherSchemes(t *testing.T) {
+	const secret = "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721"
+	message := []byte("sample")
+	schemes := []Scheme{P256, Secp256k1, Ed25519}
+
+	signatures := make(map[Scheme][]byte)
+	verifiers := make(map[Scheme]Verifier)
+	for _, scheme := range schemes {
+		k, err := PrivateKeyFromString(scheme, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signatures[scheme], err = k.Sign(message); err != nil {
+			t.Fatal(err)
+		}
+		verifiers[scheme] = k.Public()
+	}
+
+	for _, signer := range schemes {
+		for _, verifier := range schemes {
+			if got, want := verifiers[verifier].Verify(message, signatures[signer]), signer == verifier; got != want {
+				t.Errorf("%s verifier accepts a %s signature = %v, want %v", verifier, signer, got, want)
+			}
+		}
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		want    Scheme
+		wantErr bool
+	}{
+		{"", DefaultScheme, false},
+		{"p256", P256, false},
+		{"secp256k1", Secp256k1, false},
+		{"ed25519", Ed25519, false},
+		{"rsa", "", true},
+		{"P256", "", true},
+	} {
+		got, err := ParseScheme(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseScheme(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+)
+
+// int2octets() returns the big-endian encoding of v padded to the length of the curve order.
+func int2octets(v *big.Int, n *big.Int) []byte {
+	return v.FillBytes(make([]byte, (n.BitLen()+7)/8))
+}
+
+// bits2octets() reduces a digest modulo the curve order and encodes it like int2octets().
+func bits2octets(digest []byte, n *big.Int) []byte {
+	z := hashToInt(digest, n)
+	if z.Cmp(n) >= 0 {
+		z.Sub(z, n)
+	}
+	return int2octets(z, n)
+}
+
+// nonceRFC6979() derives the ECDSA nonce for a private key and digest with the
+// HMAC-SHA256 DRBG described in RFC 6979 section 3.2. The attempt argument
+// selects the next candidate when an earlier nonce produced a zero r or s.
+func nonceRFC6979(d *big.Int, n *big.Int, digest []byte, attempt int) *big.Int {
+	x := int2octets(d, n)
+	h1 := bits2octets(digest, n)
+
+	v := bytes.Repeat([]byte{0x01}, sha256.Size)
+	k := make([]byte, sha256.Size)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+		h := hmac.New(sha256.New, key)
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+
+	k = mac(k, v, []byte{0x00}, x, h1)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1)
+	v = mac(k, v)
+
+	qlen := (n.BitLen() + 7) / 8
+	for {
+		var t []byte
+		for len(t) < qlen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+
+		nonce := hashToInt(t[:qlen], n)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			if attempt == 0 {
+				return nonce
+			}
+			attempt--
+		}
+
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
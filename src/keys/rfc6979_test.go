@@ -0,0 +1,116 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/utils"
+)
+
+// The P-256 key and SHA-256 vectors of RFC 6979 appendix A.2.5.
+const (
+	rfc6979Key = "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721"
+	rfc6979Ux  = "60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6"
+	rfc6979Uy  = "7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299"
+)
+
+var rfc6979Vectors = []struct {
+	message string
+	k, r, s string
+}{
+	{
+		message: "sample",
+		k:       "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60",
+		r:       "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716",
+		s:       "f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+	},
+	{
+		message: "test",
+		k:       "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0",
+		r:       "f1abb023518351cd71d881567b1ea663ed3efcf6c5132b354f28d3b0b7d38367",
+		s:       "019f4113742a2b14bd25926b49c649155f267e60d3814b4c0cc84250e46f0083",
+	},
+}
+
+func rfc6979Signer(t *testing.T) *ecdsaPrivateKey {
+	t.Helper()
+	b, _ := hex.DecodeString(rfc6979Key)
+	signer, err := ParsePrivateKey(P256, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := signer.(*ecdsaPrivateKey)
+	if hex.EncodeToString(k.key.X.Bytes()) != rfc6979Ux || hex.EncodeToString(k.key.Y.Bytes()) != rfc6979Uy {
+		t.Fatalf("public key of the test vector key is %x, %x", k.key.X, k.key.Y)
+	}
+	return k
+}
+
+func TestNonceRFC6979(t *testing.T) {
+	k := rfc6979Signer(t)
+	n := k.key.Curve.Params().N
+	for _, v := range rfc6979Vectors {
+		digest := sha256.Sum256([]byte(v.message))
+		nonce := nonceRFC6979(k.key.D, n, digest[:], 0)
+		if got := hex.EncodeToString(int2octets(nonce, n)); got != v.k {
+			t.Errorf("nonce for %q = %s, want %s", v.message, got, v.k)
+		}
+	}
+}
+
+func TestSignRFC6979(t *testing.T) {
+	k := rfc6979Signer(t)
+	n := k.key.Curve.Params().N
+	for _, v := range rfc6979Vectors {
+		sig, err := k.Sign([]byte(v.message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(sig[:32]); got != v.r {
+			t.Errorf("r for %q = %s, want %s", v.message, got, v.r)
+		}
+
+		// Sign() returns the low-S form of the published signature
+		s, _ := new(big.Int).SetString(v.s, 16)
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			s.Sub(n, s)
+		}
+		if got, want := hex.EncodeToString(sig[32:]), hex.EncodeToString(int2octets(s, n)); got != want {
+			t.Errorf("s for %q = %s, want %s", v.message, got, want)
+		}
+
+		if !k.Public().Verify([]byte(v.message), sig) {
+			t.Errorf("signature for %q does not verify", v.message)
+		}
+		again, _ := k.Sign([]byte(v.message))
+		if hex.EncodeToString(again) != hex.EncodeToString(sig) {
+			t.Errorf("signatures for %q differ", v.message)
+		}
+	}
+}
+
+func TestVerifyRejectsMalleatedSignatures(t *testing.T) {
+	k := rfc6979Signer(t)
+	n := k.key.Curve.Params().N
+	message := []byte("sample")
+	compact, err := k.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := utils.SignatureFromCompact(compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	highS := &utils.Signature{R: sig.R, S: new(big.Int).Sub(n, sig.S)}
+	for name, b := range map[string][]byte{
+		"der":    sig.DER(),
+		"high s": highS.Compact(),
+	} {
+		if k.Public().Verify(message, b) {
+			t.Errorf("%s encoding of a valid signature verifies", name)
+		}
+	}
+}
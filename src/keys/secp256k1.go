@@ -0,0 +1,230 @@
+package keys
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+// secp256k1Curve implements elliptic.Curve for y^2 = x^3 + 7, which the generic
+// elliptic.CurveParams arithmetic (written for a = -3) cannot handle. Points are
+// kept in Jacobian coordinates internally. The arithmetic is not constant time.
+type secp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var (
+	secp256k1     *secp256k1Curve
+	secp256k1Once sync.Once
+)
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("keys: invalid curve constant " + s)
+	}
+	return n
+}
+
+// S256() returns the secp256k1 curve.
+func S256() elliptic.Curve {
+	secp256k1Once.Do(func() {
+		secp256k1 = &secp256k1Curve{&elliptic.CurveParams{
+			P:       hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+			N:       hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+			B:       big.NewInt(7),
+			Gx:      hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+			Gy:      hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+			BitSize: 256,
+			Name:    "secp256k1",
+		}}
+	})
+	return secp256k1
+}
+
+func (c *secp256k1Curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// YSquared() returns x^3 + 7 mod p.
+func (c *secp256k1Curve) YSquared(x *big.Int) *big.Int {
+	y2 := new(big.Int).Mul(x, x)
+	y2.Mul(y2, x)
+	y2.Add(y2, c.params.B)
+	return y2.Mod(y2, c.params.P)
+}
+
+func (c *secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+	return y2.Cmp(c.YSquared(x)) == 0
+}
+
+// jacobianPoint is a point (X/Z^2, Y/Z^3); Z = 0 is the point at infinity.
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func (c *secp256k1Curve) fromAffine(x, y *big.Int) *jacobianPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	return &jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (c *secp256k1Curve) toAffine(pt *jacobianPoint) (*big.Int, *big.Int) {
+	if pt.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	p := c.params.P
+	zInv := new(big.Int).ModInverse(pt.z, p)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(pt.x, zInv2)
+	x.Mod(x, p)
+	zInv2.Mul(zInv2, zInv)
+	y := new(big.Int).Mul(pt.y, zInv2)
+	y.Mod(y, p)
+	return x, y
+}
+
+// double() uses the dbl-2009-l formulas for a = 0.
+func (c *secp256k1Curve) double(pt *jacobianPoint) *jacobianPoint {
+	if pt.z.Sign() == 0 || pt.y.Sign() == 0 {
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	p := c.params.P
+
+	a := new(big.Int).Mul(pt.x, pt.x)
+	a.Mod(a, p)
+	b := new(big.Int).Mul(pt.y, pt.y)
+	b.Mod(b, p)
+	cc := new(big.Int).Mul(b, b)
+	cc.Mod(cc, p)
+
+	d := new(big.Int).Add(pt.x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, cc)
+	d.Lsh(d, 1)
+	d.Mod(d, p)
+
+	e := new(big.Int).Lsh(a, 1)
+	e.Add(e, a)
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(cc, 3))
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Mul(pt.y, pt.z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+
+	return &jacobianPoint{x3, y3, z3}
+}
+
+// add() uses the add-2007-bl formulas.
+func (c *secp256k1Curve) add(p1, p2 *jacobianPoint) *jacobianPoint {
+	if p1.z.Sign() == 0 {
+		return p2
+	}
+	if p2.z.Sign() == 0 {
+		return p1
+	}
+	p := c.params.P
+
+	z1z1 := new(big.Int).Mul(p1.z, p1.z)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(p2.z, p2.z)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(p1.x, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(p2.x, z1z1)
+	u2.Mod(u2, p)
+
+	s1 := new(big.Int).Mul(p1.y, p2.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(p2.y, p1.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.double(p1)
+		}
+		return &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	r.Lsh(r, 1)
+
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	i.Mod(i, p)
+	j := new(big.Int).Mul(h, i)
+	j.Mod(j, p)
+	v := new(big.Int).Mul(u1, i)
+	v.Mod(v, p)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	s1.Mul(s1, j)
+	s1.Lsh(s1, 1)
+	y3.Sub(y3, s1)
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Add(p1.z, p2.z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return &jacobianPoint{x3, y3, z3}
+}
+
+func (c *secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.add(c.fromAffine(x1, y1), c.fromAffine(x2, y2)))
+}
+
+func (c *secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.double(c.fromAffine(x1, y1)))
+}
+
+func (c *secp256k1Curve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	base := c.fromAffine(x1, y1)
+	acc := &jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			acc = c.double(acc)
+			if (b>>uint(bit))&1 == 1 {
+				acc = c.add(acc, base)
+			}
+		}
+	}
+
+	return c.toAffine(acc)
+}
+
+func (c *secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
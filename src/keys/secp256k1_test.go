@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// Multiples of the secp256k1 generator.
+var secp256k1Multiples = []struct {
+	k, x, y string
+}{
+	{
+		k: "01",
+		x: "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		y: "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+	},
+	{
+		k: "02",
+		x: "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+		y: "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a",
+	},
+	{
+		k: "03",
+		x: "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		y: "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+	},
+	{
+		k: "aa5e28d6a97a2479a65527f7290311a3624d4cc0fa1578598ee3c2613bf99522",
+		x: "34f9460f0e4f08393d192b3c5133a6ba099aa0ad9fd54ebccfacdfa239ff49c6",
+		y: "0b71ea9bd730fd8923f6d25a7a91e7dd7728a960686cb5a901bb419e0f2ca232",
+	},
+	{
+		k: "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+		x: "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		y: "b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+	},
+}
+
+// Deterministic secp256k1 signatures of SHA-256 digests, in low-S form.
+var secp256k1Signatures = []struct {
+	key, message, r, s string
+}{
+	{
+		key:     "0000000000000000000000000000000000000000000000000000000000000001",
+		message: "Satoshi Nakamoto",
+		r:       "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8",
+		s:       "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+	},
+	{
+		key:     "0000000000000000000000000000000000000000000000000000000000000001",
+		message: "All those moments will be lost in time, like tears in rain. Time to die...",
+		r:       "8600dbd41e348fe5c9465ab92d23e3db8b98b873beecd930736488696438cb6b",
+		s:       "547fe64427496db33bf66019dacbf0039c04199abb0122918601db38a72cfc21",
+	},
+}
+
+func TestSecp256k1ScalarMult(t *testing.T) {
+	curve := S256()
+	params := curve.Params()
+	for _, v := range secp256k1Multiples {
+		k, _ := hex.DecodeString(v.k)
+		for name, mult := range map[string]func() (*big.Int, *big.Int){
+			"ScalarBaseMult": func() (*big.Int, *big.Int) { return curve.ScalarBaseMult(k) },
+			"ScalarMult":     func() (*big.Int, *big.Int) { return curve.ScalarMult(params.Gx, params.Gy, k) },
+		} {
+			x, y := mult()
+			if got, want := hex.EncodeToString(x.FillBytes(make([]byte, 32))), v.x; got != want {
+				t.Errorf("%s(%s) x = %s, want %s", name, v.k, got, want)
+			}
+			if got, want := hex.EncodeToString(y.FillBytes(make([]byte, 32))), v.y; got != want {
+				t.Errorf("%s(%s) y = %s, want %s", name, v.k, got, want)
+			}
+			if !curve.IsOnCurve(x, y) {
+				t.Errorf("%s(%s) is not on the curve", name, v.k)
+			}
+		}
+	}
+
+	// the order of the group takes the generator to the point at infinity
+	if x, y := curve.ScalarBaseMult(params.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+		t.Errorf("ScalarBaseMult(N) = %x, %x, want the point at infinity", x, y)
+	}
+	x2, y2 := curve.Double(params.Gx, params.Gy)
+	if x, y := curve.Add(params.Gx, params.Gy, x2, y2); hex.EncodeToString(x.Bytes()) != secp256k1Multiples[2].x || hex.EncodeToString(y.Bytes()) != secp256k1Multiples[2].y {
+		t.Errorf("G + 2G = %x, %x, want 3G", x, y)
+	}
+	if curve.IsOnCurve(params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))) {
+		t.Error("point off the curve reported on it")
+	}
+}
+
+func TestSecp256k1Signatures(t *testing.T) {
+	for _, v := range secp256k1Signatures {
+		k, err := PrivateKeyFromString(Secp256k1, v.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := hex.DecodeString(v.r + v.s)
+
+		sig, err := k.Sign([]byte(v.message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(sig) != v.r+v.s {
+			t.Errorf("signature of %q = %x, want %s%s", v.message, sig, v.r, v.s)
+		}
+
+		// the signature verifies under the key parsed from either SEC1 encoding
+		for _, compressed := range []bool{true, false} {
+			b := k.Public().Bytes()
+			if !compressed {
+				b = append([]byte{0x04}, k.Public().Raw()...)
+			}
+			pub, err := ParsePublicKey(Secp256k1, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pub.Verify([]byte(v.message), want) {
+				t.Errorf("signature of %q does not verify under the key %x", v.message, b)
+			}
+			if pub.Verify([]byte(v.message+"!"), want) {
+				t.Errorf("signature of %q verifies for another message", v.message)
+			}
+		}
+
+		tampered := append([]byte{}, want...)
+		tampered[len(tampered)-1] ^= 1
+		if k.Public().Verify([]byte(v.message), tampered) {
+			t.Errorf("tampered signature of %q verifies", v.message)
+		}
+	}
+}
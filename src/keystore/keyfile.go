@@ -0,0 +1,207 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version is the version of the key file format written by this package.
+	Version = 1
+
+	cipherName = "aes-256-gcm"
+	kdfName    = "scrypt"
+
+	// Default scrypt cost parameters for newly encrypted keys.
+	DefaultScryptN = 1 << 15
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+
+	// Limits on the scrypt parameters of key files read from disk or imported, which bound the
+	// memory (128*N*r bytes) and time decrypting them takes.
+	MaxScryptN  = 1 << 18
+	MaxScryptRP = 16
+
+	scryptKeyLen = 32
+	saltLen      = 32
+)
+
+var (
+	ErrDecrypt            = errors.New("keystore: could not decrypt key with the given passphrase")
+	ErrUnsupportedVersion = errors.New("keystore: unsupported key file version")
+	ErrUnsupportedCrypto  = errors.New("keystore: unsupported cipher or key derivation function")
+	ErrKDFParams          = errors.New("keystore: scrypt parameters out of range")
+)
+
+// KDFParams is a struct for the scrypt parameters a key was encrypted with.
+type KDFParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// CryptoParams is a struct for the encrypted private key and how to decrypt it.
+type CryptoParams struct {
+	Cipher     string    `json:"cipher"`
+	CipherText string    `json:"ciphertext"`
+	Nonce      string    `json:"nonce"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+}
+
+// KeyFile is a struct for a passphrase-encrypted private key as stored on disk.
+type KeyFile struct {
+	Version   int          `json:"version"`
+	ID        string       `json:"id"`
+	Address   string       `json:"address"`
+	Scheme    keys.Scheme  `json:"signature_scheme"`
+	PublicKey string       `json:"public_key"`
+	CreatedAt time.Time    `json:"created_at"`
+	Crypto    CryptoParams `json:"crypto"`
+}
+
+// newID() returns a random version 4 UUID.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// additionalData() binds the ciphertext to the identity of the key file.
+func (kf *KeyFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d:%s:%s:%s", kf.Version, kf.ID, kf.Scheme, kf.PublicKey))
+}
+
+// aead() derives the encryption key from the passphrase and returns the cipher. The scrypt
+// parameters come from the key file, so they are checked before any work is done.
+func (kf *KeyFile) aead(passphrase string) (cipher.AEAD, error) {
+	params := kf.Crypto.KDFParams
+	if params.DKLen != scryptKeyLen {
+		return nil, fmt.Errorf("%w: derived key length must be %d", ErrKDFParams, scryptKeyLen)
+	}
+	if params.N <= 1 || params.N > MaxScryptN || params.N&(params.N-1) != 0 {
+		return nil, fmt.Errorf("%w: N must be a power of two up to %d", ErrKDFParams, MaxScryptN)
+	}
+	if params.R <= 0 || params.P <= 0 || params.R > MaxScryptRP || params.P > MaxScryptRP/params.R {
+		return nil, fmt.Errorf("%w: r*p must be at most %d", ErrKDFParams, MaxScryptRP)
+	}
+
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt() encrypts the private key with the passphrase using fresh salt and nonce.
+func (kf *KeyFile) encrypt(signer keys.Signer, passphrase string) error {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	kf.Crypto = CryptoParams{
+		Cipher: cipherName,
+		KDF:    kdfName,
+		KDFParams: KDFParams{
+			N:     DefaultScryptN,
+			R:     DefaultScryptR,
+			P:     DefaultScryptP,
+			DKLen: scryptKeyLen,
+			Salt:  hex.EncodeToString(salt),
+		},
+	}
+
+	aead, err := kf.aead(passphrase)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	kf.Crypto.Nonce = hex.EncodeToString(nonce)
+	kf.Crypto.CipherText = hex.EncodeToString(aead.Seal(nil, nonce, signer.Bytes(), kf.additionalData()))
+	return nil
+}
+
+// Decrypt() returns the private key of the key file.
+func (kf *KeyFile) Decrypt(passphrase string) (keys.Signer, error) {
+	if kf.Version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if kf.Crypto.Cipher != cipherName || kf.Crypto.KDF != kdfName {
+		return nil, ErrUnsupportedCrypto
+	}
+
+	aead, err := kf.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hex.DecodeString(kf.Crypto.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	ciphertext, err := hex.DecodeString(kf.Crypto.CipherText)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, kf.additionalData())
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	signer, err := keys.ParsePrivateKey(kf.Scheme, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if keys.ToString(signer.Public()) != kf.PublicKey {
+		return nil, ErrDecrypt
+	}
+	return signer, nil
+}
+
+// ParseKeyFile() decodes the JSON representation of a key file.
+func ParseKeyFile(data []byte) (*KeyFile, error) {
+	var kf KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, err
+	}
+	if kf.Version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if kf.ID == "" || kf.PublicKey == "" {
+		return nil, errors.New("keystore: key file is missing its id or public key")
+	}
+	return &kf, nil
+}
@@ -0,0 +1,264 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+// DefaultUnlockTimeout is how long a key stays unlocked when no timeout is given.
+const DefaultUnlockTimeout = 5 * time.Minute
+
+var (
+	ErrNotFound = errors.New("keystore: key not found")
+	ErrLocked   = errors.New("keystore: key is locked")
+	ErrExists   = errors.New("keystore: key already exists")
+)
+
+type unlockedKey struct {
+	signer keys.Signer
+	timer  *time.Timer
+}
+
+// Keystore is a struct for a directory of encrypted key files.
+type Keystore struct {
+	dir      string
+	network  *address.Network
+	mux      sync.Mutex
+	unlocked map[string]*unlockedKey
+}
+
+// New() returns a pointer to a keystore backed by the directory, creating it if needed.
+func New(dir string, network *address.Network) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Keystore{
+		dir:      dir,
+		network:  network,
+		unlocked: make(map[string]*unlockedKey),
+	}, nil
+}
+
+func (ks *Keystore) path(id string) string {
+	return filepath.Join(ks.dir, id+".json")
+}
+
+// write() atomically stores the key file in the keystore directory.
+func (ks *Keystore) write(kf *KeyFile) error {
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(ks.dir, "."+kf.ID+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ks.path(kf.ID))
+}
+
+// Get() returns the key file with the id.
+func (ks *Keystore) Get(id string) (*KeyFile, error) {
+	if strings.ContainsAny(id, `/\.`) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(ks.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyFile(data)
+}
+
+// List() returns all key files in the keystore ordered by creation time.
+func (ks *Keystore) List() ([]*KeyFile, error) {
+	matches, err := filepath.Glob(filepath.Join(ks.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*KeyFile, 0, len(matches))
+	for _, m := range matches {
+		data, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+		kf, err := ParseKeyFile(data)
+		if err != nil {
+			fmt.Printf("Skipping invalid key file %s: %v\n", m, err)
+			continue
+		}
+		res = append(res, kf)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res, nil
+}
+
+// Create() generates a new key of the scheme and stores it encrypted with the passphrase.
+func (ks *Keystore) Create(scheme keys.Scheme, passphrase string) (*KeyFile, error) {
+	signer, err := keys.GenerateKey(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Import(signer, passphrase)
+}
+
+// Import() stores an existing private key encrypted with the passphrase.
+func (ks *Keystore) Import(signer keys.Signer, passphrase string) (*KeyFile, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := signer.Public()
+	kf := &KeyFile{
+		Version:   Version,
+		ID:        id,
+		Address:   address.FromPublicKey(publicKey, ks.network),
+		Scheme:    signer.Scheme(),
+		PublicKey: keys.ToString(publicKey),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := kf.encrypt(signer, passphrase); err != nil {
+		return nil, err
+	}
+
+	if err := ks.write(kf); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// ImportFile() stores a key file exported from another keystore as is.
+func (ks *Keystore) ImportFile(data []byte) (*KeyFile, error) {
+	kf, err := ParseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ks.Get(kf.ID); err == nil {
+		return nil, ErrExists
+	}
+
+	publicKey, err := keys.PublicKeyFromString(kf.Scheme, kf.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	kf.Address = address.FromPublicKey(publicKey, ks.network)
+
+	if err := ks.write(kf); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Export() returns the encrypted JSON key file with the id.
+func (ks *Keystore) Export(id string) ([]byte, error) {
+	kf, err := ks.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(kf, "", "  ")
+}
+
+// Unlock() decrypts the key with the id and keeps it available for signing until the timeout passes.
+func (ks *Keystore) Unlock(id, passphrase string, timeout time.Duration) error {
+	kf, err := ks.Get(id)
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Decrypt(passphrase)
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultUnlockTimeout
+	}
+
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	if u, ok := ks.unlocked[id]; ok {
+		u.timer.Stop()
+	}
+	u := &unlockedKey{signer: signer}
+	u.timer = time.AfterFunc(timeout, func() {
+		ks.mux.Lock()
+		defer ks.mux.Unlock()
+		if ks.unlocked[id] == u {
+			delete(ks.unlocked, id)
+		}
+	})
+	ks.unlocked[id] = u
+	return nil
+}
+
+// Lock() forgets the decrypted key with the id.
+func (ks *Keystore) Lock(id string) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	if u, ok := ks.unlocked[id]; ok {
+		u.timer.Stop()
+		delete(ks.unlocked, id)
+	}
+}
+
+// IsUnlocked() returns whether the key with the id can currently sign.
+func (ks *Keystore) IsUnlocked(id string) bool {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	_, ok := ks.unlocked[id]
+	return ok
+}
+
+// Signer() returns the unlocked private key with the id.
+func (ks *Keystore) Signer(id string) (keys.Signer, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	u, ok := ks.unlocked[id]
+	if !ok {
+		return nil, ErrLocked
+	}
+	return u.signer, nil
+}
+
+// ChangePassphrase() re-encrypts the key with the id under a new passphrase.
+func (ks *Keystore) ChangePassphrase(id, oldPassphrase, newPassphrase string) error {
+	kf, err := ks.Get(id)
+	if err != nil {
+		return err
+	}
+	signer, err := kf.Decrypt(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := kf.encrypt(signer, newPassphrase); err != nil {
+		return err
+	}
+	return ks.write(kf)
+}
@@ -0,0 +1,174 @@
+package multisig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+// MAX_KEYS is the largest number of keys a policy can have.
+const MAX_KEYS = 15
+
+var (
+	ErrThreshold        = errors.New("multisig: not enough valid signatures")
+	ErrInvalidSignature = errors.New("multisig: invalid signature")
+	ErrUnknownKey       = errors.New("multisig: key is not part of the policy")
+	ErrUnsorted         = errors.New("multisig: public keys are not in canonical order")
+)
+
+// Policy is an m-of-n policy: any M of its public keys together can spend from its address.
+// The keys are kept in canonical order, which is also the order of a transaction's signatures.
+type Policy struct {
+	M          int
+	PublicKeys []keys.Verifier
+}
+
+// policyKey is the json representation of a public key of a policy.
+type policyKey struct {
+	Scheme    keys.Scheme `json:"signature_scheme"`
+	PublicKey string      `json:"public_key"`
+}
+
+// NewPolicy() returns a pointer to an m-of-n policy over the public keys, sorted into canonical order.
+func NewPolicy(m int, publicKeys []keys.Verifier) (*Policy, error) {
+	n := len(publicKeys)
+	if n == 0 || n > MAX_KEYS {
+		return nil, fmt.Errorf("multisig: a policy needs 1 to %d keys, got %d", MAX_KEYS, n)
+	}
+	if m < 1 || m > n {
+		return nil, fmt.Errorf("multisig: threshold must be between 1 and %d, got %d", n, m)
+	}
+
+	sorted := make([]keys.Verifier, n)
+	copy(sorted, publicKeys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return keyLess(sorted[i], sorted[j])
+	})
+	for i := 1; i < n; i++ {
+		if !keyLess(sorted[i-1], sorted[i]) {
+			return nil, errors.New("multisig: duplicate public key")
+		}
+	}
+
+	return &Policy{M: m, PublicKeys: sorted}, nil
+}
+
+// keyLess() orders keys by scheme and then by their encoding.
+func keyLess(a, b keys.Verifier) bool {
+	if a.Scheme() != b.Scheme() {
+		return a.Scheme() < b.Scheme()
+	}
+	return bytes.Compare(a.Bytes(), b.Bytes()) < 0
+}
+
+// N() returns the number of keys of the policy.
+func (p *Policy) N() int {
+	return len(p.PublicKeys)
+}
+
+// Bytes() returns the canonical encoding of the policy, which its address commits to.
+func (p *Policy) Bytes() []byte {
+	b := []byte{byte(p.M), byte(p.N())}
+	for _, k := range p.PublicKeys {
+		b = append(b, byte(len(k.Scheme())))
+		b = append(b, k.Scheme()...)
+		b = append(b, byte(len(k.Bytes())))
+		b = append(b, k.Bytes()...)
+	}
+	return b
+}
+
+// Address() returns the script hash address of the policy on the network.
+func (p *Policy) Address(network *address.Network) string {
+	return address.FromScript(p.Bytes(), network)
+}
+
+// Index() returns the position of the public key in the policy.
+func (p *Policy) Index(publicKey keys.Verifier) (int, error) {
+	for i, k := range p.PublicKeys {
+		if k.Scheme() == publicKey.Scheme() && bytes.Equal(k.Bytes(), publicKey.Bytes()) {
+			return i, nil
+		}
+	}
+	return 0, ErrUnknownKey
+}
+
+// Verify() checks that at least M of the signatures are valid signatures of the message.
+// Signatures are given by key position, with nil for the keys that did not sign; a signature
+// that is present but invalid fails the whole check.
+func (p *Policy) Verify(message []byte, signatures [][]byte) error {
+	if len(signatures) != p.N() {
+		return fmt.Errorf("multisig: expected %d signature slots, got %d", p.N(), len(signatures))
+	}
+
+	valid := 0
+	for i, signature := range signatures {
+		if len(signature) == 0 {
+			continue
+		}
+		if !p.PublicKeys[i].Verify(message, signature) {
+			return ErrInvalidSignature
+		}
+		valid++
+	}
+	if valid < p.M {
+		return ErrThreshold
+	}
+	return nil
+}
+
+// MarshalJSON() returns the json representation of the policy.
+func (p *Policy) MarshalJSON() ([]byte, error) {
+	publicKeys := make([]policyKey, 0, p.N())
+	for _, k := range p.PublicKeys {
+		publicKeys = append(publicKeys, policyKey{k.Scheme(), keys.ToString(k)})
+	}
+	return json.Marshal(struct {
+		M          int         `json:"m"`
+		PublicKeys []policyKey `json:"public_keys"`
+	}{
+		M:          p.M,
+		PublicKeys: publicKeys,
+	})
+}
+
+// UnmarshalJSON() decodes a policy, which must list its keys in canonical order.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var tmp struct {
+		M          int         `json:"m"`
+		PublicKeys []policyKey `json:"public_keys"`
+	}
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	publicKeys := make([]keys.Verifier, 0, len(tmp.PublicKeys))
+	for _, k := range tmp.PublicKeys {
+		scheme, err := keys.ParseScheme(string(k.Scheme))
+		if err != nil {
+			return err
+		}
+		publicKey, err := keys.PublicKeyFromString(scheme, k.PublicKey)
+		if err != nil {
+			return err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	policy, err := NewPolicy(tmp.M, publicKeys)
+	if err != nil {
+		return err
+	}
+	for i, k := range policy.PublicKeys {
+		if k != publicKeys[i] {
+			return ErrUnsorted
+		}
+	}
+	*p = *policy
+	return nil
+}
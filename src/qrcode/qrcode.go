@@ -0,0 +1,537 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Level is an error correction level; higher levels survive more damage but hold less data.
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// QUIET_ZONE is the width in modules of the blank border required around a symbol.
+const QUIET_ZONE = 4
+
+// MAX_VERSION is the largest symbol version the encoder produces.
+const MAX_VERSION = 10
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// formatBits are the error correction level bits of the format information.
+var formatBits = [...]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+// blockLayout describes how the codewords of a version and level are split into blocks.
+type blockLayout struct {
+	ecPerBlock   int
+	group1Blocks int
+	group1Data   int
+	group2Blocks int
+	group2Data   int
+}
+
+func (b blockLayout) dataCodewords() int {
+	return b.group1Blocks*b.group1Data + b.group2Blocks*b.group2Data
+}
+
+// layouts holds the block layout of each version by level (ISO/IEC 18004 table 9).
+var layouts = [MAX_VERSION + 1][4]blockLayout{
+	1:  {{7, 1, 19, 0, 0}, {10, 1, 16, 0, 0}, {13, 1, 13, 0, 0}, {17, 1, 9, 0, 0}},
+	2:  {{10, 1, 34, 0, 0}, {16, 1, 28, 0, 0}, {22, 1, 22, 0, 0}, {28, 1, 16, 0, 0}},
+	3:  {{15, 1, 55, 0, 0}, {26, 1, 44, 0, 0}, {18, 2, 17, 0, 0}, {22, 2, 13, 0, 0}},
+	4:  {{20, 1, 80, 0, 0}, {18, 2, 32, 0, 0}, {26, 2, 24, 0, 0}, {16, 4, 9, 0, 0}},
+	5:  {{26, 1, 108, 0, 0}, {24, 2, 43, 0, 0}, {18, 2, 15, 2, 16}, {22, 2, 11, 2, 12}},
+	6:  {{18, 2, 68, 0, 0}, {16, 4, 27, 0, 0}, {24, 4, 19, 0, 0}, {28, 4, 15, 0, 0}},
+	7:  {{20, 2, 78, 0, 0}, {18, 4, 31, 0, 0}, {18, 2, 14, 4, 15}, {26, 4, 13, 1, 14}},
+	8:  {{24, 2, 97, 0, 0}, {22, 2, 38, 2, 39}, {22, 4, 18, 2, 19}, {26, 4, 14, 2, 15}},
+	9:  {{30, 2, 116, 0, 0}, {22, 3, 36, 2, 37}, {20, 4, 16, 4, 17}, {24, 4, 12, 4, 13}},
+	10: {{18, 2, 68, 2, 69}, {26, 4, 43, 1, 44}, {24, 6, 19, 2, 20}, {28, 6, 15, 2, 16}},
+}
+
+// alignmentPositions holds the centers of the alignment patterns of each version.
+var alignmentPositions = [MAX_VERSION + 1][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// Code is a QR code symbol.
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	// modules[y][x] is set for dark modules.
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encode() returns the smallest symbol holding the data in byte mode at the error correction level.
+func Encode(data []byte, level Level) (*Code, error) {
+	version := 1
+	for ; version <= MAX_VERSION; version++ {
+		if bitsNeeded(version, len(data)) <= layouts[version][level].dataCodewords()*8 {
+			break
+		}
+	}
+	if version > MAX_VERSION {
+		return nil, ErrTooLong
+	}
+
+	size := version*4 + 17
+	c := &Code{Version: version, Level: level, Size: size}
+	c.modules = make([][]bool, size)
+	c.isFunction = make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.addErrorCorrection(encodeData(data, version, level)))
+	c.applyBestMask()
+	return c, nil
+}
+
+// Dark() reports whether the module at column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// countBits() returns the width of the character count of byte mode in a version.
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func bitsNeeded(version, n int) int {
+	return 4 + countBits(version) + 8*n
+}
+
+// bitBuffer accumulates bits most significant first.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// encodeData() returns the data codewords: the byte mode segment, the terminator and the padding.
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := layouts[version][level].dataCodewords() * 8
+
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, d := range data {
+		bits.append(int(d), 8)
+	}
+
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xec); len(codewords) < capacity/8; pad ^= 0xec ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// addErrorCorrection() splits the data into blocks, appends their error correction codewords,
+// and interleaves the result.
+func (c *Code) addErrorCorrection(data []byte) []byte {
+	layout := layouts[c.Version][c.Level]
+	divisor := rsDivisor(layout.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	for i := 0; i < layout.group1Blocks+layout.group2Blocks; i++ {
+		n := layout.group1Data
+		if i >= layout.group1Blocks {
+			n = layout.group2Data
+		}
+		block := data[:n]
+		data = data[n:]
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+
+	var res []byte
+	for i := 0; i < layout.group2Data || i < layout.group1Data; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				res = append(res, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			res = append(res, block[i])
+		}
+	}
+	return res
+}
+
+// gfMultiply() multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11d)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor() returns the generator polynomial of the degree, without its leading coefficient.
+func rsDivisor(degree int) []byte {
+	res := make([]byte, degree)
+	res[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range res {
+			res[j] = gfMultiply(res[j], root)
+			if j+1 < len(res) {
+				res[j] ^= res[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return res
+}
+
+// rsRemainder() returns the error correction codewords of the data.
+func rsRemainder(data, divisor []byte) []byte {
+	res := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ res[0]
+		copy(res, res[1:])
+		res[len(res)-1] = 0
+		for i, d := range divisor {
+			res[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return res
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns() draws the finder, timing and alignment patterns and reserves the
+// format and version areas.
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions[c.Version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// alignment patterns never overlap the finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// drawFinder() draws a finder pattern and its separator around the center.
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment() draws an alignment pattern around the center.
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits() draws both copies of the format information for the mask.
+func (c *Code) drawFormatBits(mask int) {
+	data := formatBits[c.Level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool {
+		return (bits>>i)&1 == 1
+	}
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersionBits() draws both copies of the version information, present from version 7.
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1f25)
+	}
+	bits := c.Version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords() places the codewords in the zigzag order, two columns at a time from the bottom right.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// skip the vertical timing pattern
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// masked() reports whether the mask pattern inverts the module at column x and row y.
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask() inverts the data modules selected by the mask; applying it twice undoes it.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunction[y][x] && masked(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask() applies the mask with the lowest penalty.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+// penalty() scores the symbol by the rules of ISO/IEC 18004 7.8.3; lower is easier to read.
+func (c *Code) penalty() int {
+	res := 0
+	line := make([]bool, c.Size)
+	for _, vertical := range []bool{false, true} {
+		for i := 0; i < c.Size; i++ {
+			for j := 0; j < c.Size; j++ {
+				if vertical {
+					line[j] = c.modules[j][i]
+				} else {
+					line[j] = c.modules[i][j]
+				}
+			}
+			res += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				m := c.modules[y][x]
+				if m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+					res += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (c.Size * c.Size)
+	res += abs(percent-50) / 5 * 10
+	return res
+}
+
+// finderLike is the 1:1:3:1:1 ratio of a finder pattern, which must not appear in the data.
+var finderLike = []bool{true, false, true, true, true, false, true}
+
+// linePenalty() scores runs of one color and finder-like patterns in a row or column.
+func linePenalty(line []bool) int {
+	res := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			res += 3 + run - 5
+		}
+		run = 1
+	}
+
+	light := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if i >= 0 && i < len(line) && line[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i+len(finderLike) <= len(line); i++ {
+		match := true
+		for j, dark := range finderLike {
+			if line[i+j] != dark {
+				match = false
+				break
+			}
+		}
+		if match && (light(i-4, i) || light(i+len(finderLike), i+len(finderLike)+4)) {
+			res += 40
+		}
+	}
+	return res
+}
+
+// Image() returns the symbol with its quiet zone, each module scale pixels wide.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (c.Size + 2*QUIET_ZONE) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+QUIET_ZONE)*scale+dx, (y+QUIET_ZONE)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG() returns the symbol encoded as a PNG image, each module scale pixels wide.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
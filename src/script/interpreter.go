@@ -0,0 +1,500 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+var (
+	ErrScriptSize           = errors.New("script: script is too large")
+	ErrElementSize          = errors.New("script: pushed item is too large")
+	ErrOpCount              = errors.New("script: too many opcodes")
+	ErrStackSize            = errors.New("script: stack is too large")
+	ErrStackUnderflow       = errors.New("script: not enough items on the stack")
+	ErrUnknownOpcode        = errors.New("script: unknown opcode")
+	ErrUnbalancedIf         = errors.New("script: unbalanced conditional")
+	ErrVerify               = errors.New("script: verify failed")
+	ErrReturn               = errors.New("script: OP_RETURN executed")
+	ErrNumSize              = errors.New("script: number is too long")
+	ErrMinimalData          = errors.New("script: number is not minimally encoded")
+	ErrPublicKey            = errors.New("script: invalid public key")
+	ErrMultisigKeyCount     = errors.New("script: invalid number of multisig keys")
+	ErrMultisigSigCount     = errors.New("script: invalid number of multisig signatures")
+	ErrNegativeLocktime     = errors.New("script: negative lock time")
+	ErrUnsatisfiedLocktime  = errors.New("script: lock time not reached")
+	ErrUnsatisfiedSequence  = errors.New("script: relative lock time not reached")
+	ErrUnlockNotPushOnly    = errors.New("script: unlocking script must only push data")
+	ErrEvalFalse            = errors.New("script: script finished with a false result")
+	ErrCleanStack           = errors.New("script: script must leave exactly one item on the stack")
+	ErrNullFail             = errors.New("script: failed signature check with a non-empty signature")
+	ErrUnlockingScriptEmpty = errors.New("script: no unlocking script")
+)
+
+// Context is what a script can learn about the spend it authorizes.
+type Context struct {
+	// Message is the signing payload every signature is checked against.
+	Message []byte
+	// Height is the height of the block the spend is included in.
+	Height int
+	// Time is the unix time, in seconds, of the block before it, which lock times are compared with.
+	Time int64
+	// Age is the number of blocks since the spending address received the funds the spend uses,
+	// taking funds in the order they were received.
+	Age int
+}
+
+// engine is the state of a running script.
+type engine struct {
+	ctx   *Context
+	stack [][]byte
+	// exec holds one entry per open conditional, telling whether its branch is taken.
+	exec []bool
+	ops  int
+}
+
+// Execute() runs the unlocking script followed by the locking script and returns an error unless
+// the spend is authorized. The unlocking script may only push data, and the locking script must
+// leave a single true item on the stack. Execution is deterministic and bounded by the script size,
+// opcode and stack limits.
+func Execute(unlock, lock Script, ctx *Context) error {
+	if len(unlock) == 0 {
+		return ErrUnlockingScriptEmpty
+	}
+	if len(unlock) > MAX_SCRIPT_SIZE || len(lock) > MAX_SCRIPT_SIZE {
+		return ErrScriptSize
+	}
+	if !unlock.IsPushOnly() {
+		return ErrUnlockNotPushOnly
+	}
+
+	e := &engine{ctx: ctx}
+	if err := e.run(unlock); err != nil {
+		return err
+	}
+	if err := e.run(lock); err != nil {
+		return err
+	}
+
+	if len(e.stack) == 0 || !asBool(e.stack[len(e.stack)-1]) {
+		return ErrEvalFalse
+	}
+	if len(e.stack) != 1 {
+		return ErrCleanStack
+	}
+	return nil
+}
+
+func (e *engine) executing() bool {
+	for _, taken := range e.exec {
+		if !taken {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *engine) push(item []byte) error {
+	if len(item) > MAX_ELEMENT_SIZE {
+		return ErrElementSize
+	}
+	if len(e.stack) >= MAX_STACK_SIZE {
+		return ErrStackSize
+	}
+	e.stack = append(e.stack, item)
+	return nil
+}
+
+func (e *engine) pop() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	item := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return item, nil
+}
+
+// peek() returns the item n places below the top of the stack.
+func (e *engine) peek(n int) ([]byte, error) {
+	if n >= len(e.stack) {
+		return nil, ErrStackUnderflow
+	}
+	return e.stack[len(e.stack)-1-n], nil
+}
+
+func (e *engine) popNum() (int64, error) {
+	item, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	return DecodeNum(item, MAX_NUM_SIZE)
+}
+
+func (e *engine) pushBool(b bool) error {
+	if b {
+		return e.push([]byte{1})
+	}
+	return e.push(nil)
+}
+
+// asBool() interprets a stack item as a boolean: any non-zero value, other than negative zero, is true.
+func asBool(item []byte) bool {
+	for i, c := range item {
+		if c != 0 {
+			return !(i == len(item)-1 && c == 0x80)
+		}
+	}
+	return false
+}
+
+// run() executes a script on the current stack.
+func (e *engine) run(s Script) error {
+	e.exec = nil
+	for pc := 0; pc < len(s); {
+		ins, next, err := s.next(pc)
+		if err != nil {
+			return err
+		}
+		pc = next
+
+		if !isPush(ins.op) {
+			e.ops++
+			if e.ops > MAX_OPS {
+				return ErrOpCount
+			}
+		}
+		if err := e.step(ins); err != nil {
+			return err
+		}
+	}
+	if len(e.exec) != 0 {
+		return ErrUnbalancedIf
+	}
+	return nil
+}
+
+// step() executes one instruction.
+func (e *engine) step(ins instruction) error {
+	op := ins.op
+
+	// conditionals are tracked even inside branches that are not taken
+	switch op {
+	case OP_IF, OP_NOTIF:
+		taken := false
+		if e.executing() {
+			item, err := e.pop()
+			if err != nil {
+				return err
+			}
+			taken = asBool(item) == (op == OP_IF)
+		}
+		e.exec = append(e.exec, taken)
+		return nil
+	case OP_ELSE:
+		if len(e.exec) == 0 {
+			return ErrUnbalancedIf
+		}
+		e.exec[len(e.exec)-1] = !e.exec[len(e.exec)-1]
+		return nil
+	case OP_ENDIF:
+		if len(e.exec) == 0 {
+			return ErrUnbalancedIf
+		}
+		e.exec = e.exec[:len(e.exec)-1]
+		return nil
+	}
+
+	if !e.executing() {
+		if _, ok := opcodeNames[op]; !ok && !isPush(op) {
+			return ErrUnknownOpcode
+		}
+		return nil
+	}
+
+	switch {
+	case op == OP_0:
+		return e.push(nil)
+	case op < OP_PUSHDATA1 || op == OP_PUSHDATA1 || op == OP_PUSHDATA2:
+		return e.push(ins.data)
+	case op == OP_1NEGATE:
+		return e.push(EncodeNum(-1))
+	case op >= OP_1 && op <= OP_16:
+		return e.push(EncodeNum(int64(op - OP_1 + 1)))
+	}
+
+	switch op {
+	case OP_NOP:
+		return nil
+	case OP_VERIFY:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if !asBool(item) {
+			return ErrVerify
+		}
+		return nil
+	case OP_RETURN:
+		return ErrReturn
+
+	case OP_DROP:
+		_, err := e.pop()
+		return err
+	case OP_DUP, OP_OVER:
+		n := 0
+		if op == OP_OVER {
+			n = 1
+		}
+		item, err := e.peek(n)
+		if err != nil {
+			return err
+		}
+		return e.push(item)
+	case OP_SWAP:
+		if len(e.stack) < 2 {
+			return ErrStackUnderflow
+		}
+		n := len(e.stack)
+		e.stack[n-1], e.stack[n-2] = e.stack[n-2], e.stack[n-1]
+		return nil
+	case OP_SIZE:
+		item, err := e.peek(0)
+		if err != nil {
+			return err
+		}
+		return e.push(EncodeNum(int64(len(item))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		return e.result(op == OP_EQUALVERIFY, bytes.Equal(a, b))
+
+	case OP_1ADD, OP_NOT:
+		n, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		if op == OP_1ADD {
+			return e.push(EncodeNum(n + 1))
+		}
+		return e.pushBool(n == 0)
+	case OP_ADD, OP_SUB, OP_NUMEQUAL, OP_NUMEQUALVERIFY, OP_LESSTHAN, OP_GREATERTHAN, OP_LESSTHANOREQUAL, OP_GREATERTHANOREQUAL:
+		b, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		a, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case OP_ADD:
+			return e.push(EncodeNum(a + b))
+		case OP_SUB:
+			return e.push(EncodeNum(a - b))
+		case OP_NUMEQUAL, OP_NUMEQUALVERIFY:
+			return e.result(op == OP_NUMEQUALVERIFY, a == b)
+		case OP_LESSTHAN:
+			return e.pushBool(a < b)
+		case OP_GREATERTHAN:
+			return e.pushBool(a > b)
+		case OP_LESSTHANOREQUAL:
+			return e.pushBool(a <= b)
+		default:
+			return e.pushBool(a >= b)
+		}
+	case OP_WITHIN:
+		max, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		min, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		x, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		return e.pushBool(min <= x && x < max)
+
+	case OP_SHA256:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(item)
+		return e.push(digest[:])
+	case OP_HASH160:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		return e.push(address.Hash160(item))
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		return e.checkSig(op == OP_CHECKSIGVERIFY)
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		return e.checkMultisig(op == OP_CHECKMULTISIGVERIFY)
+
+	case OP_CHECKLOCKTIMEVERIFY:
+		return e.checkLocktime()
+	case OP_CHECKSEQUENCEVERIFY:
+		return e.checkSequence()
+	}
+
+	return ErrUnknownOpcode
+}
+
+// result() pushes the outcome of a comparison, or fails unless it holds for the VERIFY variants.
+func (e *engine) result(verify, ok bool) error {
+	if !verify {
+		return e.pushBool(ok)
+	}
+	if !ok {
+		return ErrVerify
+	}
+	return nil
+}
+
+// checkSig() pops a public key and a signature and checks the signature over the message.
+func (e *engine) checkSig(verify bool) error {
+	encodedKey, err := e.pop()
+	if err != nil {
+		return err
+	}
+	signature, err := e.pop()
+	if err != nil {
+		return err
+	}
+	publicKey, err := DecodeKey(encodedKey)
+	if err != nil {
+		return err
+	}
+
+	ok := len(signature) > 0 && publicKey.Verify(e.ctx.Message, signature)
+	// only an empty signature may fail, so that a failing spend cannot be altered into another failing spend
+	if !ok && len(signature) > 0 {
+		return ErrNullFail
+	}
+	return e.result(verify, ok)
+}
+
+// checkMultisig() pops n public keys and m signatures, in the same order as the keys, and
+// checks that every signature is valid for a distinct key.
+func (e *engine) checkMultisig(verify bool) error {
+	n, err := e.popNum()
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > MAX_MULTISIG_KEYS {
+		return ErrMultisigKeyCount
+	}
+	e.ops += int(n)
+	if e.ops > MAX_OPS {
+		return ErrOpCount
+	}
+	encodedKeys := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		if encodedKeys[i], err = e.pop(); err != nil {
+			return err
+		}
+	}
+
+	m, err := e.popNum()
+	if err != nil {
+		return err
+	}
+	if m < 0 || m > n {
+		return ErrMultisigSigCount
+	}
+	signatures := make([][]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		if signatures[i], err = e.pop(); err != nil {
+			return err
+		}
+	}
+
+	// match each signature to the next key that verifies it
+	ok := true
+	k := 0
+	for _, signature := range signatures {
+		matched := false
+		for k < len(encodedKeys) && !matched {
+			publicKey, err := DecodeKey(encodedKeys[k])
+			if err != nil {
+				return err
+			}
+			matched = len(signature) > 0 && publicKey.Verify(e.ctx.Message, signature)
+			k++
+		}
+		if !matched {
+			ok = false
+			break
+		}
+	}
+
+	if !ok {
+		for _, signature := range signatures {
+			if len(signature) > 0 {
+				return ErrNullFail
+			}
+		}
+	}
+	return e.result(verify, ok)
+}
+
+// checkLocktime() fails unless the spend is at or past the lock time on top of the stack, which is
+// a block height below LOCKTIME_THRESHOLD and a unix time otherwise. The lock time is left on the stack.
+func (e *engine) checkLocktime() error {
+	item, err := e.peek(0)
+	if err != nil {
+		return err
+	}
+	locktime, err := DecodeNum(item, MAX_NUM_SIZE+1)
+	if err != nil {
+		return err
+	}
+	if locktime < 0 {
+		return ErrNegativeLocktime
+	}
+
+	if locktime < LOCKTIME_THRESHOLD {
+		if int64(e.ctx.Height) < locktime {
+			return ErrUnsatisfiedLocktime
+		}
+		return nil
+	}
+	if e.ctx.Time < locktime {
+		return ErrUnsatisfiedLocktime
+	}
+	return nil
+}
+
+// checkSequence() fails unless the funds the spend uses were received at least the number of
+// blocks on top of the stack ago. The number is left on the stack.
+func (e *engine) checkSequence() error {
+	item, err := e.peek(0)
+	if err != nil {
+		return err
+	}
+	age, err := DecodeNum(item, MAX_NUM_SIZE+1)
+	if err != nil {
+		return err
+	}
+	if age < 0 {
+		return ErrNegativeLocktime
+	}
+	if int64(e.ctx.Age) < age {
+		return ErrUnsatisfiedSequence
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+var testMessage = []byte("spend")
+
+func mustParse(text string) Script {
+	s, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// repeat() returns the script of the opcode n times.
+func repeat(op byte, n int) Script {
+	return Script(bytes.Repeat([]byte{op}, n))
+}
+
+func concat(scripts ...Script) Script {
+	var res Script
+	for _, s := range scripts {
+		res = append(res, s...)
+	}
+	return res
+}
+
+func generateKey(t *testing.T, scheme keys.Scheme) keys.Signer {
+	t.Helper()
+	k, err := keys.GenerateKey(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func sign(t *testing.T, k keys.Signer, message []byte) []byte {
+	t.Helper()
+	sig, err := k.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+type scriptTest struct {
+	name   string
+	unlock Script
+	lock   Script
+	ctx    Context
+	err    error
+}
+
+func runScriptTests(t *testing.T, tests []scriptTest) {
+	t.Helper()
+	for _, tt := range tests {
+		ctx := tt.ctx
+		if err := Execute(tt.unlock, tt.lock, &ctx); !errors.Is(err, tt.err) {
+			t.Errorf("%s: Execute() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestOpcodes(t *testing.T) {
+	data76 := bytes.Repeat([]byte{0xab}, 76)
+	data300 := bytes.Repeat([]byte{0xcd}, 300)
+	abc := []byte("abc")
+	abcSHA256 := sha256.Sum256(abc)
+
+	runScriptTests(t, []scriptTest{
+		{"OP_0", mustParse("1"), mustParse("DROP 0"), Context{}, ErrEvalFalse},
+		{"OP_1 to OP_16", mustParse("16"), mustParse("16 EQUAL"), Context{}, nil},
+		{"OP_1NEGATE", mustParse("-1"), mustParse("-1 EQUAL"), Context{}, nil},
+		{"direct push", mustParse("<0102>"), mustParse("<0102> EQUAL"), Context{}, nil},
+		{"OP_PUSHDATA1", NewBuilder().AddData(data76).Script(), mustParse("SIZE 76 EQUALVERIFY DROP 1"), Context{}, nil},
+		{"OP_PUSHDATA2", NewBuilder().AddData(data300).Script(), mustParse("SIZE 300 EQUALVERIFY DROP 1"), Context{}, nil},
+		{"truncated push", mustParse("1"), Script{0x05, 0x01}, Context{}, ErrMalformed},
+		{"truncated OP_PUSHDATA1", mustParse("1"), Script{OP_PUSHDATA1}, Context{}, ErrMalformed},
+		{"truncated OP_PUSHDATA2", mustParse("1"), Script{OP_PUSHDATA2, 0x01}, Context{}, ErrMalformed},
+
+		{"OP_NOP", mustParse("1"), mustParse("NOP"), Context{}, nil},
+		{"OP_IF taken", mustParse("1"), mustParse("IF 2 ELSE 3 ENDIF 2 EQUAL"), Context{}, nil},
+		{"OP_IF not taken", mustParse("0"), mustParse("IF 2 ELSE 3 ENDIF 3 EQUAL"), Context{}, nil},
+		{"OP_NOTIF", mustParse("0"), mustParse("NOTIF 1 ELSE 0 ENDIF"), Context{}, nil},
+		{"nested OP_IF", mustParse("1 0"), mustParse("IF 0 ELSE IF 1 ELSE 0 ENDIF ENDIF"), Context{}, nil},
+		{"OP_IF without OP_ENDIF", mustParse("1"), mustParse("IF 1"), Context{}, ErrUnbalancedIf},
+		{"OP_ELSE without OP_IF", mustParse("1"), mustParse("ELSE"), Context{}, ErrUnbalancedIf},
+		{"OP_ENDIF without OP_IF", mustParse("1"), mustParse("ENDIF"), Context{}, ErrUnbalancedIf},
+		{"OP_IF on an empty stack", mustParse("1"), mustParse("DROP IF 1 ENDIF"), Context{}, ErrStackUnderflow},
+		{"conditional across scripts", mustParse("1"), mustParse("ENDIF 1"), Context{}, ErrUnbalancedIf},
+		{"OP_VERIFY", mustParse("1"), mustParse("1 VERIFY"), Context{}, nil},
+		{"OP_VERIFY false", mustParse("1"), mustParse("0 VERIFY"), Context{}, ErrVerify},
+		{"OP_RETURN", mustParse("1"), mustParse("RETURN"), Context{}, ErrReturn},
+		{"OP_RETURN not taken", mustParse("0"), mustParse("IF RETURN ENDIF 1"), Context{}, nil},
+		{"unknown opcode", mustParse("1"), Script{0xff}, Context{}, ErrUnknownOpcode},
+		{"unknown opcode not taken", mustParse("0"), Script{OP_IF, 0xff, OP_ENDIF, OP_1}, Context{}, ErrUnknownOpcode},
+
+		{"OP_DROP", mustParse("1 2"), mustParse("DROP 1 EQUAL"), Context{}, nil},
+		{"OP_DROP on an empty stack", mustParse("1"), mustParse("DROP DROP"), Context{}, ErrStackUnderflow},
+		{"OP_DUP", mustParse("3"), mustParse("DUP 3 EQUALVERIFY 3 EQUAL"), Context{}, nil},
+		{"OP_DUP on an empty stack", mustParse("1"), mustParse("DROP DUP"), Context{}, ErrStackUnderflow},
+		{"OP_OVER", mustParse("1 2"), mustParse("OVER 1 EQUALVERIFY 2 EQUALVERIFY 1 EQUAL"), Context{}, nil},
+		{"OP_OVER with one item", mustParse("1"), mustParse("OVER"), Context{}, ErrStackUnderflow},
+		{"OP_SWAP", mustParse("1 2"), mustParse("SWAP 1 EQUALVERIFY 2 EQUAL"), Context{}, nil},
+		{"OP_SWAP with one item", mustParse("1"), mustParse("SWAP"), Context{}, ErrStackUnderflow},
+		{"OP_SIZE", mustParse("<0102>"), mustParse("SIZE 2 EQUALVERIFY <0102> EQUAL"), Context{}, nil},
+		{"OP_SIZE on an empty stack", mustParse("1"), mustParse("DROP SIZE"), Context{}, ErrStackUnderflow},
+
+		{"OP_EQUAL", mustParse("1"), mustParse("1 EQUAL"), Context{}, nil},
+		{"OP_EQUAL false", mustParse("1"), mustParse("2 EQUAL"), Context{}, ErrEvalFalse},
+		{"OP_EQUALVERIFY", mustParse("1"), mustParse("1 EQUALVERIFY 1"), Context{}, nil},
+		{"OP_EQUALVERIFY false", mustParse("1"), mustParse("2 EQUALVERIFY 1"), Context{}, ErrVerify},
+		{"OP_EQUAL with one item", mustParse("1"), mustParse("EQUAL"), Context{}, ErrStackUnderflow},
+
+		{"OP_1ADD", mustParse("4"), mustParse("1ADD 5 EQUAL"), Context{}, nil},
+		{"OP_NOT of zero", mustParse("0"), mustParse("NOT"), Context{}, nil},
+		{"OP_NOT of non-zero", mustParse("5"), mustParse("NOT"), Context{}, ErrEvalFalse},
+		{"OP_ADD", mustParse("2 3"), mustParse("ADD 5 EQUAL"), Context{}, nil},
+		{"OP_SUB", mustParse("2 3"), mustParse("SUB -1 EQUAL"), Context{}, nil},
+		{"OP_SUB to a negative number", mustParse("2 300"), mustParse("SUB -298 EQUAL"), Context{}, nil},
+		{"OP_NUMEQUAL", mustParse("2"), mustParse("2 NUMEQUAL"), Context{}, nil},
+		{"OP_NUMEQUAL false", mustParse("2"), mustParse("3 NUMEQUAL"), Context{}, ErrEvalFalse},
+		{"OP_NUMEQUALVERIFY", mustParse("2"), mustParse("2 NUMEQUALVERIFY 1"), Context{}, nil},
+		{"OP_NUMEQUALVERIFY false", mustParse("2"), mustParse("3 NUMEQUALVERIFY 1"), Context{}, ErrVerify},
+		{"OP_LESSTHAN", mustParse("2 3"), mustParse("LESSTHAN"), Context{}, nil},
+		{"OP_LESSTHAN false", mustParse("3 3"), mustParse("LESSTHAN"), Context{}, ErrEvalFalse},
+		{"OP_GREATERTHAN", mustParse("3 2"), mustParse("GREATERTHAN"), Context{}, nil},
+		{"OP_GREATERTHAN false", mustParse("-1 2"), mustParse("GREATERTHAN"), Context{}, ErrEvalFalse},
+		{"OP_LESSTHANOREQUAL", mustParse("3 3"), mustParse("LESSTHANOREQUAL"), Context{}, nil},
+		{"OP_LESSTHANOREQUAL false", mustParse("4 3"), mustParse("LESSTHANOREQUAL"), Context{}, ErrEvalFalse},
+		{"OP_GREATERTHANOREQUAL", mustParse("3 3"), mustParse("GREATERTHANOREQUAL"), Context{}, nil},
+		{"OP_GREATERTHANOREQUAL false", mustParse("2 3"), mustParse("GREATERTHANOREQUAL"), Context{}, ErrEvalFalse},
+		{"OP_WITHIN", mustParse("2 1 3"), mustParse("WITHIN"), Context{}, nil},
+		{"OP_WITHIN at the upper bound", mustParse("3 1 3"), mustParse("WITHIN"), Context{}, ErrEvalFalse},
+		{"OP_WITHIN with two items", mustParse("1 3"), mustParse("WITHIN"), Context{}, ErrStackUnderflow},
+		{"arithmetic on a long number", mustParse("<0102030405>"), mustParse("1ADD"), Context{}, ErrNumSize},
+		{"arithmetic on a non-minimal number", mustParse("<0100>"), mustParse("1ADD"), Context{}, ErrMinimalData},
+		{"negative zero is false", mustParse("<80>"), mustParse("NOP"), Context{}, ErrEvalFalse},
+
+		{"OP_SHA256", NewBuilder().AddData(abc).Script(), NewBuilder().AddOp(OP_SHA256).AddData(abcSHA256[:]).AddOp(OP_EQUAL).Script(), Context{}, nil},
+		{"OP_SHA256 mismatch", mustParse("<616264>"), NewBuilder().AddOp(OP_SHA256).AddData(abcSHA256[:]).AddOp(OP_EQUAL).Script(), Context{}, ErrEvalFalse},
+		{"OP_HASH160", NewBuilder().AddData(abc).Script(), NewBuilder().AddOp(OP_HASH160).AddData(address.Hash160(abc)).AddOp(OP_EQUAL).Script(), Context{}, nil},
+		{"OP_HASH160 on an empty stack", mustParse("1"), mustParse("DROP HASH160"), Context{}, ErrStackUnderflow},
+		{"OP_CHECKSIG with an invalid key", mustParse("<01> <ff00>"), mustParse("CHECKSIG"), Context{}, ErrPublicKey},
+		{"OP_CHECKSIG with one item", mustParse("1"), mustParse("CHECKSIG"), Context{}, ErrStackUnderflow},
+
+		{"OP_CHECKLOCKTIMEVERIFY leaves the lock time", mustParse("1"), mustParse("DROP 10 CLTV"), Context{Height: 10}, nil},
+		{"OP_CHECKLOCKTIMEVERIFY on an empty stack", mustParse("1"), mustParse("DROP CLTV"), Context{}, ErrStackUnderflow},
+		{"OP_CHECKLOCKTIMEVERIFY with a negative lock time", mustParse("1"), mustParse("-1 CLTV"), Context{}, ErrNegativeLocktime},
+		{"OP_CHECKLOCKTIMEVERIFY with a five byte lock time", mustParse("1"), mustParse("DROP 4294967296 CLTV"), Context{Time: 1 << 32}, nil},
+		{"OP_CHECKLOCKTIMEVERIFY with a six byte lock time", mustParse("1"), mustParse("DROP 1099511627776 CLTV"), Context{Time: 1 << 40}, ErrNumSize},
+		{"OP_CHECKSEQUENCEVERIFY leaves the age", mustParse("1"), mustParse("DROP 3 CSV"), Context{Age: 3}, nil},
+		{"OP_CHECKSEQUENCEVERIFY with a negative age", mustParse("1"), mustParse("-1 CSV"), Context{}, ErrNegativeLocktime},
+		{"OP_CHECKSEQUENCEVERIFY on an empty stack", mustParse("1"), mustParse("DROP CSV"), Context{}, ErrStackUnderflow},
+	})
+}
+
+func TestExecuteRules(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{"empty unlocking script", nil, mustParse("1"), Context{}, ErrUnlockingScriptEmpty},
+		{"unlocking script with an opcode", mustParse("1 NOP"), mustParse("1"), Context{}, ErrUnlockNotPushOnly},
+		{"malformed unlocking script", Script{0x02, 0x01}, mustParse("1"), Context{}, ErrUnlockNotPushOnly},
+		{"empty stack", mustParse("1"), mustParse("DROP"), Context{}, ErrEvalFalse},
+		{"false result", mustParse("1"), mustParse("0"), Context{}, ErrEvalFalse},
+		{"unclean stack", mustParse("1 1"), mustParse("NOP"), Context{}, ErrCleanStack},
+	})
+}
+
+func TestLimits(t *testing.T) {
+	element := bytes.Repeat([]byte{0xee}, MAX_ELEMENT_SIZE)
+
+	// a locking script of exactly the maximum size
+	maxLock := NewBuilder().AddData(element).AddOp(OP_DROP).Script()
+	// the filler is pushed with OP_PUSHDATA2, which takes three bytes, and dropped
+	filler := make([]byte, MAX_SCRIPT_SIZE-len(maxLock)-5)
+	maxLock = NewBuilder().AddOp(maxLock...).AddData(filler).AddOp(OP_DROP, OP_NOP).Script()
+	if len(maxLock) != MAX_SCRIPT_SIZE {
+		t.Fatalf("locking script is %d bytes, want %d", len(maxLock), MAX_SCRIPT_SIZE)
+	}
+
+	// 15 keys are never decoded when no signatures are required
+	multisig := func(nops int) Script {
+		b := NewBuilder().AddOp(repeat(OP_NOP, nops)...)
+		for i := 0; i < MAX_MULTISIG_KEYS; i++ {
+			b.AddData([]byte{byte(i)})
+		}
+		return b.AddInt(MAX_MULTISIG_KEYS).AddOp(OP_CHECKMULTISIG).Script()
+	}
+
+	runScriptTests(t, []scriptTest{
+		{"locking script at the size limit", mustParse("1"), maxLock, Context{}, nil},
+		{"locking script over the size limit", mustParse("1"), concat(Script{OP_NOP}, maxLock), Context{}, ErrScriptSize},
+		{"unlocking script over the size limit", repeat(OP_1, MAX_SCRIPT_SIZE+1), mustParse("1"), Context{}, ErrScriptSize},
+
+		{"item at the size limit", NewBuilder().AddData(element).Script(), mustParse("SIZE 520 EQUALVERIFY DROP 1"), Context{}, nil},
+		{"item over the size limit", NewBuilder().AddData(append(element, 0)).Script(), mustParse("1"), Context{}, ErrElementSize},
+
+		{"stack at the size limit", repeat(OP_1, MAX_STACK_SIZE), repeat(OP_DROP, MAX_STACK_SIZE-1), Context{}, nil},
+		{"stack over the size limit", repeat(OP_1, MAX_STACK_SIZE+1), repeat(OP_DROP, MAX_STACK_SIZE), Context{}, ErrStackSize},
+		{"stack grown over the size limit", repeat(OP_1, MAX_STACK_SIZE), mustParse("DUP"), Context{}, ErrStackSize},
+
+		{"opcodes at the limit", mustParse("1"), repeat(OP_NOP, MAX_OPS), Context{}, nil},
+		{"opcodes over the limit", mustParse("1"), repeat(OP_NOP, MAX_OPS+1), Context{}, ErrOpCount},
+		{"opcodes are counted in branches not taken", mustParse("0"), concat(Script{OP_IF}, repeat(OP_NOP, MAX_OPS-1), Script{OP_ENDIF, OP_1}), Context{}, ErrOpCount},
+		{"pushes are not counted", mustParse("0"), concat(Script{OP_IF}, repeat(OP_1, 500), Script{OP_ENDIF, OP_1}, repeat(OP_NOP, MAX_OPS-2)), Context{}, nil},
+		{"multisig keys at the opcode limit", mustParse("0"), multisig(MAX_OPS - MAX_MULTISIG_KEYS - 1), Context{}, nil},
+		{"multisig keys over the opcode limit", mustParse("0"), multisig(MAX_OPS - MAX_MULTISIG_KEYS), Context{}, ErrOpCount},
+	})
+
+	if _, err := Parse(maxLock.String() + " NOP"); !errors.Is(err, ErrScriptSize) {
+		t.Errorf("Parse() of a script over the size limit = %v, want %v", err, ErrScriptSize)
+	}
+}
+
+func TestPayToPublicKeyHash(t *testing.T) {
+	for _, scheme := range []keys.Scheme{keys.P256, keys.Secp256k1, keys.Ed25519} {
+		k := generateKey(t, scheme)
+		other := generateKey(t, scheme)
+		lock := PayToPublicKey(k.Public())
+		ctx := Context{Message: testMessage}
+
+		unlock := func(sig []byte, publicKey keys.Verifier) Script {
+			return NewBuilder().AddData(sig).AddData(EncodeKey(publicKey)).Script()
+		}
+		runScriptTests(t, []scriptTest{
+			{string(scheme) + " valid", unlock(sign(t, k, testMessage), k.Public()), lock, ctx, nil},
+			{string(scheme) + " other key", unlock(sign(t, other, testMessage), other.Public()), lock, ctx, ErrVerify},
+			{string(scheme) + " other message", unlock(sign(t, k, []byte("other")), k.Public()), lock, ctx, ErrNullFail},
+			{string(scheme) + " empty signature", unlock(nil, k.Public()), lock, ctx, ErrEvalFalse},
+			{string(scheme) + " missing key", NewBuilder().AddData(sign(t, k, testMessage)).Script(), lock, ctx, ErrVerify},
+		})
+	}
+}
+
+func TestMultisig(t *testing.T) {
+	a, b, c := generateKey(t, keys.P256), generateKey(t, keys.Secp256k1), generateKey(t, keys.Ed25519)
+	lock := Multisig(2, []keys.Verifier{a.Public(), b.Public(), c.Public()})
+	ctx := Context{Message: testMessage}
+	sigA, sigB, sigC := sign(t, a, testMessage), sign(t, b, testMessage), sign(t, c, testMessage)
+
+	unlock := func(sigs ...[]byte) Script {
+		builder := NewBuilder()
+		for _, sig := range sigs {
+			builder.AddData(sig)
+		}
+		return builder.Script()
+	}
+	runScriptTests(t, []scriptTest{
+		{"first and second keys", unlock(sigA, sigB), lock, ctx, nil},
+		{"first and third keys", unlock(sigA, sigC), lock, ctx, nil},
+		{"second and third keys", unlock(sigB, sigC), lock, ctx, nil},
+		{"signatures out of order", unlock(sigC, sigA), lock, ctx, ErrNullFail},
+		{"same signature twice", unlock(sigA, sigA), lock, ctx, ErrNullFail},
+		{"one signature", unlock(sigA), lock, ctx, ErrStackUnderflow},
+		{"empty signatures", unlock(nil, nil), lock, ctx, ErrEvalFalse},
+		{"signature of another message", unlock(sigA, sign(t, b, []byte("other"))), lock, ctx, ErrNullFail},
+		{"too many keys", mustParse("1"), mustParse("0 16 CHECKMULTISIG"), ctx, ErrMultisigKeyCount},
+		{"no keys", mustParse("1"), mustParse("0 0 CHECKMULTISIG"), ctx, ErrMultisigKeyCount},
+		{"more signatures than keys", mustParse("1"), mustParse("2 <aa> 1 CHECKMULTISIG"), ctx, ErrMultisigSigCount},
+		{"invalid key", unlock(sigA), mustParse("1 <ff00> 1 CHECKMULTISIG"), ctx, ErrPublicKey},
+		{"CHECKMULTISIGVERIFY", unlock(sigA, sigB), concat(lock[:len(lock)-1], mustParse("CHECKMULTISIGVERIFY 1")), ctx, nil},
+		{"CHECKMULTISIGVERIFY failed", unlock(nil, nil), concat(lock[:len(lock)-1], mustParse("CHECKMULTISIGVERIFY 1")), ctx, ErrVerify},
+	})
+}
+
+func TestHashLock(t *testing.T) {
+	k := generateKey(t, keys.P256)
+	preimage := []byte("secret")
+	hash := sha256.Sum256(preimage)
+	lock := HashLock(hash[:], k.Public())
+	ctx := Context{Message: testMessage}
+
+	unlock := func(sig, preimage []byte) Script {
+		return NewBuilder().AddData(sig).AddData(preimage).Script()
+	}
+	runScriptTests(t, []scriptTest{
+		{"valid", unlock(sign(t, k, testMessage), preimage), lock, ctx, nil},
+		{"wrong preimage", unlock(sign(t, k, testMessage), []byte("guess")), lock, ctx, ErrVerify},
+		{"other key", unlock(sign(t, generateKey(t, keys.P256), testMessage), preimage), lock, ctx, ErrNullFail},
+		{"missing signature", unlock(nil, preimage), lock, ctx, ErrEvalFalse},
+	})
+}
+
+func TestTimeLock(t *testing.T) {
+	k := generateKey(t, keys.Secp256k1)
+	unlock := NewBuilder().AddData(sign(t, k, testMessage)).Script()
+	heightLock := TimeLock(100, k.Public())
+	timeLock := TimeLock(1700000000, k.Public())
+
+	runScriptTests(t, []scriptTest{
+		{"height reached", unlock, heightLock, Context{Message: testMessage, Height: 100}, nil},
+		{"height passed", unlock, heightLock, Context{Message: testMessage, Height: 101}, nil},
+		{"height not reached", unlock, heightLock, Context{Message: testMessage, Height: 99, Time: 1700000000}, ErrUnsatisfiedLocktime},
+		{"time reached", unlock, timeLock, Context{Message: testMessage, Time: 1700000000}, nil},
+		{"time not reached", unlock, timeLock, Context{Message: testMessage, Height: 1700000000, Time: 1699999999}, ErrUnsatisfiedLocktime},
+		{"reached with another key", NewBuilder().AddData(sign(t, generateKey(t, keys.Secp256k1), testMessage)).Script(), heightLock, Context{Message: testMessage, Height: 100}, ErrNullFail},
+	})
+}
+
+func TestRelativeTimeLock(t *testing.T) {
+	k := generateKey(t, keys.Ed25519)
+	unlock := NewBuilder().AddData(sign(t, k, testMessage)).Script()
+	lock := RelativeTimeLock(6, k.Public())
+
+	runScriptTests(t, []scriptTest{
+		{"age reached", unlock, lock, Context{Message: testMessage, Age: 6}, nil},
+		{"age passed", unlock, lock, Context{Message: testMessage, Age: 7}, nil},
+		{"age not reached", unlock, lock, Context{Message: testMessage, Age: 5, Height: 1000}, ErrUnsatisfiedSequence},
+		{"reached with another key", NewBuilder().AddData(sign(t, generateKey(t, keys.Ed25519), testMessage)).Script(), lock, Context{Message: testMessage, Age: 6}, ErrNullFail},
+	})
+}
+
+func TestHashTimeLock(t *testing.T) {
+	claimKey, refundKey := generateKey(t, keys.P256), generateKey(t, keys.Secp256k1)
+	preimage := []byte("secret")
+	hash := sha256.Sum256(preimage)
+	lock := HashTimeLock(hash[:], claimKey.Public(), 100, refundKey.Public())
+
+	claim := func(k keys.Signer, preimage []byte) Script {
+		return NewBuilder().AddData(sign(t, k, testMessage)).AddData(preimage).AddInt(1).Script()
+	}
+	refund := func(k keys.Signer) Script {
+		return NewBuilder().AddData(sign(t, k, testMessage)).AddInt(0).Script()
+	}
+	runScriptTests(t, []scriptTest{
+		{"claim", claim(claimKey, preimage), lock, Context{Message: testMessage}, nil},
+		{"claim with the wrong preimage", claim(claimKey, []byte("guess")), lock, Context{Message: testMessage}, ErrVerify},
+		{"claim with the refund key", claim(refundKey, preimage), lock, Context{Message: testMessage}, ErrNullFail},
+		{"refund", refund(refundKey), lock, Context{Message: testMessage, Height: 100}, nil},
+		{"refund before the lock time", refund(refundKey), lock, Context{Message: testMessage, Height: 99}, ErrUnsatisfiedLocktime},
+		{"refund with the claim key", refund(claimKey), lock, Context{Message: testMessage, Height: 100}, ErrNullFail},
+	})
+}
+
+func TestNumEncoding(t *testing.T) {
+	for _, n := range []int64{0, 1, -1, 16, 127, 128, -128, 255, 256, -32768, 1<<31 - 1, -(1<<31 - 1), LOCKTIME_THRESHOLD} {
+		got, err := DecodeNum(EncodeNum(n), MAX_NUM_SIZE+1)
+		if err != nil || got != n {
+			t.Errorf("DecodeNum(EncodeNum(%d)) = %d, %v", n, got, err)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	k := generateKey(t, keys.P256)
+	hash := sha256.Sum256([]byte("secret"))
+	for _, s := range []Script{
+		PayToPublicKey(k.Public()),
+		Multisig(1, []keys.Verifier{k.Public()}),
+		HashTimeLock(hash[:], k.Public(), 1700000000, k.Public()),
+		mustParse("-1 0 16 17 NOP CLTV CSV"),
+	} {
+		parsed, err := Parse(s.String())
+		if err != nil || !bytes.Equal(parsed, s) {
+			t.Errorf("Parse(%q) = %x, %v, want %x", s.String(), parsed, err, []byte(s))
+		}
+	}
+}
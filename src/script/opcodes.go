@@ -0,0 +1,116 @@
+package script
+
+// Opcodes of the script language. Opcodes 0x01 to 0x4b push that many following bytes.
+const (
+	OP_0         byte = 0x00
+	OP_PUSHDATA1 byte = 0x4c
+	OP_PUSHDATA2 byte = 0x4d
+	OP_1NEGATE   byte = 0x4f
+	OP_1         byte = 0x51
+	OP_16        byte = 0x60
+
+	OP_NOP    byte = 0x61
+	OP_IF     byte = 0x63
+	OP_NOTIF  byte = 0x64
+	OP_ELSE   byte = 0x67
+	OP_ENDIF  byte = 0x68
+	OP_VERIFY byte = 0x69
+	OP_RETURN byte = 0x6a
+
+	OP_DROP byte = 0x75
+	OP_DUP  byte = 0x76
+	OP_OVER byte = 0x78
+	OP_SWAP byte = 0x7c
+	OP_SIZE byte = 0x82
+
+	OP_EQUAL       byte = 0x87
+	OP_EQUALVERIFY byte = 0x88
+
+	OP_1ADD               byte = 0x8b
+	OP_NOT                byte = 0x91
+	OP_ADD                byte = 0x93
+	OP_SUB                byte = 0x94
+	OP_NUMEQUAL           byte = 0x9c
+	OP_NUMEQUALVERIFY     byte = 0x9d
+	OP_LESSTHAN           byte = 0x9f
+	OP_GREATERTHAN        byte = 0xa0
+	OP_LESSTHANOREQUAL    byte = 0xa1
+	OP_GREATERTHANOREQUAL byte = 0xa2
+	OP_WITHIN             byte = 0xa5
+
+	OP_SHA256              byte = 0xa8
+	OP_HASH160             byte = 0xa9
+	OP_CHECKSIG            byte = 0xac
+	OP_CHECKSIGVERIFY      byte = 0xad
+	OP_CHECKMULTISIG       byte = 0xae
+	OP_CHECKMULTISIGVERIFY byte = 0xaf
+
+	OP_CHECKLOCKTIMEVERIFY byte = 0xb1
+	OP_CHECKSEQUENCEVERIFY byte = 0xb2
+)
+
+// OP_FALSE and OP_TRUE are the opcodes pushing false and true.
+const (
+	OP_FALSE = OP_0
+	OP_TRUE  = OP_1
+)
+
+// opcodeNames are the names of the opcodes other than the data pushes and OP_1 to OP_16.
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_NOP:                 "OP_NOP",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_OVER:                "OP_OVER",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_1ADD:                "OP_1ADD",
+	OP_NOT:                 "OP_NOT",
+	OP_ADD:                 "OP_ADD",
+	OP_SUB:                 "OP_SUB",
+	OP_NUMEQUAL:            "OP_NUMEQUAL",
+	OP_NUMEQUALVERIFY:      "OP_NUMEQUALVERIFY",
+	OP_LESSTHAN:            "OP_LESSTHAN",
+	OP_GREATERTHAN:         "OP_GREATERTHAN",
+	OP_LESSTHANOREQUAL:     "OP_LESSTHANOREQUAL",
+	OP_GREATERTHANOREQUAL:  "OP_GREATERTHANOREQUAL",
+	OP_WITHIN:              "OP_WITHIN",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+// opcodesByName maps the names of the opcodes, with and without the OP_ prefix, back to them.
+var opcodesByName = func() map[string]byte {
+	m := make(map[string]byte)
+	for op, name := range opcodeNames {
+		m[name] = op
+		m[name[3:]] = op
+	}
+	m["OP_FALSE"], m["FALSE"] = OP_FALSE, OP_FALSE
+	m["OP_TRUE"], m["TRUE"] = OP_TRUE, OP_TRUE
+	m["OP_CLTV"], m["CLTV"] = OP_CHECKLOCKTIMEVERIFY, OP_CHECKLOCKTIMEVERIFY
+	m["OP_CSV"], m["CSV"] = OP_CHECKSEQUENCEVERIFY, OP_CHECKSEQUENCEVERIFY
+	return m
+}()
+
+// isPush() reports whether the opcode only pushes data.
+func isPush(op byte) bool {
+	return op <= OP_PUSHDATA2 || op == OP_1NEGATE || (op >= OP_1 && op <= OP_16)
+}
@@ -0,0 +1,318 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+const (
+	// MAX_SCRIPT_SIZE is the largest script, in bytes.
+	MAX_SCRIPT_SIZE = 1000
+	// MAX_ELEMENT_SIZE is the largest item that can be pushed onto the stack.
+	MAX_ELEMENT_SIZE = 520
+	// MAX_OPS is the most non-push opcodes a locking and unlocking script may execute together.
+	MAX_OPS = 200
+	// MAX_STACK_SIZE is the most items the stack may hold.
+	MAX_STACK_SIZE = 100
+	// MAX_MULTISIG_KEYS is the most keys OP_CHECKMULTISIG accepts.
+	MAX_MULTISIG_KEYS = 15
+	// MAX_NUM_SIZE is the longest number arithmetic accepts; lock times may use one more byte.
+	MAX_NUM_SIZE = 4
+	// LOCKTIME_THRESHOLD separates lock times given as block heights from those given as unix times.
+	LOCKTIME_THRESHOLD = 500000000
+)
+
+var ErrMalformed = errors.New("script: malformed push")
+
+// Script is a program of the script language.
+type Script []byte
+
+// instruction is an opcode and, for pushes, its data.
+type instruction struct {
+	op   byte
+	data []byte
+}
+
+// next() decodes the instruction at the offset and returns it with the offset of the one after.
+func (s Script) next(pc int) (instruction, int, error) {
+	op := s[pc]
+	pc++
+
+	var n int
+	switch {
+	case op > OP_0 && op < OP_PUSHDATA1:
+		n = int(op)
+	case op == OP_PUSHDATA1:
+		if pc+1 > len(s) {
+			return instruction{}, 0, ErrMalformed
+		}
+		n = int(s[pc])
+		pc++
+	case op == OP_PUSHDATA2:
+		if pc+2 > len(s) {
+			return instruction{}, 0, ErrMalformed
+		}
+		n = int(binary.LittleEndian.Uint16(s[pc:]))
+		pc += 2
+	default:
+		return instruction{op: op}, pc, nil
+	}
+
+	if pc+n > len(s) {
+		return instruction{}, 0, ErrMalformed
+	}
+	return instruction{op, s[pc : pc+n]}, pc + n, nil
+}
+
+// instructions() decodes the whole script.
+func (s Script) instructions() ([]instruction, error) {
+	var res []instruction
+	for pc := 0; pc < len(s); {
+		ins, next, err := s.next(pc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ins)
+		pc = next
+	}
+	return res, nil
+}
+
+// IsPushOnly() reports whether the script is well formed and only pushes data.
+func (s Script) IsPushOnly() bool {
+	instructions, err := s.instructions()
+	if err != nil {
+		return false
+	}
+	for _, ins := range instructions {
+		if !isPush(ins.op) {
+			return false
+		}
+	}
+	return true
+}
+
+// PushedData() returns the data the script pushes, in order, ignoring its other opcodes.
+func (s Script) PushedData() ([][]byte, error) {
+	instructions, err := s.instructions()
+	if err != nil {
+		return nil, err
+	}
+	var res [][]byte
+	for _, ins := range instructions {
+		if ins.data != nil {
+			res = append(res, ins.data)
+		}
+	}
+	return res, nil
+}
+
+// Address() returns the script hash address of the script on the network.
+func (s Script) Address(network *address.Network) string {
+	return address.FromScript(s, network)
+}
+
+// String() returns the disassembly of the script, in the syntax Parse() reads.
+func (s Script) String() string {
+	instructions, err := s.instructions()
+	if err != nil {
+		return "[malformed script]"
+	}
+	parts := make([]string, 0, len(instructions))
+	for _, ins := range instructions {
+		switch {
+		case ins.op == OP_0:
+			parts = append(parts, "0")
+		case ins.op >= OP_1 && ins.op <= OP_16:
+			parts = append(parts, strconv.Itoa(int(ins.op-OP_1+1)))
+		case ins.op == OP_1NEGATE:
+			parts = append(parts, "-1")
+		case ins.data != nil:
+			parts = append(parts, "<"+hex.EncodeToString(ins.data)+">")
+		default:
+			name, ok := opcodeNames[ins.op]
+			if !ok {
+				name = fmt.Sprintf("OP_UNKNOWN_%#02x", ins.op)
+			}
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// MarshalText() encodes the script as hex.
+func (s Script) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(s)), nil
+}
+
+// UnmarshalText() decodes a hex encoded script.
+func (s *Script) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*s = b
+	return nil
+}
+
+// Builder assembles a script one instruction at a time.
+type Builder struct {
+	script Script
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddOp() appends an opcode.
+func (b *Builder) AddOp(ops ...byte) *Builder {
+	b.script = append(b.script, ops...)
+	return b
+}
+
+// AddData() appends the shortest push of the data.
+func (b *Builder) AddData(data []byte) *Builder {
+	n := len(data)
+	switch {
+	case n == 0:
+		b.script = append(b.script, OP_0)
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		b.script = append(b.script, OP_1+data[0]-1)
+	case n == 1 && data[0] == 0x81:
+		b.script = append(b.script, OP_1NEGATE)
+	case n < int(OP_PUSHDATA1):
+		b.script = append(b.script, byte(n))
+		b.script = append(b.script, data...)
+	case n <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(n))
+		b.script = append(b.script, data...)
+	default:
+		b.script = append(b.script, OP_PUSHDATA2, byte(n), byte(n>>8))
+		b.script = append(b.script, data...)
+	}
+	return b
+}
+
+// AddInt() appends the push of a number.
+func (b *Builder) AddInt(n int64) *Builder {
+	return b.AddData(EncodeNum(n))
+}
+
+// Script() returns the assembled script.
+func (b *Builder) Script() Script {
+	return b.script
+}
+
+// Parse() assembles a script from its text form: opcode names with or without the OP_ prefix,
+// decimal numbers, and hex data in angle brackets, separated by spaces.
+func Parse(text string) (Script, error) {
+	b := NewBuilder()
+	for _, token := range strings.Fields(text) {
+		if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+			data, err := hex.DecodeString(token[1 : len(token)-1])
+			if err != nil {
+				return nil, fmt.Errorf("script: invalid data %s", token)
+			}
+			b.AddData(data)
+			continue
+		}
+		if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+			b.AddInt(n)
+			continue
+		}
+		op, ok := opcodesByName[strings.ToUpper(token)]
+		if !ok {
+			return nil, fmt.Errorf("script: unknown opcode %s", token)
+		}
+		b.AddOp(op)
+	}
+	if len(b.script) > MAX_SCRIPT_SIZE {
+		return nil, ErrScriptSize
+	}
+	return b.Script(), nil
+}
+
+// EncodeNum() returns the minimal little-endian sign and magnitude encoding of a number.
+func EncodeNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+	negative := n < 0
+	abs := uint64(n)
+	if negative {
+		abs = uint64(-n)
+	}
+
+	var res []byte
+	for abs > 0 {
+		res = append(res, byte(abs))
+		abs >>= 8
+	}
+	// the top bit of the last byte is the sign, so add a byte if the magnitude uses it
+	if res[len(res)-1]&0x80 != 0 {
+		extra := byte(0)
+		if negative {
+			extra = 0x80
+		}
+		res = append(res, extra)
+	} else if negative {
+		res[len(res)-1] |= 0x80
+	}
+	return res
+}
+
+// DecodeNum() decodes a minimally encoded number of at most maxSize bytes.
+func DecodeNum(b []byte, maxSize int) (int64, error) {
+	if len(b) > maxSize {
+		return 0, ErrNumSize
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	// the last byte may only be 0x00 or 0x80 if the byte before needs its top bit
+	if b[len(b)-1]&0x7f == 0 && (len(b) == 1 || b[len(b)-2]&0x80 == 0) {
+		return 0, ErrMinimalData
+	}
+
+	var n int64
+	for i, c := range b {
+		n |= int64(c) << (8 * uint(i))
+	}
+	if b[len(b)-1]&0x80 != 0 {
+		n &^= int64(0x80) << (8 * uint(len(b)-1))
+		return -n, nil
+	}
+	return n, nil
+}
+
+// Key tags identify the signature scheme of a public key pushed by a script.
+var keyTags = []keys.Scheme{1: keys.P256, 2: keys.Secp256k1, 3: keys.Ed25519}
+
+// EncodeKey() returns the encoding of a public key in a script: a scheme tag followed by the key.
+func EncodeKey(publicKey keys.Verifier) []byte {
+	for tag, scheme := range keyTags {
+		if scheme == publicKey.Scheme() {
+			return append([]byte{byte(tag)}, publicKey.Bytes()...)
+		}
+	}
+	panic("script: unsupported signature scheme " + string(publicKey.Scheme()))
+}
+
+// DecodeKey() decodes a public key encoded by EncodeKey().
+func DecodeKey(b []byte) (keys.Verifier, error) {
+	if len(b) < 1 || int(b[0]) >= len(keyTags) || keyTags[b[0]] == "" {
+		return nil, ErrPublicKey
+	}
+	publicKey, err := keys.ParsePublicKey(keyTags[b[0]], b[1:])
+	if err != nil {
+		return nil, ErrPublicKey
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,83 @@
+package script
+
+import (
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+// PayToPublicKeyHash() returns a locking script spendable by a signature of the key whose
+// encoding hashes to the hash. The unlocking script pushes the signature and the encoded key.
+func PayToPublicKeyHash(keyHash []byte) Script {
+	return NewBuilder().
+		AddOp(OP_DUP, OP_HASH160).
+		AddData(keyHash).
+		AddOp(OP_EQUALVERIFY, OP_CHECKSIG).
+		Script()
+}
+
+// PayToPublicKey() returns the pay to public key hash locking script of the key.
+func PayToPublicKey(publicKey keys.Verifier) Script {
+	return PayToPublicKeyHash(address.Hash160(EncodeKey(publicKey)))
+}
+
+// Multisig() returns a locking script spendable by m signatures of the keys. The unlocking
+// script pushes the signatures in the order of their keys.
+func Multisig(m int, publicKeys []keys.Verifier) Script {
+	b := NewBuilder().AddInt(int64(m))
+	for _, publicKey := range publicKeys {
+		b.AddData(EncodeKey(publicKey))
+	}
+	return b.AddInt(int64(len(publicKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// HashLock() returns a locking script spendable by the key once the sha256 preimage of the hash
+// is revealed. The unlocking script pushes the signature and the preimage.
+func HashLock(hash []byte, publicKey keys.Verifier) Script {
+	return NewBuilder().
+		AddOp(OP_SHA256).
+		AddData(hash).
+		AddOp(OP_EQUALVERIFY).
+		AddData(EncodeKey(publicKey)).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// TimeLock() returns a locking script spendable by the key from the block height or unix time
+// of the lock time on. The unlocking script pushes the signature.
+func TimeLock(locktime int64, publicKey keys.Verifier) Script {
+	return NewBuilder().
+		AddInt(locktime).
+		AddOp(OP_CHECKLOCKTIMEVERIFY, OP_DROP).
+		AddData(EncodeKey(publicKey)).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// RelativeTimeLock() returns a locking script spendable by the key once the number of blocks
+// has passed since the address received the funds spent. The unlocking script pushes the signature.
+func RelativeTimeLock(blocks int64, publicKey keys.Verifier) Script {
+	return NewBuilder().
+		AddInt(blocks).
+		AddOp(OP_CHECKSEQUENCEVERIFY, OP_DROP).
+		AddData(EncodeKey(publicKey)).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// HashTimeLock() returns the locking script of a hashed time lock contract: the claim key can spend
+// by revealing the sha256 preimage of the hash, and the refund key can spend from the lock time on.
+// The unlocking script of a claim pushes the signature, the preimage and 1; that of a refund pushes
+// the signature and 0.
+func HashTimeLock(hash []byte, claimKey keys.Verifier, locktime int64, refundKey keys.Verifier) Script {
+	return NewBuilder().
+		AddOp(OP_IF, OP_SHA256).
+		AddData(hash).
+		AddOp(OP_EQUALVERIFY).
+		AddData(EncodeKey(claimKey)).
+		AddOp(OP_ELSE).
+		AddInt(locktime).
+		AddOp(OP_CHECKLOCKTIMEVERIFY, OP_DROP).
+		AddData(EncodeKey(refundKey)).
+		AddOp(OP_ENDIF, OP_CHECKSIG).
+		Script()
+}
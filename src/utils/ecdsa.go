@@ -3,45 +3,137 @@ package utils
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"errors"
 	"fmt"
 	"math/big"
 )
 
-// Signature is a struct for a signature.
-type Signature struct {
-	R, S *big.Int
+const (
+	// SEC1 prefixes of encoded public keys.
+	pubKeyCompressedEven = 0x02
+	pubKeyCompressedOdd  = 0x03
+	pubKeyUncompressed   = 0x04
+)
+
+var (
+	ErrInvalidPublicKey  = errors.New("invalid public key")
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrPointNotOnCurve   = errors.New("public key is not on the curve")
+)
+
+// coordinateSize() returns the length in bytes of a field element of the curve.
+func coordinateSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
 }
 
-// ToString() returns a string representation of the signature.
-func (s *Signature) ToString() string {
-	return fmt.Sprintf("%064x%064x", s.R, s.S)
+// PublicKeyBytes() returns the SEC1 encoding of the public key, compressed or uncompressed.
+func PublicKeyBytes(publicKey *ecdsa.PublicKey, compressed bool) []byte {
+	size := coordinateSize(publicKey.Curve)
+
+	if compressed {
+		b := make([]byte, 1+size)
+		b[0] = pubKeyCompressedEven
+		if publicKey.Y.Bit(0) == 1 {
+			b[0] = pubKeyCompressedOdd
+		}
+		publicKey.X.FillBytes(b[1:])
+		return b
+	}
+
+	b := make([]byte, 1+2*size)
+	b[0] = pubKeyUncompressed
+	publicKey.X.FillBytes(b[1 : 1+size])
+	publicKey.Y.FillBytes(b[1+size:])
+	return b
 }
 
-// String2BigIntTuple() converts a string to a tuple of two big.Ints.
-func String2BigIntTuple(s string) (*big.Int, *big.Int) {
-	x := new(big.Int)
-	y := new(big.Int)
+// ParsePublicKey() decodes a public key on the curve from its SEC1 compressed or uncompressed
+// encoding, or from the raw 64-byte X||Y form used by older wallets.
+func ParsePublicKey(curve elliptic.Curve, b []byte) (*ecdsa.PublicKey, error) {
+	size := coordinateSize(curve)
+	x, y := new(big.Int), new(big.Int)
+
+	switch {
+	case len(b) == 1+size && (b[0] == pubKeyCompressedEven || b[0] == pubKeyCompressedOdd):
+		x.SetBytes(b[1:])
+		var err error
+		y, err = decompressY(curve, x, b[0] == pubKeyCompressedOdd)
+		if err != nil {
+			return nil, err
+		}
+	case len(b) == 1+2*size && b[0] == pubKeyUncompressed:
+		x.SetBytes(b[1 : 1+size])
+		y.SetBytes(b[1+size:])
+	case len(b) == 2*size:
+		x.SetBytes(b[:size])
+		y.SetBytes(b[size:])
+	default:
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrInvalidPublicKey, len(b))
+	}
 
-	x.SetString(s[:len(s)/2], 16)
-	y.SetString(s[len(s)/2:], 16)
+	p := curve.Params().P
+	if x.Cmp(p) >= 0 || y.Cmp(p) >= 0 || !curve.IsOnCurve(x, y) {
+		return nil, ErrPointNotOnCurve
+	}
 
-	return x, y
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
 }
 
-// PublicKeyFromString() converts a string to a public key.
-func PublicKeyFromString(s string) *ecdsa.PublicKey {
-	x, y := String2BigIntTuple(s)
-	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+// curveEquation is implemented by curves whose equation is not y^2 = x^3 - 3x + b.
+type curveEquation interface {
+	YSquared(x *big.Int) *big.Int
 }
 
-// PrivateKeyFromString() converts a string to a private key.
-func PrivateKeyFromString(s string, publicKey *ecdsa.PublicKey) *ecdsa.PrivateKey {
-	d := new(big.Int)
-	d.SetString(s, 16)
-	return &ecdsa.PrivateKey{PublicKey: *publicKey, D: d}
+// decompressY() solves the curve equation for the y with the requested parity.
+func decompressY(curve elliptic.Curve, x *big.Int, odd bool) (*big.Int, error) {
+	params := curve.Params()
+	if x.Cmp(params.P) >= 0 {
+		return nil, ErrPointNotOnCurve
+	}
+
+	var y2 *big.Int
+	if eq, ok := curve.(curveEquation); ok {
+		y2 = eq.YSquared(x)
+	} else {
+		y2 = new(big.Int).Mul(x, x)
+		y2.Mul(y2, x)
+		threeX := new(big.Int).Lsh(x, 1)
+		threeX.Add(threeX, x)
+		y2.Sub(y2, threeX)
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+	}
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil, ErrPointNotOnCurve
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(params.P, y)
+	}
+	return y, nil
+}
+
+// PrivateKeyBytes() returns the fixed-width big-endian encoding of the private key.
+func PrivateKeyBytes(privateKey *ecdsa.PrivateKey) []byte {
+	b := make([]byte, (privateKey.Curve.Params().N.BitLen()+7)/8)
+	return privateKey.D.FillBytes(b)
 }
 
-func SignatureFromString(s string) *Signature {
-	x, y := String2BigIntTuple(s)
-	return &Signature{R: x, S: y}
+// ParsePrivateKey() decodes a fixed-width private key on the curve and derives its public key.
+func ParsePrivateKey(curve elliptic.Curve, b []byte) (*ecdsa.PrivateKey, error) {
+	n := curve.Params().N
+	if len(b) != (n.BitLen()+7)/8 {
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrInvalidPrivateKey, len(b))
+	}
+
+	d := new(big.Int).SetBytes(b)
+	if d.Sign() == 0 || d.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("%w: scalar out of range", ErrInvalidPrivateKey)
+	}
+
+	privateKey := &ecdsa.PrivateKey{D: d}
+	privateKey.Curve = curve
+	privateKey.X, privateKey.Y = curve.ScalarBaseMult(b)
+	return privateKey, nil
 }
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// The generator of P-256, which has an odd y.
+const (
+	p256GeneratorCompressed   = "036b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"
+	p256GeneratorUncompressed = "046b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c2964fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPublicKeyBytes(t *testing.T) {
+	params := elliptic.P256().Params()
+	g := &ecdsa.PublicKey{Curve: elliptic.P256(), X: params.Gx, Y: params.Gy}
+	if got := hex.EncodeToString(PublicKeyBytes(g, true)); got != p256GeneratorCompressed {
+		t.Errorf("compressed generator = %s, want %s", got, p256GeneratorCompressed)
+	}
+	if got := hex.EncodeToString(PublicKeyBytes(g, false)); got != p256GeneratorUncompressed {
+		t.Errorf("uncompressed generator = %s, want %s", got, p256GeneratorUncompressed)
+	}
+}
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		size := coordinateSize(curve)
+		// enough keys to see both parities of y
+		for i := 0; i < 16; i++ {
+			k, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			uncompressed := PublicKeyBytes(&k.PublicKey, false)
+			for _, tt := range []struct {
+				name string
+				b    []byte
+				size int
+			}{
+				{"compressed", PublicKeyBytes(&k.PublicKey, true), 1 + size},
+				{"uncompressed", uncompressed, 1 + 2*size},
+				{"raw", uncompressed[1:], 2 * size},
+			} {
+				if len(tt.b) != tt.size {
+					t.Errorf("%s %s key is %d bytes, want %d", curve.Params().Name, tt.name, len(tt.b), tt.size)
+				}
+				pub, err := ParsePublicKey(curve, tt.b)
+				if err != nil {
+					t.Fatalf("%s %s key %x: %v", curve.Params().Name, tt.name, tt.b, err)
+				}
+				if pub.X.Cmp(k.X) != 0 || pub.Y.Cmp(k.Y) != 0 {
+					t.Errorf("%s %s key %x parsed as %x, %x", curve.Params().Name, tt.name, tt.b, pub.X, pub.Y)
+				}
+			}
+		}
+	}
+}
+
+func TestParsePublicKeyErrors(t *testing.T) {
+	curve := elliptic.P256()
+	generator := mustDecodeHex(t, p256GeneratorUncompressed)
+	offCurve := append([]byte{}, generator...)
+	offCurve[len(offCurve)-1] ^= 1
+	p := curve.Params().P.FillBytes(make([]byte, 32))
+
+	for _, tt := range []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"empty", nil, ErrInvalidPublicKey},
+		{"a bare coordinate", generator[1:33], ErrInvalidPublicKey},
+		{"a truncated compressed key", mustDecodeHex(t, p256GeneratorCompressed)[:32], ErrInvalidPublicKey},
+		{"a compressed key with a trailing byte", append(mustDecodeHex(t, p256GeneratorCompressed), 0), ErrInvalidPublicKey},
+		// 64 bytes are read as the raw X||Y form
+		{"a truncated uncompressed key", generator[:64], ErrPointNotOnCurve},
+		{"a truncated raw key", generator[1:64], ErrInvalidPublicKey},
+		{"the hybrid prefix", append([]byte{0x06}, generator[1:33]...), ErrInvalidPublicKey},
+		{"the uncompressed prefix on a compressed key", append([]byte{0x04}, generator[1:33]...), ErrInvalidPublicKey},
+		{"a compressed prefix on an uncompressed key", append([]byte{0x02}, generator[1:]...), ErrInvalidPublicKey},
+		{"an uncompressed point off the curve", offCurve, ErrPointNotOnCurve},
+		{"a raw point off the curve", offCurve[1:], ErrPointNotOnCurve},
+		{"a compressed x without a point", append([]byte{0x02}, big.NewInt(1).FillBytes(make([]byte, 32))...), ErrPointNotOnCurve},
+		{"a compressed x of the field size", append([]byte{0x02}, p...), ErrPointNotOnCurve},
+		{"coordinates of the field size", append(append([]byte{0x04}, p...), p...), ErrPointNotOnCurve},
+	} {
+		if _, err := ParsePublicKey(curve, tt.b); !errors.Is(err, tt.want) {
+			t.Errorf("ParsePublicKey() of %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	curve := elliptic.P256()
+	params := curve.Params()
+	for _, tt := range []struct {
+		name string
+		d    string
+	}{
+		{"one", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{"a leading zero byte", "00c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f67"},
+		{"leading zero bytes", "000000c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b12"},
+		{"the largest scalar", new(big.Int).Sub(params.N, big.NewInt(1)).Text(16)},
+	} {
+		b := mustDecodeHex(t, tt.d)
+		k, err := ParsePrivateKey(curve, b)
+		if err != nil {
+			t.Fatalf("ParsePrivateKey() of %s: %v", tt.name, err)
+		}
+		if got := PrivateKeyBytes(k); !bytes.Equal(got, b) {
+			t.Errorf("PrivateKeyBytes() of %s = %x, want %x", tt.name, got, b)
+		}
+		x, y := curve.ScalarBaseMult(b)
+		if k.X.Cmp(x) != 0 || k.Y.Cmp(y) != 0 {
+			t.Errorf("public key of %s = %x, %x, want %x, %x", tt.name, k.X, k.Y, x, y)
+		}
+	}
+
+	if k, err := ParsePrivateKey(curve, mustDecodeHex(t, "0000000000000000000000000000000000000000000000000000000000000001")); err != nil || k.X.Cmp(params.Gx) != 0 {
+		t.Errorf("public key of one is not the generator: %v", err)
+	}
+}
+
+func TestParsePrivateKeyErrors(t *testing.T) {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	for _, tt := range []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"a key without its leading zero byte", mustDecodeHex(t, "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f67")},
+		{"a key with an extra byte", make([]byte, 33)},
+		{"zero", make([]byte, 32)},
+		{"the order of the curve", n.FillBytes(make([]byte, 32))},
+		{"more than the order of the curve", new(big.Int).Add(n, big.NewInt(1)).FillBytes(make([]byte, 32))},
+	} {
+		if _, err := ParsePrivateKey(curve, tt.b); !errors.Is(err, ErrInvalidPrivateKey) {
+			t.Errorf("ParsePrivateKey() of %s = %v, want %v", tt.name, err, ErrInvalidPrivateKey)
+		}
+	}
+}
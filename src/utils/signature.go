@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// CompactSignatureSize is the length of a compact R||S signature.
+const CompactSignatureSize = 64
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signature is a struct for a signature.
+type Signature struct {
+	R, S *big.Int
+}
+
+// ToString() returns the hex encoded compact representation of the signature.
+func (s *Signature) ToString() string {
+	return hex.EncodeToString(s.Compact())
+}
+
+// Compact() returns the 64-byte R||S encoding of the signature.
+func (s *Signature) Compact() []byte {
+	b := make([]byte, CompactSignatureSize)
+	s.R.FillBytes(b[:CompactSignatureSize/2])
+	s.S.FillBytes(b[CompactSignatureSize/2:])
+	return b
+}
+
+// DER() returns the ASN.1 DER encoding of the signature.
+func (s *Signature) DER() []byte {
+	b, err := asn1.Marshal(struct{ R, S *big.Int }{s.R, s.S})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// SignatureFromCompact() decodes a 64-byte R||S signature.
+func SignatureFromCompact(b []byte) (*Signature, error) {
+	if len(b) != CompactSignatureSize {
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrInvalidSignature, len(b))
+	}
+
+	s := &Signature{
+		R: new(big.Int).SetBytes(b[:CompactSignatureSize/2]),
+		S: new(big.Int).SetBytes(b[CompactSignatureSize/2:]),
+	}
+	if s.R.Sign() == 0 || s.S.Sign() == 0 {
+		return nil, fmt.Errorf("%w: zero component", ErrInvalidSignature)
+	}
+	return s, nil
+}
+
+// SignatureFromDER() decodes an ASN.1 DER encoded signature.
+func SignatureFromDER(b []byte) (*Signature, error) {
+	var tmp struct{ R, S *big.Int }
+	rest, err := asn1.Unmarshal(b, &tmp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidSignature)
+	}
+	if tmp.R.Sign() <= 0 || tmp.S.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: non-positive component", ErrInvalidSignature)
+	}
+	return &Signature{R: tmp.R, S: tmp.S}, nil
+}
+
+// ParseSignature() decodes a compact or DER signature. Only the compact encoding is valid on chain,
+// so DER signatures must be re-encoded with Compact() before they are submitted.
+func ParseSignature(b []byte) (*Signature, error) {
+	if len(b) > 0 && b[0] == 0x30 {
+		if sig, err := SignatureFromDER(b); err == nil || len(b) != CompactSignatureSize {
+			return sig, err
+		}
+	}
+	return SignatureFromCompact(b)
+}
+
+// SignatureFromString() converts a hex encoded compact or DER signature to a signature.
+func SignatureFromString(s string) (*Signature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return ParseSignature(b)
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		sig  *Signature
+	}{
+		{"full width components", &Signature{
+			R: new(big.Int).SetBytes(mustDecodeHex(t, "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716")),
+			S: new(big.Int).SetBytes(mustDecodeHex(t, "f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8")),
+		}},
+		{"short components", &Signature{R: big.NewInt(1), S: big.NewInt(0x7f)}},
+		{"a component with a leading zero byte", &Signature{
+			R: new(big.Int).SetBytes(mustDecodeHex(t, "00d48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716")),
+			S: big.NewInt(0x80),
+		}},
+	} {
+		compact := tt.sig.Compact()
+		if len(compact) != CompactSignatureSize {
+			t.Errorf("compact %s signature is %d bytes", tt.name, len(compact))
+		}
+		for encoding, b := range map[string][]byte{"compact": compact, "DER": tt.sig.DER()} {
+			sig, err := ParseSignature(b)
+			if err != nil {
+				t.Fatalf("ParseSignature() of the %s %s signature: %v", encoding, tt.name, err)
+			}
+			if sig.R.Cmp(tt.sig.R) != 0 || sig.S.Cmp(tt.sig.S) != 0 {
+				t.Errorf("%s %s signature parsed as %x, %x", encoding, tt.name, sig.R, sig.S)
+			}
+		}
+		sig, err := SignatureFromString(tt.sig.ToString())
+		if err != nil || !bytes.Equal(sig.Compact(), compact) {
+			t.Errorf("SignatureFromString() of the %s signature = %v, %v", tt.name, sig, err)
+		}
+	}
+
+	// a compact signature may start with the DER sequence tag
+	compact := (&Signature{R: new(big.Int).Lsh(big.NewInt(0x30), 248), S: big.NewInt(1)}).Compact()
+	if sig, err := ParseSignature(compact); err != nil || sig.R.Cmp(new(big.Int).Lsh(big.NewInt(0x30), 248)) != 0 {
+		t.Errorf("ParseSignature() of a compact signature starting with 0x30 = %v, %v", sig, err)
+	}
+}
+
+func TestParseSignatureErrors(t *testing.T) {
+	der := (&Signature{R: big.NewInt(1), S: big.NewInt(2)}).DER()
+	for _, tt := range []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"a short compact signature", make([]byte, CompactSignatureSize-1)},
+		{"a long compact signature", make([]byte, CompactSignatureSize+1)},
+		{"a compact signature with a zero R", append(make([]byte, 32), bytes.Repeat([]byte{1}, 32)...)},
+		{"a compact signature with a zero S", append(bytes.Repeat([]byte{1}, 32), make([]byte, 32)...)},
+		{"a truncated DER signature", der[:len(der)-1]},
+		{"a DER signature with trailing data", append(append([]byte{}, der...), 0)},
+		{"a DER signature with a wrong length", append([]byte{0x30, 0x07}, der[2:]...)},
+		{"a DER sequence of one integer", []byte{0x30, 0x03, 0x02, 0x01, 0x01}},
+		{"a DER sequence of octet strings", []byte{0x30, 0x06, 0x04, 0x01, 0x01, 0x04, 0x01, 0x02}},
+		{"a DER signature with a negative R", []byte{0x30, 0x06, 0x02, 0x01, 0xff, 0x02, 0x01, 0x02}},
+		{"a DER signature with a zero S", []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00}},
+		{"a DER signature with a non-minimal integer", []byte{0x30, 0x07, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x02}},
+	} {
+		if _, err := ParseSignature(tt.b); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("ParseSignature() of %s = %v, want %v", tt.name, err, ErrInvalidSignature)
+		}
+	}
+
+	if _, err := SignatureFromString("not hex"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("SignatureFromString() of a string that is not hex = %v, want %v", err, ErrInvalidSignature)
+	}
+}
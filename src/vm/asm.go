@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/script"
+)
+
+// Assemble() assembles contract code from its text form, separated by spaces: opcode names,
+// decimal numbers, hex data in angle brackets and text in single quotes, which are pushed, label
+// definitions ending in a colon, and jump targets of the form @label following JUMP and JUMPI.
+// Everything after a # on a line is a comment.
+func Assemble(text string) ([]byte, error) {
+	var code []byte
+	labels := make(map[string]int)
+	fixups := make(map[int]string)
+
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, token := range strings.Fields(line) {
+			switch {
+			case strings.HasSuffix(token, ":"):
+				name := strings.TrimSuffix(token, ":")
+				if _, ok := labels[name]; ok || name == "" {
+					return nil, fmt.Errorf("vm: duplicate label %s", token)
+				}
+				labels[name] = len(code)
+			case strings.HasPrefix(token, "@"):
+				if len(code) == 0 || (code[len(code)-1] != JUMP && code[len(code)-1] != JUMPI) {
+					return nil, fmt.Errorf("vm: jump target %s must follow JUMP or JUMPI", token)
+				}
+				fixups[len(code)] = token[1:]
+				code = append(code, 0, 0)
+			default:
+				if data, ok, err := ParseData(token); ok {
+					if err != nil {
+						return nil, err
+					}
+					code = appendPush(code, data)
+					continue
+				}
+				op, ok := opcodesByName[strings.ToUpper(token)]
+				if !ok || op == PUSH1 || op == PUSH2 {
+					return nil, fmt.Errorf("vm: unknown opcode %s", token)
+				}
+				code = append(code, op)
+			}
+		}
+	}
+
+	for offset, name := range fixups {
+		target, ok := labels[name]
+		if !ok {
+			return nil, fmt.Errorf("vm: unknown label %s", name)
+		}
+		binary.LittleEndian.PutUint16(code[offset:], uint16(target))
+	}
+	if err := Validate(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// ParseData() decodes an item written as in the assembly language: a decimal number, hex data in
+// angle brackets or text in single quotes. It reports whether the token is written as data at all.
+func ParseData(token string) ([]byte, bool, error) {
+	switch {
+	case strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">"):
+		data, err := hex.DecodeString(token[1 : len(token)-1])
+		if err != nil {
+			return nil, true, fmt.Errorf("vm: invalid data %s", token)
+		}
+		return data, true, nil
+	case len(token) >= 2 && strings.HasPrefix(token, "'") && strings.HasSuffix(token, "'"):
+		return []byte(token[1 : len(token)-1]), true, nil
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return script.EncodeNum(n), true, nil
+	}
+	return nil, false, nil
+}
+
+// appendPush() appends the shortest push of the data.
+func appendPush(code, data []byte) []byte {
+	if len(data) <= 0xff {
+		return append(append(code, PUSH1, byte(len(data))), data...)
+	}
+	code = append(code, PUSH2, 0, 0)
+	binary.LittleEndian.PutUint16(code[len(code)-2:], uint16(len(data)))
+	return append(code, data...)
+}
+
+// Disassemble() returns the text form of contract code, which Assemble() turns back into it.
+// Jump targets are labelled by their offset.
+func Disassemble(code []byte) (string, error) {
+	var instructions []instruction
+	targets := make(map[int]bool)
+	offsets := []int{}
+	for pc := 0; pc < len(code); {
+		ins, next, err := decode(code, pc)
+		if err != nil {
+			return "", err
+		}
+		if ins.op == JUMP || ins.op == JUMPI {
+			targets[ins.target] = true
+		}
+		instructions = append(instructions, ins)
+		offsets = append(offsets, pc)
+		pc = next
+	}
+
+	var tokens []string
+	for i, ins := range instructions {
+		if targets[offsets[i]] {
+			tokens = append(tokens, fmt.Sprintf("L%d:", offsets[i]))
+		}
+		switch ins.op {
+		case PUSH1, PUSH2:
+			tokens = append(tokens, "<"+hex.EncodeToString(ins.data)+">")
+		case JUMP, JUMPI:
+			tokens = append(tokens, opcodeNames[ins.op], fmt.Sprintf("@L%d", ins.target))
+		default:
+			tokens = append(tokens, opcodeNames[ins.op])
+		}
+	}
+	return strings.Join(tokens, " "), nil
+}
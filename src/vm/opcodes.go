@@ -0,0 +1,123 @@
+package vm
+
+// Opcodes of the contract bytecode. PUSH1 and PUSH2 are followed by the length of the data, in one
+// byte or two little-endian bytes, and the data; JUMP and JUMPI by a two byte little-endian offset.
+const (
+	STOP  byte = 0x00
+	PUSH1 byte = 0x01
+	PUSH2 byte = 0x02
+
+	POP  byte = 0x08
+	DUP  byte = 0x09
+	SWAP byte = 0x0a
+	OVER byte = 0x0b
+
+	ADD    byte = 0x10
+	SUB    byte = 0x11
+	MUL    byte = 0x12
+	DIV    byte = 0x13
+	MOD    byte = 0x14
+	LT     byte = 0x15
+	GT     byte = 0x16
+	EQ     byte = 0x17
+	ISZERO byte = 0x18
+	AND    byte = 0x19
+	OR     byte = 0x1a
+
+	JUMP  byte = 0x20
+	JUMPI byte = 0x21
+
+	CALLER  byte = 0x30
+	ADDRESS byte = 0x31
+	HEIGHT  byte = 0x32
+	ARG     byte = 0x33
+	NARGS   byte = 0x34
+
+	SLOAD  byte = 0x40
+	SSTORE byte = 0x41
+
+	SHA256 byte = 0x50
+	CAT    byte = 0x51
+	SIZE   byte = 0x52
+
+	RETURN byte = 0x60
+	REVERT byte = 0x61
+	ASSERT byte = 0x62
+)
+
+// opcodeNames are the names of the opcodes in the assembly language.
+var opcodeNames = map[byte]string{
+	STOP:    "STOP",
+	PUSH1:   "PUSH1",
+	PUSH2:   "PUSH2",
+	POP:     "POP",
+	DUP:     "DUP",
+	SWAP:    "SWAP",
+	OVER:    "OVER",
+	ADD:     "ADD",
+	SUB:     "SUB",
+	MUL:     "MUL",
+	DIV:     "DIV",
+	MOD:     "MOD",
+	LT:      "LT",
+	GT:      "GT",
+	EQ:      "EQ",
+	ISZERO:  "ISZERO",
+	AND:     "AND",
+	OR:      "OR",
+	JUMP:    "JUMP",
+	JUMPI:   "JUMPI",
+	CALLER:  "CALLER",
+	ADDRESS: "ADDRESS",
+	HEIGHT:  "HEIGHT",
+	ARG:     "ARG",
+	NARGS:   "NARGS",
+	SLOAD:   "SLOAD",
+	SSTORE:  "SSTORE",
+	SHA256:  "SHA256",
+	CAT:     "CAT",
+	SIZE:    "SIZE",
+	RETURN:  "RETURN",
+	REVERT:  "REVERT",
+	ASSERT:  "ASSERT",
+}
+
+// opcodesByName maps the names of the opcodes back to them.
+var opcodesByName = func() map[string]byte {
+	m := make(map[string]byte)
+	for op, name := range opcodeNames {
+		m[name] = op
+	}
+	return m
+}()
+
+// Gas costs of the opcodes. Storage is priced per byte written, so that state grows only as
+// fast as callers pay for it.
+const (
+	GAS_BASE          = 1
+	GAS_JUMP          = 2
+	GAS_HASH          = 20
+	GAS_SLOAD         = 50
+	GAS_SSTORE        = 200
+	GAS_SSTORE_BYTE   = 2
+	GAS_CALL          = 100
+	GAS_DEPLOY        = 1000
+	GAS_DEPLOY_BYTE   = 10
+	GAS_COPY_PER_BYTE = 1
+)
+
+// gasCost() returns the fixed cost of the opcode; SSTORE, CAT and SHA256 add a cost per byte.
+func gasCost(op byte) uint64 {
+	switch op {
+	case JUMP, JUMPI:
+		return GAS_JUMP
+	case SHA256:
+		return GAS_HASH
+	case SLOAD:
+		return GAS_SLOAD
+	case SSTORE:
+		return GAS_SSTORE
+	default:
+		return GAS_BASE
+	}
+}
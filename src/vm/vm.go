@@ -0,0 +1,497 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/script"
+)
+
+const (
+	// MAX_CODE_SIZE is the largest contract, in bytes.
+	MAX_CODE_SIZE = 4096
+	// MAX_GAS is the most gas a transaction may spend.
+	MAX_GAS = 1000000
+	// MAX_STACK_SIZE is the most items the stack may hold.
+	MAX_STACK_SIZE = 256
+	// MAX_ITEM_SIZE is the largest item the stack or the storage may hold.
+	MAX_ITEM_SIZE = 1024
+	// MAX_NUM_SIZE is the longest number arithmetic accepts.
+	MAX_NUM_SIZE = 8
+	// MAX_ARGS is the most arguments a call may pass.
+	MAX_ARGS = 16
+)
+
+var (
+	ErrOutOfGas       = errors.New("vm: out of gas")
+	ErrStackUnderflow = errors.New("vm: not enough items on the stack")
+	ErrStackSize      = errors.New("vm: stack is too large")
+	ErrItemSize       = errors.New("vm: item is too large")
+	ErrInvalidOpcode  = errors.New("vm: invalid opcode")
+	ErrInvalidJump    = errors.New("vm: jump to an invalid offset")
+	ErrMalformed      = errors.New("vm: malformed instruction")
+	ErrCodeSize       = errors.New("vm: code is too large")
+	ErrArgument       = errors.New("vm: no such argument")
+	ErrOverflow       = errors.New("vm: arithmetic overflow")
+	ErrDivisionByZero = errors.New("vm: division by zero")
+	ErrAssert         = errors.New("vm: assertion failed")
+)
+
+// RevertError is returned when a contract reverts, with the message it reverted with.
+type RevertError struct {
+	Message []byte
+}
+
+func (e *RevertError) Error() string {
+	return "vm: reverted: " + string(e.Message)
+}
+
+// Code is the bytecode of a contract. Its text form is hex.
+type Code []byte
+
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(c)), nil
+}
+
+func (c *Code) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*c = b
+	return nil
+}
+
+// String() returns the disassembly of the code, or its hex if it does not decode.
+func (c Code) String() string {
+	asm, err := Disassemble(c)
+	if err != nil {
+		return hex.EncodeToString(c)
+	}
+	return asm
+}
+
+// ContractAddress() returns the address of the contract with the code deployed by the deployer. It is
+// the script hash address of an OP_RETURN script committing to both, which nothing can spend from.
+func ContractAddress(deployer string, code Code, network *address.Network) string {
+	digest := sha256.Sum256(append([]byte(deployer), code...))
+	return script.NewBuilder().AddOp(script.OP_RETURN).AddData(digest[:]).Script().Address(network)
+}
+
+// Storage is the key/value storage of a contract.
+type Storage interface {
+	// Get() returns the value of the key, or nil if it is not set.
+	Get(key []byte) []byte
+	// Set() sets the value of the key; an empty value deletes it.
+	Set(key, value []byte)
+}
+
+// Context is what a contract can learn about the call it runs for.
+type Context struct {
+	// Caller is the address of the sender of the call.
+	Caller string
+	// Contract is the address of the contract.
+	Contract string
+	// Height is the height of the block the call is included in.
+	Height int
+	// Input holds the arguments of the call.
+	Input [][]byte
+}
+
+// Result is the outcome of a call. GasUsed is set whether or not the call succeeded.
+type Result struct {
+	Return  []byte `json:"return"`
+	GasUsed uint64 `json:"gas_used"`
+}
+
+// engine is the state of a running contract.
+type engine struct {
+	code    []byte
+	ctx     *Context
+	storage Storage
+	stack   [][]byte
+	gas     uint64
+	limit   uint64
+	// starts marks the offsets instructions start at, which jumps may target.
+	starts []bool
+}
+
+// Execute() runs the code of a contract with at most gasLimit gas. The storage is written as the
+// contract runs, so callers must discard the writes of a call that returns an error. Execution
+// is deterministic: it only depends on the code, the context, the storage and the gas limit.
+func Execute(code []byte, ctx *Context, storage Storage, gasLimit uint64) (*Result, error) {
+	e := &engine{code: code, ctx: ctx, storage: storage, limit: gasLimit, starts: instructionStarts(code)}
+	ret, err := e.run()
+	return &Result{ret, e.gas}, err
+}
+
+func (e *engine) useGas(gas uint64) error {
+	if e.gas+gas > e.limit {
+		e.gas = e.limit
+		return ErrOutOfGas
+	}
+	e.gas += gas
+	return nil
+}
+
+func (e *engine) push(item []byte) error {
+	if len(item) > MAX_ITEM_SIZE {
+		return ErrItemSize
+	}
+	if len(e.stack) >= MAX_STACK_SIZE {
+		return ErrStackSize
+	}
+	e.stack = append(e.stack, item)
+	return nil
+}
+
+func (e *engine) pop() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	item := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return item, nil
+}
+
+func (e *engine) popNum() (int64, error) {
+	item, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	return script.DecodeNum(item, MAX_NUM_SIZE)
+}
+
+func (e *engine) pushNum(n int64) error {
+	return e.push(script.EncodeNum(n))
+}
+
+func (e *engine) pushBool(b bool) error {
+	if b {
+		return e.pushNum(1)
+	}
+	return e.pushNum(0)
+}
+
+// isTrue() interprets an item as a boolean: any non-zero number is true.
+func isTrue(item []byte) bool {
+	for i, c := range item {
+		if c != 0 {
+			return !(i == len(item)-1 && c == 0x80)
+		}
+	}
+	return false
+}
+
+// run() executes the code from its start and returns what it returned.
+func (e *engine) run() ([]byte, error) {
+	if len(e.code) > MAX_CODE_SIZE {
+		return nil, ErrCodeSize
+	}
+	for pc := 0; pc < len(e.code); {
+		ins, next, err := decode(e.code, pc)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.useGas(gasCost(ins.op)); err != nil {
+			return nil, err
+		}
+		pc = next
+
+		switch ins.op {
+		case STOP:
+			return nil, nil
+		case RETURN:
+			return e.pop()
+		case REVERT:
+			message, err := e.pop()
+			if err != nil {
+				return nil, err
+			}
+			return nil, &RevertError{message}
+		case JUMP, JUMPI:
+			jump := ins.op == JUMP
+			if !jump {
+				cond, err := e.pop()
+				if err != nil {
+					return nil, err
+				}
+				jump = isTrue(cond)
+			}
+			if jump {
+				if ins.target >= len(e.starts) || !e.starts[ins.target] {
+					return nil, ErrInvalidJump
+				}
+				pc = ins.target
+			}
+		default:
+			if err := e.step(ins); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// step() executes an instruction that does not change the flow of the program.
+func (e *engine) step(ins instruction) error {
+	switch ins.op {
+	case PUSH1, PUSH2:
+		return e.push(ins.data)
+
+	case POP:
+		_, err := e.pop()
+		return err
+	case DUP, OVER:
+		n := 1
+		if ins.op == OVER {
+			n = 2
+		}
+		if len(e.stack) < n {
+			return ErrStackUnderflow
+		}
+		return e.push(e.stack[len(e.stack)-n])
+	case SWAP:
+		if len(e.stack) < 2 {
+			return ErrStackUnderflow
+		}
+		n := len(e.stack)
+		e.stack[n-1], e.stack[n-2] = e.stack[n-2], e.stack[n-1]
+		return nil
+
+	case ADD, SUB, MUL, DIV, MOD, LT, GT, AND, OR:
+		b, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		a, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		return e.arithmetic(ins.op, a, b)
+	case ISZERO:
+		n, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		return e.pushBool(n == 0)
+	case EQ:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		return e.pushBool(bytes.Equal(a, b))
+
+	case CALLER:
+		return e.push([]byte(e.ctx.Caller))
+	case ADDRESS:
+		return e.push([]byte(e.ctx.Contract))
+	case HEIGHT:
+		return e.pushNum(int64(e.ctx.Height))
+	case ARG:
+		i, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		if i < 0 || i >= int64(len(e.ctx.Input)) {
+			return ErrArgument
+		}
+		return e.push(e.ctx.Input[i])
+	case NARGS:
+		return e.pushNum(int64(len(e.ctx.Input)))
+
+	case SLOAD:
+		key, err := e.pop()
+		if err != nil {
+			return err
+		}
+		return e.push(e.storage.Get(key))
+	case SSTORE:
+		value, err := e.pop()
+		if err != nil {
+			return err
+		}
+		key, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 || len(key) > MAX_ITEM_SIZE {
+			return ErrItemSize
+		}
+		if err := e.useGas(uint64(len(key)+len(value)) * GAS_SSTORE_BYTE); err != nil {
+			return err
+		}
+		e.storage.Set(key, value)
+		return nil
+
+	case SHA256:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if err := e.useGas(uint64(len(item)) * GAS_COPY_PER_BYTE); err != nil {
+			return err
+		}
+		digest := sha256.Sum256(item)
+		return e.push(digest[:])
+	case CAT:
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if err := e.useGas(uint64(len(a)+len(b)) * GAS_COPY_PER_BYTE); err != nil {
+			return err
+		}
+		return e.push(append(append([]byte{}, a...), b...))
+	case SIZE:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		return e.pushNum(int64(len(item)))
+	case ASSERT:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if !isTrue(item) {
+			return ErrAssert
+		}
+		return nil
+	}
+	return ErrInvalidOpcode
+}
+
+// arithmetic() pushes the result of a binary operation on numbers, failing on overflow.
+func (e *engine) arithmetic(op byte, a, b int64) error {
+	switch op {
+	case ADD:
+		if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < -math.MaxInt64-b) {
+			return ErrOverflow
+		}
+		return e.pushNum(a + b)
+	case SUB:
+		if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < -math.MaxInt64+b) {
+			return ErrOverflow
+		}
+		return e.pushNum(a - b)
+	case MUL:
+		if a != 0 && b != 0 {
+			p := a * b
+			if p/b != a || p == math.MinInt64 {
+				return ErrOverflow
+			}
+		}
+		return e.pushNum(a * b)
+	case DIV, MOD:
+		if b == 0 {
+			return ErrDivisionByZero
+		}
+		if op == DIV {
+			return e.pushNum(a / b)
+		}
+		return e.pushNum(a % b)
+	case LT:
+		return e.pushBool(a < b)
+	case GT:
+		return e.pushBool(a > b)
+	case AND:
+		return e.pushBool(a != 0 && b != 0)
+	default:
+		return e.pushBool(a != 0 || b != 0)
+	}
+}
+
+// instruction is a decoded opcode with its data or jump target.
+type instruction struct {
+	op     byte
+	data   []byte
+	target int
+}
+
+// decode() decodes the instruction at the offset and returns it with the offset of the one after.
+func decode(code []byte, pc int) (instruction, int, error) {
+	op := code[pc]
+	pc++
+	if _, ok := opcodeNames[op]; !ok {
+		return instruction{}, 0, ErrInvalidOpcode
+	}
+
+	switch op {
+	case PUSH1, PUSH2:
+		size := 1
+		if op == PUSH2 {
+			size = 2
+		}
+		if pc+size > len(code) {
+			return instruction{}, 0, ErrMalformed
+		}
+		n := int(code[pc])
+		if op == PUSH2 {
+			n = int(binary.LittleEndian.Uint16(code[pc:]))
+		}
+		pc += size
+		if pc+n > len(code) {
+			return instruction{}, 0, ErrMalformed
+		}
+		return instruction{op: op, data: code[pc : pc+n]}, pc + n, nil
+	case JUMP, JUMPI:
+		if pc+2 > len(code) {
+			return instruction{}, 0, ErrMalformed
+		}
+		return instruction{op: op, target: int(binary.LittleEndian.Uint16(code[pc:]))}, pc + 2, nil
+	}
+	return instruction{op: op}, pc, nil
+}
+
+// instructionStarts() marks the offsets of the code instructions start at, up to the first one
+// that does not decode.
+func instructionStarts(code []byte) []bool {
+	starts := make([]bool, len(code))
+	for pc := 0; pc < len(code); {
+		starts[pc] = true
+		_, next, err := decode(code, pc)
+		if err != nil {
+			break
+		}
+		pc = next
+	}
+	return starts
+}
+
+// Validate() returns an error unless the code decodes and every jump lands on an instruction.
+func Validate(code []byte) error {
+	if len(code) == 0 || len(code) > MAX_CODE_SIZE {
+		return ErrCodeSize
+	}
+	starts := instructionStarts(code)
+	for pc := 0; pc < len(code); {
+		ins, next, err := decode(code, pc)
+		if err != nil {
+			return err
+		}
+		if (ins.op == JUMP || ins.op == JUMPI) && (ins.target >= len(starts) || !starts[ins.target]) {
+			return ErrInvalidJump
+		}
+		pc = next
+	}
+	return nil
+}
+
+// DeployGas() returns the gas deploying the code costs.
+func DeployGas(code []byte) uint64 {
+	return GAS_DEPLOY + uint64(len(code))*GAS_DEPLOY_BYTE
+}
@@ -0,0 +1,260 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/script"
+)
+
+// mapStorage is the storage of a contract outside of a chain.
+type mapStorage map[string][]byte
+
+func (s mapStorage) Get(key []byte) []byte {
+	return s[string(key)]
+}
+
+func (s mapStorage) Set(key, value []byte) {
+	if len(value) == 0 {
+		delete(s, string(key))
+		return
+	}
+	s[string(key)] = append([]byte{}, value...)
+}
+
+func mustAssemble(t *testing.T, text string) []byte {
+	t.Helper()
+	code, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", text, err)
+	}
+	return code
+}
+
+func num(n int64) []byte {
+	return script.EncodeNum(n)
+}
+
+var testContext = &Context{
+	Caller:   "caller",
+	Contract: "contract",
+	Height:   42,
+	Input:    [][]byte{[]byte("first"), num(7)},
+}
+
+func TestOpcodes(t *testing.T) {
+	digest := sha256.Sum256([]byte("abc"))
+	maxInt := strconv.FormatInt(math.MaxInt64, 10)
+	for _, tt := range []struct {
+		name string
+		asm  string
+		want []byte
+		err  error
+	}{
+		{"STOP returns nothing", "1 STOP 2 RETURN", nil, nil},
+		{"running off the end returns nothing", "1", nil, nil},
+		{"PUSH1 of a number", "5 RETURN", num(5), nil},
+		{"PUSH1 of text", "'hello' RETURN", []byte("hello"), nil},
+		{"PUSH2 of data longer than 255 bytes", "<" + string(bytes.Repeat([]byte("ab"), 300)) + "> SIZE RETURN", num(300), nil},
+		{"POP", "1 2 POP RETURN", num(1), nil},
+		{"DUP", "3 DUP ADD RETURN", num(6), nil},
+		{"SWAP", "5 2 SWAP SUB RETURN", num(-3), nil},
+		{"OVER", "5 2 OVER SUB SUB RETURN", num(8), nil},
+		{"ADD", "2 3 ADD RETURN", num(5), nil},
+		{"SUB", "2 3 SUB RETURN", num(-1), nil},
+		{"MUL", "-4 3 MUL RETURN", num(-12), nil},
+		{"DIV truncates", "-7 2 DIV RETURN", num(-3), nil},
+		{"MOD takes the sign of the dividend", "-7 2 MOD RETURN", num(-1), nil},
+		{"LT", "2 3 LT RETURN", num(1), nil},
+		{"GT", "2 3 GT RETURN", num(0), nil},
+		{"EQ of equal items", "'a' 'a' EQ RETURN", num(1), nil},
+		{"EQ of different items", "'a' 'b' EQ RETURN", num(0), nil},
+		{"ISZERO of zero", "0 ISZERO RETURN", num(1), nil},
+		{"AND", "1 0 AND RETURN", num(0), nil},
+		{"OR", "1 0 OR RETURN", num(1), nil},
+		{"JUMP", "JUMP @end 1 RETURN end: 2 RETURN", num(2), nil},
+		{"JUMPI taken", "1 JUMPI @end 1 RETURN end: 2 RETURN", num(2), nil},
+		{"JUMPI not taken", "0 JUMPI @end 1 RETURN end: 2 RETURN", num(1), nil},
+		{"a loop", "0 loop: 1 ADD DUP 10 LT JUMPI @loop RETURN", num(10), nil},
+		{"CALLER", "CALLER RETURN", []byte("caller"), nil},
+		{"ADDRESS", "ADDRESS RETURN", []byte("contract"), nil},
+		{"HEIGHT", "HEIGHT RETURN", num(42), nil},
+		{"ARG", "1 ARG RETURN", num(7), nil},
+		{"NARGS", "NARGS RETURN", num(2), nil},
+		{"SSTORE and SLOAD", "'k' 'v' SSTORE 'k' SLOAD RETURN", []byte("v"), nil},
+		{"SLOAD of an unset key", "'unset' SLOAD SIZE RETURN", num(0), nil},
+		{"SHA256", "'abc' SHA256 RETURN", digest[:], nil},
+		{"CAT", "'ab' 'c' CAT RETURN", []byte("abc"), nil},
+		{"SIZE", "'abc' SIZE RETURN", num(3), nil},
+		{"ASSERT of true", "1 ASSERT 2 RETURN", num(2), nil},
+
+		{"REVERT", "'no' REVERT", nil, &RevertError{[]byte("no")}},
+		{"ASSERT of false", "0 ASSERT", nil, ErrAssert},
+		{"RETURN of an empty stack", "RETURN", nil, ErrStackUnderflow},
+		{"POP of an empty stack", "POP", nil, ErrStackUnderflow},
+		{"OVER of one item", "1 OVER", nil, ErrStackUnderflow},
+		{"SWAP of one item", "1 SWAP", nil, ErrStackUnderflow},
+		{"ADD of one item", "1 ADD", nil, ErrStackUnderflow},
+		{"ADD overflowing", maxInt + " 1 ADD", nil, ErrOverflow},
+		{"SUB overflowing", "-" + maxInt + " 1 SUB", nil, ErrOverflow},
+		{"MUL overflowing", maxInt + " 2 MUL", nil, ErrOverflow},
+		{"DIV by zero", "1 0 DIV", nil, ErrDivisionByZero},
+		{"MOD by zero", "1 0 MOD", nil, ErrDivisionByZero},
+		{"arithmetic on a number too long", "<010203040506070809> 1 ADD", nil, script.ErrNumSize},
+		{"arithmetic on a number not minimally encoded", "<0100> 1 ADD", nil, script.ErrMinimalData},
+		{"ARG out of range", "2 ARG", nil, ErrArgument},
+		{"ARG of a negative index", "-1 ARG", nil, ErrArgument},
+		{"SSTORE of an empty key", "<> 'v' SSTORE", nil, ErrItemSize},
+		{"CAT of items too large", "<" + string(bytes.Repeat([]byte("ab"), 600)) + "> DUP CAT", nil, ErrItemSize},
+		{"a stack too large", "loop: 1 JUMP @loop", nil, ErrStackSize},
+	} {
+		res, err := Execute(mustAssemble(t, tt.asm), testContext, mapStorage{}, MAX_GAS)
+		if tt.err != nil {
+			var revert *RevertError
+			if errors.As(tt.err, &revert) {
+				if got, ok := err.(*RevertError); !ok || !bytes.Equal(got.Message, revert.Message) {
+					t.Errorf("%s: Execute() = %v, want %v", tt.name, err, tt.err)
+				}
+			} else if !errors.Is(err, tt.err) {
+				t.Errorf("%s: Execute() = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Execute() = %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(res.Return, tt.want) {
+			t.Errorf("%s: Execute() returned %x, want %x", tt.name, res.Return, tt.want)
+		}
+	}
+}
+
+func TestMalformedCode(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		code []byte
+		err  error
+	}{
+		{"an unknown opcode", []byte{0xff}, ErrInvalidOpcode},
+		{"a push without its length", []byte{PUSH1}, ErrMalformed},
+		{"a push shorter than its length", []byte{PUSH1, 3, 'a', 'b'}, ErrMalformed},
+		{"a PUSH2 without its length", []byte{PUSH2, 1}, ErrMalformed},
+		{"a jump without its target", []byte{JUMP, 0}, ErrMalformed},
+		{"a jump past the end", []byte{JUMP, 10, 0}, ErrInvalidJump},
+		{"a jump into push data", []byte{JUMP, 4, 0, PUSH1, 1, STOP}, ErrInvalidJump},
+		{"code too large", bytes.Repeat([]byte{STOP}, MAX_CODE_SIZE+1), ErrCodeSize},
+	} {
+		if err := Validate(tt.code); !errors.Is(err, tt.err) {
+			t.Errorf("Validate() of %s = %v, want %v", tt.name, err, tt.err)
+		}
+		if _, err := Execute(tt.code, testContext, mapStorage{}, MAX_GAS); !errors.Is(err, tt.err) {
+			t.Errorf("Execute() of %s = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if err := Validate(nil); !errors.Is(err, ErrCodeSize) {
+		t.Errorf("Validate() of no code = %v, want %v", err, ErrCodeSize)
+	}
+}
+
+func TestAssembleDisassemble(t *testing.T) {
+	text := "NARGS ISZERO JUMPI @empty 0 ARG SHA256 'last' SWAP SSTORE STOP empty: 'empty' REVERT"
+	code := mustAssemble(t, text)
+	asm, err := Disassemble(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := mustAssemble(t, asm); !bytes.Equal(again, code) {
+		t.Errorf("Assemble(Disassemble()) = %x, want %x", again, code)
+	}
+
+	for _, text := range []string{"JUMP @missing", "@start", "a: a: STOP", "PUSH1", "FOO", "<zz>"} {
+		if _, err := Assemble(text); err == nil {
+			t.Errorf("Assemble(%q) succeeded", text)
+		}
+	}
+}
+
+func TestGas(t *testing.T) {
+	code := mustAssemble(t, "'k' 'v' SSTORE 1 JUMP @end end: 'abc' SHA256 RETURN")
+	// two pushes, the store and its two bytes, a push, a jump, a push, the hash and its three bytes, and the return
+	want := uint64(GAS_BASE*2 + GAS_SSTORE + 2*GAS_SSTORE_BYTE + GAS_BASE + GAS_JUMP + GAS_BASE + GAS_HASH + 3*GAS_COPY_PER_BYTE + GAS_BASE)
+
+	res, err := Execute(code, testContext, mapStorage{}, want)
+	if err != nil {
+		t.Fatalf("Execute() with exactly enough gas = %v", err)
+	}
+	if res.GasUsed != want {
+		t.Errorf("GasUsed = %d, want %d", res.GasUsed, want)
+	}
+
+	for limit := uint64(0); limit < want; limit++ {
+		res, err := Execute(code, testContext, mapStorage{}, limit)
+		if !errors.Is(err, ErrOutOfGas) {
+			t.Fatalf("Execute() with %d gas = %v, want %v", limit, err, ErrOutOfGas)
+		}
+		if res.GasUsed != limit {
+			t.Errorf("GasUsed of a call out of gas = %d, want the limit %d", res.GasUsed, limit)
+		}
+	}
+
+	// an endless loop ends when its gas runs out
+	res, err = Execute(mustAssemble(t, "loop: JUMP @loop"), testContext, mapStorage{}, MAX_GAS)
+	if !errors.Is(err, ErrOutOfGas) || res.GasUsed != MAX_GAS {
+		t.Errorf("endless loop = %v after %d gas, want %v after %d", err, res.GasUsed, ErrOutOfGas, MAX_GAS)
+	}
+}
+
+func TestDeterminism(t *testing.T) {
+	code := mustAssemble(t, `
+		'count' SLOAD DUP SIZE JUMPI @counted
+		POP 0
+		counted: 1 ADD DUP 'count' SWAP SSTORE
+		CALLER 0 ARG CAT HEIGHT CAT SHA256 DUP 'last' SWAP SSTORE
+		CAT RETURN`)
+
+	// run() calls the contract three times on a fresh storage
+	run := func() (*Result, mapStorage) {
+		storage := mapStorage{}
+		var res *Result
+		for i := 0; i < 3; i++ {
+			var err error
+			if res, err = Execute(code, testContext, storage, MAX_GAS); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return res, storage
+	}
+	first, firstStorage := run()
+	for i := 0; i < 10; i++ {
+		res, storage := run()
+		if !bytes.Equal(res.Return, first.Return) || res.GasUsed != first.GasUsed {
+			t.Fatalf("run %d returned %x with %d gas, want %x with %d", i, res.Return, res.GasUsed, first.Return, first.GasUsed)
+		}
+		if len(storage) != len(firstStorage) {
+			t.Fatalf("run %d stored %d keys, want %d", i, len(storage), len(firstStorage))
+		}
+		for key, value := range firstStorage {
+			if !bytes.Equal(storage[key], value) {
+				t.Errorf("run %d stored %x at %q, want %x", i, storage[key], key, value)
+			}
+		}
+	}
+	if count := firstStorage["count"]; !bytes.Equal(count, num(3)) {
+		t.Errorf("count after three calls = %x, want 3", count)
+	}
+
+	other := *testContext
+	other.Height++
+	res, err := Execute(code, &other, mapStorage{}, MAX_GAS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh, _ := Execute(code, testContext, mapStorage{}, MAX_GAS); bytes.Equal(res.Return, fresh.Return) {
+		t.Error("call at another height returned the same result")
+	}
+}
@@ -0,0 +1,309 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/utils"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// HardenedKeyStart is the index of the first hardened child key.
+const HardenedKeyStart uint32 = 0x80000000
+
+var (
+	// BIP32 version bytes of serialized extended keys.
+	xprvVersion = []byte{0x04, 0x88, 0xad, 0xe4}
+	xpubVersion = []byte{0x04, 0x88, 0xb2, 0x1e}
+	tprvVersion = []byte{0x04, 0x35, 0x83, 0x94}
+	tpubVersion = []byte{0x04, 0x35, 0x87, 0xcf}
+
+	ErrInvalidChild     = errors.New("wallet: derived key is invalid, use the next index")
+	ErrHardenedFromPub  = errors.New("wallet: cannot derive a hardened key from a public key")
+	ErrInvalidPath      = errors.New("wallet: invalid derivation path")
+	ErrInvalidExtended  = errors.New("wallet: invalid extended key")
+	ErrInvalidSeedBytes = errors.New("wallet: seed must be 16 to 64 bytes")
+)
+
+// ExtendedKey is a struct for a BIP32 extended key on secp256k1.
+type ExtendedKey struct {
+	key               []byte // 32-byte private scalar or 33-byte compressed public key
+	chainCode         []byte
+	depth             uint8
+	parentFingerprint []byte
+	childNumber       uint32
+	isPrivate         bool
+}
+
+// NewMasterKey() derives the master extended private key of a seed.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeedBytes
+	}
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(i[:32])
+	if k.Sign() == 0 || k.Cmp(keys.S256().Params().N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	return &ExtendedKey{
+		key:               i[:32],
+		chainCode:         i[32:],
+		parentFingerprint: []byte{0, 0, 0, 0},
+		isPrivate:         true,
+	}, nil
+}
+
+// IsPrivate() returns whether the extended key holds a private key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// GetDepth() returns the number of derivations from the master key.
+func (k *ExtendedKey) GetDepth() uint8 {
+	return k.depth
+}
+
+// GetChildNumber() returns the index the key was derived with.
+func (k *ExtendedKey) GetChildNumber() uint32 {
+	return k.childNumber
+}
+
+// publicKeyBytes() returns the compressed public key of the extended key.
+func (k *ExtendedKey) publicKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	curve := keys.S256()
+	x, y := curve.ScalarBaseMult(k.key)
+	return compressPoint(x, y)
+}
+
+func compressPoint(x, y *big.Int) []byte {
+	b := make([]byte, 33)
+	b[0] = 0x02 + byte(y.Bit(0))
+	x.FillBytes(b[1:])
+	return b
+}
+
+// ser32() returns the big-endian encoding of a 32-bit integer.
+func ser32(i uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, i)
+	return b
+}
+
+// Fingerprint() returns the first four bytes of the HASH160 of the public key.
+func (k *ExtendedKey) Fingerprint() []byte {
+	return address.Hash160(k.publicKeyBytes())[:4]
+}
+
+// Child() derives the child extended key with the given index using CKDpriv or CKDpub.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	hardened := index >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrHardenedFromPub
+	}
+
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.publicKeyBytes()...)
+	}
+	data = append(data, ser32(index)...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	i := mac.Sum(nil)
+
+	curve := keys.S256()
+	n := curve.Params().N
+	il := new(big.Int).SetBytes(i[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	child := &ExtendedKey{
+		chainCode:         i[32:],
+		depth:             k.depth + 1,
+		parentFingerprint: k.Fingerprint(),
+		childNumber:       index,
+		isPrivate:         k.isPrivate,
+	}
+
+	if k.isPrivate {
+		childKey := il.Add(il, new(big.Int).SetBytes(k.key))
+		childKey.Mod(childKey, n)
+		if childKey.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		child.key = childKey.FillBytes(make([]byte, 32))
+		return child, nil
+	}
+
+	parent, err := utils.ParsePublicKey(curve, k.key)
+	if err != nil {
+		return nil, err
+	}
+	ilx, ily := curve.ScalarBaseMult(i[:32])
+	x, y := curve.Add(ilx, ily, parent.X, parent.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+	child.key = compressPoint(x, y)
+	return child, nil
+}
+
+// Neuter() returns the extended public key of the extended key.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		key:               k.publicKeyBytes(),
+		chainCode:         k.chainCode,
+		depth:             k.depth,
+		parentFingerprint: k.parentFingerprint,
+		childNumber:       k.childNumber,
+	}
+}
+
+// ParsePath() converts a path such as m/44'/0'/0'/0/1 to child indexes. Hardened
+// indexes may be marked with ' or h.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		idx, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(idx) >= HardenedKeyStart {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+		if hardened {
+			idx += uint64(HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(idx))
+	}
+	return indexes, nil
+}
+
+// Derive() derives the descendant extended key at the path relative to this key.
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := k
+	for _, idx := range indexes {
+		if key, err = key.Child(idx); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Signer() returns the secp256k1 private key of an extended private key.
+func (k *ExtendedKey) Signer() (keys.Signer, error) {
+	if !k.isPrivate {
+		return nil, fmt.Errorf("%w: not a private key", ErrInvalidExtended)
+	}
+	return keys.ParsePrivateKey(keys.Secp256k1, k.key)
+}
+
+// Verifier() returns the secp256k1 public key of the extended key.
+func (k *ExtendedKey) Verifier() (keys.Verifier, error) {
+	return keys.ParsePublicKey(keys.Secp256k1, k.publicKeyBytes())
+}
+
+// Serialize() returns the Base58Check xprv/xpub (or tprv/tpub on the test network) encoding of the key.
+func (k *ExtendedKey) Serialize(network *address.Network) string {
+	version := xpubVersion
+	switch {
+	case k.isPrivate && network == address.TestNet:
+		version = tprvVersion
+	case k.isPrivate:
+		version = xprvVersion
+	case network == address.TestNet:
+		version = tpubVersion
+	}
+
+	payload := make([]byte, 0, 82)
+	payload = append(payload, version...)
+	payload = append(payload, k.depth)
+	payload = append(payload, k.parentFingerprint...)
+	payload = append(payload, ser32(k.childNumber)...)
+	payload = append(payload, k.chainCode...)
+	if k.isPrivate {
+		payload = append(payload, 0x00)
+	}
+	payload = append(payload, k.key...)
+	payload = append(payload, address.Checksum(payload)...)
+
+	return base58.Encode(payload)
+}
+
+// ParseExtendedKey() decodes a Base58Check serialized extended key.
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	decoded := base58.Decode(s)
+	if len(decoded) != 82 {
+		return nil, fmt.Errorf("%w: unexpected length", ErrInvalidExtended)
+	}
+
+	payload := decoded[:78]
+	if !bytes.Equal(address.Checksum(payload), decoded[78:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidExtended)
+	}
+
+	version := payload[:4]
+	k := &ExtendedKey{
+		depth:             payload[4],
+		parentFingerprint: append([]byte{}, payload[5:9]...),
+		childNumber:       binary.BigEndian.Uint32(payload[9:13]),
+		chainCode:         append([]byte{}, payload[13:45]...),
+	}
+
+	keyData := payload[45:]
+	switch {
+	case bytes.Equal(version, xprvVersion), bytes.Equal(version, tprvVersion):
+		if keyData[0] != 0x00 {
+			return nil, fmt.Errorf("%w: malformed private key", ErrInvalidExtended)
+		}
+		if _, err := keys.ParsePrivateKey(keys.Secp256k1, keyData[1:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtended, err)
+		}
+		k.key = append([]byte{}, keyData[1:]...)
+		k.isPrivate = true
+	case bytes.Equal(version, xpubVersion), bytes.Equal(version, tpubVersion):
+		if _, err := utils.ParsePublicKey(keys.S256(), keyData); err != nil || len(keyData) != 33 {
+			return nil, fmt.Errorf("%w: malformed public key", ErrInvalidExtended)
+		}
+		k.key = append([]byte{}, keyData...)
+	default:
+		return nil, fmt.Errorf("%w: unknown version", ErrInvalidExtended)
+	}
+
+	return k, nil
+}
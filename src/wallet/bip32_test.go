@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+// The seeds of BIP32 test vectors 1 and 2.
+const (
+	bip32Seed1 = "000102030405060708090a0b0c0d0e0f"
+	bip32Seed2 = "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542"
+)
+
+var bip32Vectors = []struct {
+	seed string
+	path string
+	xpub string
+	xprv string
+}{
+	{bip32Seed1, "m",
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+	{bip32Seed1, "m/0'",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"},
+	{bip32Seed1, "m/0'/1",
+		"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs"},
+	{bip32Seed1, "m/0'/1/2'",
+		"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+		"xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"},
+	{bip32Seed1, "m/0'/1/2'/2",
+		"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		"xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334"},
+	{bip32Seed1, "m/0'/1/2'/2/1000000000",
+		"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"},
+	{bip32Seed2, "m",
+		"xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+		"xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"},
+	{bip32Seed2, "m/0",
+		"xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+		"xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"},
+	{bip32Seed2, "m/0/2147483647'",
+		"xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+		"xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9"},
+	{bip32Seed2, "m/0/2147483647'/1",
+		"xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+		"xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef"},
+	{bip32Seed2, "m/0/2147483647'/1/2147483646'",
+		"xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+		"xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"},
+	{bip32Seed2, "m/0/2147483647'/1/2147483646'/2",
+		"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+		"xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j"},
+}
+
+func TestBIP32Vectors(t *testing.T) {
+	for _, v := range bip32Vectors {
+		seed, _ := hex.DecodeString(v.seed)
+		master, err := NewMasterKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := master.Derive(v.path)
+		if err != nil {
+			t.Fatalf("Derive(%s): %v", v.path, err)
+		}
+		if got := key.Serialize(address.MainNet); got != v.xprv {
+			t.Errorf("private key at %s = %s, want %s", v.path, got, v.xprv)
+		}
+		if got := key.Neuter().Serialize(address.MainNet); got != v.xpub {
+			t.Errorf("public key at %s = %s, want %s", v.path, got, v.xpub)
+		}
+
+		for _, s := range []string{v.xprv, v.xpub} {
+			parsed, err := ParseExtendedKey(s)
+			if err != nil {
+				t.Fatalf("ParseExtendedKey(%s): %v", s, err)
+			}
+			if got := parsed.Serialize(address.MainNet); got != s {
+				t.Errorf("ParseExtendedKey(%s) serializes to %s", s, got)
+			}
+		}
+	}
+}
+
+// TestBIP32PublicDerivation checks that deriving a normal child from the public parent gives
+// the public key of the child derived from the private parent.
+func TestBIP32PublicDerivation(t *testing.T) {
+	for _, v := range bip32Vectors {
+		i := strings.LastIndexByte(v.path, '/')
+		if i < 0 || strings.HasSuffix(v.path, "'") {
+			continue
+		}
+		seed, _ := hex.DecodeString(v.seed)
+		master, _ := NewMasterKey(seed)
+		parent, err := master.Derive(v.path[:i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexes, _ := ParsePath("m" + v.path[i:])
+		child, err := parent.Neuter().Child(indexes[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := child.Serialize(address.MainNet); got != v.xpub {
+			t.Errorf("public derivation of %s = %s, want %s", v.path, got, v.xpub)
+		}
+	}
+}
+
+func TestBIP32HardenedFromPublic(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32Seed1)
+	master, _ := NewMasterKey(seed)
+	if _, err := master.Neuter().Child(HardenedKeyStart); err != ErrHardenedFromPub {
+		t.Errorf("hardened child of a public key = %v, want %v", err, ErrHardenedFromPub)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []uint32
+		wantErr bool
+	}{
+		{"m", []uint32{}, false},
+		{"m/44'/0'/0'/0/1", []uint32{HardenedKeyStart + 44, HardenedKeyStart, HardenedKeyStart, 0, 1}, false},
+		{"m/0h/1", []uint32{HardenedKeyStart, 1}, false},
+		{"44'/0'", nil, true},
+		{"m/x", nil, true},
+		{"m/2147483648", nil, true},
+		{"m//1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePath(%q) error = %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParsePath(%q) = %v, want %v", tt.path, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParsePath(%q) = %v, want %v", tt.path, got, tt.want)
+				break
+			}
+		}
+	}
+}
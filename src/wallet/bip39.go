@@ -0,0 +1,123 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+//go:embed english.txt
+var englishWordlist string
+
+var (
+	wordList  = strings.Fields(englishWordlist)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(wordList))
+		for i, w := range wordList {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+var (
+	ErrEntropyLength    = errors.New("wallet: entropy must be 128 to 256 bits in multiples of 32")
+	ErrMnemonicLength   = errors.New("wallet: mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrMnemonicWord     = errors.New("wallet: mnemonic contains an unknown word")
+	ErrMnemonicChecksum = errors.New("wallet: mnemonic checksum mismatch")
+)
+
+// WordList() returns the BIP39 English word list, indexed by the 11-bit values the words encode.
+func WordList() []string {
+	return append([]string{}, wordList...)
+}
+
+// NewEntropy() returns random entropy of the given size in bits for a mnemonic.
+func NewEntropy(bits int) ([]byte, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return nil, ErrEntropyLength
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic() encodes entropy as a BIP39 mnemonic sentence.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", ErrEntropyLength
+	}
+
+	// Append the first ENT/32 bits of the SHA256 hash of the entropy as a checksum
+	checksumBits := bits / 32
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	// Split the result into groups of 11 bits, each selecting a word
+	count := (bits + checksumBits) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	for i := count - 1; i >= 0; i-- {
+		idx := new(big.Int).And(data, mask)
+		words[i] = wordList[idx.Int64()]
+		data.Rsh(data, 11)
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy() decodes a BIP39 mnemonic sentence and verifies its checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrMnemonicLength
+	}
+
+	data := new(big.Int)
+	for _, w := range words {
+		idx, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMnemonicWord, w)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(idx)))
+	}
+
+	checksumBits := len(words) * 11 / 33
+	checksum := new(big.Int).And(data, big.NewInt(int64(1)<<uint(checksumBits)-1))
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := data.FillBytes(make([]byte, checksumBits*4))
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum.Int64() {
+		return nil, ErrMnemonicChecksum
+	}
+
+	return entropy, nil
+}
+
+// ValidateMnemonic() returns an error if the mnemonic is not a valid BIP39 sentence.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// NewSeed() derives the 64-byte BIP39 seed of a mnemonic protected by an optional passphrase.
+// The mnemonic and passphrase are used as given, without NFKD normalization, so
+// non-ASCII passphrases may not match other implementations.
+func NewSeed(mnemonic, passphrase string) []byte {
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/Rha02/block-beard/src/address"
+)
+
+const (
+	// DefaultGapLimit is the number of consecutive unused addresses after which a scan stops.
+	DefaultGapLimit = 20
+	// DefaultMnemonicBits is the entropy size of newly generated mnemonics.
+	DefaultMnemonicBits = 128
+
+	// BIP44 change values of the address chains of an account.
+	ExternalChain = 0
+	InternalChain = 1
+)
+
+// HDWallet is a struct for a hierarchical deterministic wallet backed by a mnemonic.
+type HDWallet struct {
+	mnemonic string
+	master   *ExtendedKey
+	network  *address.Network
+}
+
+// NewHDWallet() returns a pointer to an HD wallet with a freshly generated mnemonic.
+func NewHDWallet(network *address.Network) (*HDWallet, error) {
+	entropy, err := NewEntropy(DefaultMnemonicBits)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := NewMnemonic(entropy)
+	if err != nil {
+		return nil, err
+	}
+	return RestoreHDWallet(network, mnemonic, "")
+}
+
+// RestoreHDWallet() returns a pointer to the HD wallet of a mnemonic and optional passphrase.
+func RestoreHDWallet(network *address.Network, mnemonic, passphrase string) (*HDWallet, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	master, err := NewMasterKey(NewSeed(mnemonic, passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	return &HDWallet{mnemonic: mnemonic, master: master, network: network}, nil
+}
+
+// GetMnemonic() returns the mnemonic backing the wallet.
+func (h *HDWallet) GetMnemonic() string {
+	return h.mnemonic
+}
+
+// GetMasterKey() returns the master extended private key of the wallet.
+func (h *HDWallet) GetMasterKey() *ExtendedKey {
+	return h.master
+}
+
+// CoinType() returns the BIP44 coin type of a network.
+func CoinType(network *address.Network) uint32 {
+	if network == address.TestNet {
+		return 1
+	}
+	return 0
+}
+
+// AccountPath() returns the BIP44 path m/44'/coin'/account' of an account.
+func (h *HDWallet) AccountPath(account uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'", CoinType(h.network), account)
+}
+
+// AddressPath() returns the BIP44 path of the address at an index of an account's chain.
+func (h *HDWallet) AddressPath(account, change, index uint32) string {
+	return fmt.Sprintf("%s/%d/%d", h.AccountPath(account), change, index)
+}
+
+// Derive() returns the wallet holding the key at the path.
+func (h *HDWallet) Derive(path string) (*Wallet, error) {
+	key, err := h.master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		return nil, err
+	}
+	return NewWalletFromKey(h.network, signer), nil
+}
+
+// Address() returns the wallet at an index of an account's chain.
+func (h *HDWallet) Address(account, change, index uint32) (*Wallet, error) {
+	return h.Derive(h.AddressPath(account, change, index))
+}
+
+// Scan() walks the addresses of an account's chain and returns those for which isUsed
+// reports activity, stopping after gapLimit consecutive unused addresses. It also
+// returns the index of the first address after the last used one.
+func (h *HDWallet) Scan(
+	account, change uint32, gapLimit int, isUsed func(addr string) (bool, error),
+) ([]*Wallet, uint32, error) {
+	var used []*Wallet
+	var next uint32
+
+	for index, gap := uint32(0), 0; gap < gapLimit; index++ {
+		w, err := h.Address(account, change, index)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		ok, err := isUsed(w.GetAddress())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if ok {
+			used = append(used, w)
+			next = index + 1
+			gap = 0
+		} else {
+			gap++
+		}
+	}
+
+	return used, next, nil
+}
@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/multisig"
+)
+
+var ErrTransactionMismatch = errors.New("wallet: partial transactions spend differently")
+
+// PartialTransaction is a spend from a multisig address that co-signers sign one at a time,
+// in the spirit of a PSBT. Signatures holds one hex encoded slot per key of the policy, in the
+// policy's order, and is empty where a co-signer has not signed yet.
+type PartialTransaction struct {
+	SenderAddress    string           `json:"sender_address"`
+	RecipientAddress string           `json:"recipient_address"`
+	Amount           float32          `json:"amount"`
+	Fee              float32          `json:"fee,omitempty"`
+	Nonce            uint64           `json:"nonce,omitempty"`
+	Multisig         *multisig.Policy `json:"multisig"`
+	SigningPayload   string           `json:"signing_payload"`
+	Signatures       []string         `json:"signatures"`
+}
+
+// NewPartialTransaction() builds an unsigned spend from the address of the policy, carrying the nonce
+// the node reports with the balance of the address.
+func NewPartialTransaction(
+	policy *multisig.Policy, network *address.Network, recipientAddress string, amount, fee float32, nonce uint64,
+) *PartialTransaction {
+	pt := &PartialTransaction{
+		SenderAddress:    policy.Address(network),
+		RecipientAddress: recipientAddress,
+		Amount:           amount,
+		Fee:              fee,
+		Nonce:            nonce,
+		Multisig:         policy,
+		Signatures:       make([]string, policy.N()),
+	}
+	pt.SigningPayload = hex.EncodeToString(pt.payload())
+	return pt
+}
+
+// payload() returns the bytes every co-signer signs, as produced by Transaction.MarshalJSON().
+func (pt *PartialTransaction) payload() []byte {
+	t := NewTransaction(nil, pt.SenderAddress, pt.RecipientAddress, pt.Amount, pt.Fee)
+	t.SetNonce(pt.Nonce)
+	m, err := json.Marshal(t)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// check() returns an error unless the transaction is well formed and spends from the address of its policy.
+func (pt *PartialTransaction) check(network *address.Network) error {
+	if pt.Multisig == nil {
+		return errors.New("wallet: partial transaction has no multisig policy")
+	}
+	if pt.Multisig.Address(network) != pt.SenderAddress {
+		return errors.New("wallet: multisig policy does not match the sender address")
+	}
+	if len(pt.Signatures) != pt.Multisig.N() {
+		return fmt.Errorf("wallet: expected %d signature slots, got %d", pt.Multisig.N(), len(pt.Signatures))
+	}
+	if pt.SigningPayload != hex.EncodeToString(pt.payload()) {
+		return errors.New("wallet: signing payload does not match the transaction")
+	}
+	return nil
+}
+
+// Sign() adds the signature of a co-signer's private key.
+func (pt *PartialTransaction) Sign(network *address.Network, signer keys.Signer) error {
+	if err := pt.check(network); err != nil {
+		return err
+	}
+	i, err := pt.Multisig.Index(signer.Public())
+	if err != nil {
+		return ErrKeyMismatch
+	}
+
+	signature, err := signer.Sign(pt.payload())
+	if err != nil {
+		return err
+	}
+	pt.Signatures[i] = hex.EncodeToString(signature)
+	return nil
+}
+
+// Combine() adds the signatures of another copy of the same transaction signed by other co-signers.
+func (pt *PartialTransaction) Combine(other *PartialTransaction) error {
+	if other.SigningPayload != pt.SigningPayload || other.Multisig == nil || pt.Multisig == nil ||
+		!bytes.Equal(other.Multisig.Bytes(), pt.Multisig.Bytes()) || len(other.Signatures) != len(pt.Signatures) {
+		return ErrTransactionMismatch
+	}
+	for i, signature := range other.Signatures {
+		if pt.Signatures[i] == "" {
+			pt.Signatures[i] = signature
+		}
+	}
+	return nil
+}
+
+// SignatureCount() returns the number of co-signers that have signed.
+func (pt *PartialTransaction) SignatureCount() int {
+	count := 0
+	for _, signature := range pt.Signatures {
+		if signature != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// IsComplete() returns whether enough co-signers have signed to broadcast the transaction.
+func (pt *PartialTransaction) IsComplete() bool {
+	return pt.Multisig != nil && pt.SignatureCount() >= pt.Multisig.M
+}
+
+// Verify() returns an error unless the transaction carries at least the threshold of valid signatures.
+func (pt *PartialTransaction) Verify(network *address.Network) error {
+	if err := pt.check(network); err != nil {
+		return err
+	}
+	signatures := make([][]byte, len(pt.Signatures))
+	for i, signature := range pt.Signatures {
+		if signature == "" {
+			continue
+		}
+		b, err := hex.DecodeString(signature)
+		if err != nil {
+			return fmt.Errorf("wallet: invalid signature: %v", err)
+		}
+		signatures[i] = b
+	}
+	return pt.Multisig.Verify(pt.payload(), signatures)
+}
@@ -1,74 +1,177 @@
 package wallet
 
 import (
-	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
 
-	"github.com/Rha02/block-beard/src/utils"
+	"github.com/Rha02/block-beard/src/keys"
 )
 
+// FeeLevel is a named fee a wallet offers to pay the miner of a transaction.
+type FeeLevel struct {
+	Name string  `json:"name"`
+	Fee  float32 `json:"fee"`
+}
+
+// FeeLevels are the fees offered when sending, cheapest first.
+var FeeLevels = []FeeLevel{
+	{"none", 0},
+	{"economy", 0.001},
+	{"normal", 0.01},
+	{"priority", 0.05},
+}
+
+// ParseFee() converts the name of a fee level or a decimal fee to a fee. An empty string is no fee.
+func ParseFee(s string) (float32, error) {
+	if s == "" {
+		return 0, nil
+	}
+	for _, level := range FeeLevels {
+		if level.Name == s {
+			return level.Fee, nil
+		}
+	}
+	fee, err := strconv.ParseFloat(s, 32)
+	if err != nil || math.IsNaN(fee) || math.IsInf(fee, 0) {
+		return 0, errors.New("wallet: invalid fee: " + s)
+	}
+	if fee < 0 {
+		return 0, errors.New("wallet: fee must not be negative")
+	}
+	return float32(fee), nil
+}
+
 // Transaction is a struct for a transaction.
 type Transaction struct {
-	senderPrivateKey *ecdsa.PrivateKey
-	senderPublicKey  *ecdsa.PublicKey
+	senderPrivateKey keys.Signer
 	senderAddress    string
 	recipientAddress string
 	amount           float32
+	fee              float32
+	nonce            uint64
+	locktime         int64
+	code             []byte
+	input            [][]byte
+	gasLimit         uint64
+	token            string
+	tokenAction      string
+	mintable         bool
 }
 
-// NewTransaction creates a new transaction.
+// NewTransaction creates a new transaction paying the fee to the miner.
 func NewTransaction(
-	privateKey *ecdsa.PrivateKey, publicKey *ecdsa.PublicKey,
-	senderAddress, recipientAddress string, amount float32,
+	privateKey keys.Signer, senderAddress, recipientAddress string, amount, fee float32,
 ) *Transaction {
 	return &Transaction{
 		senderPrivateKey: privateKey,
-		senderPublicKey:  publicKey,
 		senderAddress:    senderAddress,
 		recipientAddress: recipientAddress,
 		amount:           amount,
+		fee:              fee,
 	}
 }
 
+// SetNonce() sets the number of transactions the sender sent before this one, which the
+// blockchain requires so the signed transaction cannot be replayed.
+func (t *Transaction) SetNonce(nonce uint64) {
+	t.nonce = nonce
+}
+
+// SetLocktime() sets the block height or unix time before which the transaction cannot be mined.
+func (t *Transaction) SetLocktime(locktime int64) {
+	t.locktime = locktime
+}
+
+// SetContract() makes the transaction deploy the code, or call the contract it is sent to with the
+// input if the code is empty, using at most gasLimit gas.
+func (t *Transaction) SetContract(code []byte, input [][]byte, gasLimit uint64) {
+	t.code = code
+	t.input = input
+	t.gasLimit = gasLimit
+}
+
+// SetToken() makes the transaction issue, mint, transfer or burn the amount of the token with the
+// symbol; mintable sets whether an issued token can be minted later.
+func (t *Transaction) SetToken(symbol, action string, mintable bool) {
+	t.token = symbol
+	t.tokenAction = action
+	t.mintable = mintable
+}
+
 // MarshalJSON is a custom JSON marshaller for the Transaction struct.
+// A zero fee, nonce, lock time, contract or token field is left out, matching the payload the blockchain verifies.
 func (t *Transaction) MarshalJSON() ([]byte, error) {
+	var input []string
+	for _, arg := range t.input {
+		input = append(input, hex.EncodeToString(arg))
+	}
+
 	return json.Marshal(struct {
-		Sender    string  `json:"sender_address"`
-		Recipient string  `json:"recipient_address"`
-		Amount    float32 `json:"amount"`
+		Sender      string   `json:"sender_address"`
+		Recipient   string   `json:"recipient_address"`
+		Amount      float32  `json:"amount"`
+		Fee         float32  `json:"fee,omitempty"`
+		Nonce       uint64   `json:"nonce,omitempty"`
+		Locktime    int64    `json:"locktime,omitempty"`
+		Code        string   `json:"code,omitempty"`
+		Input       []string `json:"input,omitempty"`
+		GasLimit    uint64   `json:"gas_limit,omitempty"`
+		Token       string   `json:"token,omitempty"`
+		TokenAction string   `json:"token_action,omitempty"`
+		Mintable    bool     `json:"mintable,omitempty"`
 	}{
-		Sender:    t.senderAddress,
-		Recipient: t.recipientAddress,
-		Amount:    t.amount,
+		Sender:      t.senderAddress,
+		Recipient:   t.recipientAddress,
+		Amount:      t.amount,
+		Fee:         t.fee,
+		Nonce:       t.nonce,
+		Locktime:    t.locktime,
+		Code:        hex.EncodeToString(t.code),
+		Input:       input,
+		GasLimit:    t.gasLimit,
+		Token:       t.token,
+		TokenAction: t.tokenAction,
+		Mintable:    t.mintable,
 	})
 }
 
-// GenerateSignature() generates a signature for the transaction.
-func (t *Transaction) GenerateSignature() *utils.Signature {
+// GenerateSignature() generates a deterministic signature for the transaction.
+func (t *Transaction) GenerateSignature() []byte {
 	m, err := json.Marshal(t)
 	if err != nil {
 		panic(err)
 	}
-	h := sha256.Sum256(m)
 
-	r, s, err := ecdsa.Sign(rand.Reader, t.senderPrivateKey, h[:])
+	signature, err := t.senderPrivateKey.Sign(m)
 	if err != nil {
 		panic(err)
 	}
 
-	return &utils.Signature{R: r, S: s}
+	return signature
 }
 
 type TransactionRequest struct {
+	KeyID            *string `json:"key_id"`
 	SenderPrivateKey *string `json:"sender_private_key"`
 	SenderPublicKey  *string `json:"sender_public_key"`
 	SenderAddress    *string `json:"sender_address"`
 	RecipientAddress *string `json:"recipient_address"`
 	Amount           *string `json:"amount"`
+	Fee              *string `json:"fee"`
+	SignatureScheme  *string `json:"signature_scheme"`
 }
 
+// IsValid() returns whether the request names a recipient and amount and either a
+// keystore key or a raw sender key pair.
 func (t *TransactionRequest) IsValid() bool {
-	return t.SenderPrivateKey != nil && t.SenderPublicKey != nil && t.SenderAddress != nil && t.RecipientAddress != nil && t.Amount != nil
+	if t.RecipientAddress == nil || t.Amount == nil {
+		return false
+	}
+	if t.KeyID != nil {
+		return true
+	}
+	return t.SenderPrivateKey != nil && t.SenderPublicKey != nil && t.SenderAddress != nil
 }
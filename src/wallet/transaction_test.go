@@ -0,0 +1,28 @@
+package wallet
+
+import "testing"
+
+func TestParseFee(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		fee float32
+	}{
+		{"", 0},
+		{"none", 0},
+		{"normal", 0.01},
+		{"0", 0},
+		{"0.25", 0.25},
+		{"1e-3", 0.001},
+	} {
+		fee, err := ParseFee(tt.s)
+		if err != nil || fee != tt.fee {
+			t.Errorf("ParseFee(%q) = %v, %v, want %v", tt.s, fee, err, tt.fee)
+		}
+	}
+
+	for _, s := range []string{"fast", "-0.1", "NaN", "nan", "Inf", "+Inf", "-Inf", "infinity", "1e39", "0x"} {
+		if fee, err := ParseFee(s); err == nil {
+			t.Errorf("ParseFee(%q) = %v, want an error", s, fee)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+var ErrKeyMismatch = errors.New("wallet: key does not belong to the transaction sender")
+
+// UnsignedTransaction is a struct for a transaction built for signing outside the wallet server.
+type UnsignedTransaction struct {
+	SenderAddress    string      `json:"sender_address"`
+	RecipientAddress string      `json:"recipient_address"`
+	Amount           float32     `json:"amount"`
+	Fee              float32     `json:"fee,omitempty"`
+	Nonce            uint64      `json:"nonce,omitempty"`
+	SignatureScheme  keys.Scheme `json:"signature_scheme"`
+	SenderPublicKey  string      `json:"sender_public_key"`
+	SigningPayload   string      `json:"signing_payload"`
+}
+
+// SignedTransaction is a struct for an unsigned transaction together with the sender's signature.
+type SignedTransaction struct {
+	UnsignedTransaction
+	Signature string `json:"signature"`
+}
+
+// NewUnsignedTransaction() builds a transaction from the sender's public key and fills in its signing payload.
+// The nonce is the number of transactions the sender has sent, which the node reports with its balance.
+func NewUnsignedTransaction(
+	publicKey keys.Verifier, network *address.Network, recipientAddress string, amount, fee float32, nonce uint64,
+) *UnsignedTransaction {
+	ut := &UnsignedTransaction{
+		SenderAddress:    address.FromPublicKey(publicKey, network),
+		RecipientAddress: recipientAddress,
+		Amount:           amount,
+		Fee:              fee,
+		Nonce:            nonce,
+		SignatureScheme:  publicKey.Scheme(),
+		SenderPublicKey:  keys.ToString(publicKey),
+	}
+	ut.SigningPayload = hex.EncodeToString(ut.payload())
+	return ut
+}
+
+// payload() returns the bytes the sender signs, as produced by Transaction.MarshalJSON().
+func (ut *UnsignedTransaction) payload() []byte {
+	t := NewTransaction(nil, ut.SenderAddress, ut.RecipientAddress, ut.Amount, ut.Fee)
+	t.SetNonce(ut.Nonce)
+	m, err := json.Marshal(t)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// publicKey() decodes the sender's public key and checks that it owns the sender address.
+func (ut *UnsignedTransaction) publicKey(network *address.Network) (keys.Verifier, error) {
+	publicKey, err := keys.PublicKeyFromString(ut.SignatureScheme, ut.SenderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if address.FromPublicKey(publicKey, network) != ut.SenderAddress {
+		return nil, ErrKeyMismatch
+	}
+	return publicKey, nil
+}
+
+// Sign() signs the transaction with the sender's private key.
+func (ut *UnsignedTransaction) Sign(signer keys.Signer) (*SignedTransaction, error) {
+	if signer.Scheme() != ut.SignatureScheme || keys.ToString(signer.Public()) != ut.SenderPublicKey {
+		return nil, ErrKeyMismatch
+	}
+	if ut.SigningPayload != hex.EncodeToString(ut.payload()) {
+		return nil, errors.New("wallet: signing payload does not match the transaction")
+	}
+
+	signature, err := signer.Sign(ut.payload())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedTransaction{
+		UnsignedTransaction: *ut,
+		Signature:           hex.EncodeToString(signature),
+	}, nil
+}
+
+// Verify() returns an error unless the signature is a valid signature of the sender over the transaction.
+func (st *SignedTransaction) Verify(network *address.Network) error {
+	publicKey, err := st.publicKey(network)
+	if err != nil {
+		return err
+	}
+
+	signature, err := hex.DecodeString(st.Signature)
+	if err != nil {
+		return fmt.Errorf("wallet: invalid signature: %v", err)
+	}
+	if !publicKey.Verify(st.payload(), signature) {
+		return errors.New("wallet: signature verification failed")
+	}
+	return nil
+}
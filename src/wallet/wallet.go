@@ -1,82 +1,55 @@
 package wallet
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/sha256"
 	"encoding/json"
-	"fmt"
 
-	"github.com/btcsuite/btcutil/base58"
-	"golang.org/x/crypto/ripemd160"
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keys"
 )
 
 // Wallet is a struct for a wallet.
 type Wallet struct {
 	address    string
-	privateKey *ecdsa.PrivateKey
-	publicKey  *ecdsa.PublicKey
+	privateKey keys.Signer
+	publicKey  keys.Verifier
 }
 
-// NewWallet() returns a pointer to a new wallet.
-func NewWallet() *Wallet {
-	// Create a new wallet
-	w := new(Wallet)
-
+// NewWallet() returns a pointer to a new wallet on the given network using keys of the given scheme.
+func NewWallet(network *address.Network, scheme keys.Scheme) *Wallet {
 	// Generate a new private key
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privateKey, err := keys.GenerateKey(scheme)
 	if err != nil {
 		panic(err)
 	}
-	w.privateKey = privateKey
-
-	// Set the public key
-	w.publicKey = &privateKey.PublicKey
-
-	// Generate SHA256 hash of the public key
-	h := sha256.New()
-	h.Write(w.publicKey.X.Bytes())
-	h.Write(w.publicKey.Y.Bytes())
-	digest := h.Sum(nil)
-
-	// Generate RIPEMD160 hash of the SHA256 hash
-	h2 := ripemd160.New()
-	h2.Write(digest)
-	digest2 := h2.Sum(nil)
 
-	// Add the network byte to the beginning of the hash
-	digest3 := append([]byte{0x00}, digest2...)
-
-	// Generate SHA256 hash of the hash
-	h3 := sha256.New()
-	h3.Write(digest3)
-	digest4 := h3.Sum(nil)
+	return NewWalletFromKey(network, privateKey)
+}
 
-	// Generate SHA256 hash of the hash
-	h4 := sha256.New()
-	h4.Write(digest4)
-	digest5 := h4.Sum(nil)
+// NewWalletFromKey() returns a pointer to a wallet holding an existing private key.
+func NewWalletFromKey(network *address.Network, privateKey keys.Signer) *Wallet {
+	// Create a new wallet
+	w := new(Wallet)
+	w.privateKey = privateKey
 
-	// Take the first 4 bytes of the hash and append it to the end of the hash
-	digest6 := append(digest3, digest5[:4]...)
+	// Set the public key
+	w.publicKey = privateKey.Public()
 
-	// Convert the hash to a base58 string
-	w.address = base58.Encode(digest6)
+	// Derive the address from the public key
+	w.address = address.FromPublicKey(w.publicKey, network)
 
 	return w
 }
 
-// MarshalJSON() returns the JSON representation of the wallet.
+// MarshalJSON() returns the JSON representation of the wallet, which leaves out the private key.
 func (w *Wallet) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		PrivateKey string `json:"private_key"`
-		PublicKey  string `json:"public_key"`
-		Address    string `json:"address"`
+		PublicKey string `json:"public_key"`
+		Address   string `json:"address"`
+		Scheme    string `json:"signature_scheme"`
 	}{
-		PrivateKey: w.GetPrivateKeyStr(),
-		PublicKey:  w.GetPublicKeyStr(),
-		Address:    w.GetAddress(),
+		PublicKey: w.GetPublicKeyStr(),
+		Address:   w.GetAddress(),
+		Scheme:    string(w.GetScheme()),
 	})
 }
 
@@ -85,22 +58,27 @@ func (w *Wallet) GetAddress() string {
 	return w.address
 }
 
+// GetScheme() returns the signature scheme of the wallet's keys.
+func (w *Wallet) GetScheme() keys.Scheme {
+	return w.privateKey.Scheme()
+}
+
 // GetPrivateKey() returns the private key of the wallet.
-func (w *Wallet) GetPrivateKey() *ecdsa.PrivateKey {
+func (w *Wallet) GetPrivateKey() keys.Signer {
 	return w.privateKey
 }
 
 // GetPrivateKeyStr() returns the private key of the wallet as a string.
 func (w *Wallet) GetPrivateKeyStr() string {
-	return fmt.Sprintf("%x", w.privateKey.D.Bytes())
+	return keys.ToString(w.privateKey)
 }
 
 // GetPublicKey() returns the public key of the wallet.
-func (w *Wallet) GetPublicKey() *ecdsa.PublicKey {
+func (w *Wallet) GetPublicKey() keys.Verifier {
 	return w.publicKey
 }
 
 // GetPublicKeyStr() returns the public key of the wallet as a string.
 func (w *Wallet) GetPublicKeyStr() string {
-	return fmt.Sprintf("%064x%064x", w.publicKey.X.Bytes(), w.publicKey.Y.Bytes())
+	return keys.ToString(w.publicKey)
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rha02/block-beard/src/blockchain"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// EXPLORER_PAGE_SIZE is the number of blocks or transactions listed per explorer page.
+const EXPLORER_PAGE_SIZE = 20
+
+// explorerPages are the explorer templates; each is rendered inside layout.html.
+var explorerPages = []string{"blocks", "block", "transaction", "address", "mempool", "peers", "error"}
+
+// parseExplorerTemplates() parses the embedded explorer templates by page name.
+func parseExplorerTemplates() map[string]*template.Template {
+	funcs := template.FuncMap{
+		"timestamp": func(ns int64) string {
+			return time.Unix(0, ns).UTC().Format("2006-01-02 15:04:05 MST")
+		},
+		"reward": func(bcAddress string) bool {
+			return bcAddress == blockchain.MINING_SENDER
+		},
+		"short": func(s string) string {
+			if len(s) <= 16 {
+				return s
+			}
+			return s[:8] + "…" + s[len(s)-8:]
+		},
+	}
+
+	pages := make(map[string]*template.Template, len(explorerPages))
+	for _, page := range explorerPages {
+		pages[page] = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(
+			templates, "templates/explorer/layout.html", "templates/explorer/"+page+".html",
+		))
+	}
+	return pages
+}
+
+// render() writes an explorer page with the status.
+func (s *Server) render(w http.ResponseWriter, status int, page, title string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := s.explorer[page].Execute(w, struct {
+		Title string
+		Data  interface{}
+	}{title, data})
+	if err != nil {
+		fmt.Println("Error rendering explorer page:", err)
+	}
+}
+
+// explorerCall() runs a registered method for an explorer page, rendering its error if it fails.
+func (s *Server) explorerCall(w http.ResponseWriter, r *http.Request, name string, params map[string]interface{}) (interface{}, bool) {
+	result, apiErr := s.methods[name](r.Context(), namedParams(params))
+	if apiErr != nil {
+		s.render(w, apiErr.status, "error", "Error", apiErr.message)
+		return nil, false
+	}
+	return result, true
+}
+
+// ExplorerHandler serves the block explorer: recent blocks at /explorer/ and pages for blocks,
+// transactions, addresses, the pool and the peers below it.
+func (s *Server) ExplorerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	switch strings.TrimPrefix(r.URL.Path, "/explorer") {
+	case "/", "":
+		s.explorerBlocks(w, r, query.Get("cursor"))
+	case "/block":
+		s.explorerBlock(w, r, query.Get("hash"), query.Get("height"))
+	case "/tx":
+		s.explorerTransaction(w, r, query.Get("id"))
+	case "/address":
+		s.explorerAddress(w, r, query.Get("address"), query.Get("cursor"))
+	case "/mempool":
+		if pool, ok := s.explorerCall(w, r, "getMempool", nil); ok {
+			s.render(w, http.StatusOK, "mempool", "Mempool", pool)
+		}
+	case "/peers":
+		if peers, ok := s.explorerCall(w, r, "getPeerInfo", nil); ok {
+			s.render(w, http.StatusOK, "peers", "Peers", peers)
+		}
+	case "/search":
+		s.explorerSearch(w, r, strings.TrimSpace(query.Get("q")))
+	default:
+		s.render(w, http.StatusNotFound, "error", "Not found", "Page not found")
+	}
+}
+
+func (s *Server) explorerBlocks(w http.ResponseWriter, r *http.Request, cursor string) {
+	tip, ok := s.explorerCall(w, r, "getChainTip", nil)
+	if !ok {
+		return
+	}
+	page, ok := s.explorerCall(w, r, "getBlocks", map[string]interface{}{"cursor": cursor, "limit": EXPLORER_PAGE_SIZE})
+	if !ok {
+		return
+	}
+	s.render(w, http.StatusOK, "blocks", "Blocks", struct {
+		Tip  *blockchain.ChainTip
+		Page *blockchain.BlockPage
+	}{tip.(*blockchain.ChainTip), page.(*blockchain.BlockPage)})
+}
+
+func (s *Server) explorerBlock(w http.ResponseWriter, r *http.Request, hash, height string) {
+	var block interface{}
+	var ok bool
+	if hash != "" {
+		block, ok = s.explorerCall(w, r, "getBlockByHash", map[string]interface{}{"hash": hash})
+	} else {
+		n, err := strconv.Atoi(height)
+		if err != nil {
+			s.render(w, http.StatusBadRequest, "error", "Error", "Invalid block height")
+			return
+		}
+		block, ok = s.explorerCall(w, r, "getBlockByHeight", map[string]interface{}{"height": n})
+	}
+	if ok {
+		s.render(w, http.StatusOK, "block", "Block", block)
+	}
+}
+
+// explorerTransaction() renders a transaction with the receipt of the contract transactions.
+func (s *Server) explorerTransaction(w http.ResponseWriter, r *http.Request, id string) {
+	t, ok := s.explorerCall(w, r, "getTransaction", map[string]interface{}{"id": id})
+	if !ok {
+		return
+	}
+	receipt, _ := s.GetBlockchain().Receipt(id)
+	s.render(w, http.StatusOK, "transaction", "Transaction", struct {
+		*blockchain.TransactionView
+		Receipt *blockchain.Receipt
+	}{t.(*blockchain.TransactionView), receipt})
+}
+
+func (s *Server) explorerAddress(w http.ResponseWriter, r *http.Request, bcAddress, cursor string) {
+	summary, ok := s.explorerCall(w, r, "getAddress", map[string]interface{}{"address": bcAddress})
+	if !ok {
+		return
+	}
+	amounts, ok := s.explorerCall(w, r, "getBalance", map[string]interface{}{"address": bcAddress})
+	if !ok {
+		return
+	}
+	history, ok := s.explorerCall(w, r, "getAddressHistory", map[string]interface{}{
+		"address": bcAddress, "cursor": cursor, "limit": EXPLORER_PAGE_SIZE,
+	})
+	if !ok {
+		return
+	}
+	s.render(w, http.StatusOK, "address", "Address", struct {
+		Summary *blockchain.AddressSummary
+		Amounts *blockchain.AmountResponse
+		History *blockchain.AddressHistory
+	}{summary.(*blockchain.AddressSummary), amounts.(*blockchain.AmountResponse), history.(*blockchain.AddressHistory)})
+}
+
+// explorerSearch() redirects to the page of a block height, a block hash, a transaction ID or an address.
+func (s *Server) explorerSearch(w http.ResponseWriter, r *http.Request, q string) {
+	target := "/explorer/address?address=" + url.QueryEscape(q)
+	if _, err := strconv.Atoi(q); err == nil {
+		target = "/explorer/block?height=" + q
+	} else if decoded, err := hex.DecodeString(q); err == nil && len(decoded) == 32 {
+		target = "/explorer/tx?id=" + q
+		var hash [32]byte
+		copy(hash[:], decoded)
+		if _, _, ok := s.GetBlockchain().GetBlockByHash(hash); ok {
+			target = "/explorer/block?hash=" + q
+		}
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
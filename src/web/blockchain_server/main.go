@@ -1,20 +1,105 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"log"
+	"os"
+	"strings"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/keystore"
 )
 
+// passphraseEnv is the environment variable the passphrase of the validator key is read from when
+// no passphrase file is given.
+const passphraseEnv = "BEARD_VALIDATOR_PASSPHRASE"
+
 func init() {
 	log.SetPrefix("Blockchain: ")
 }
 
+// readValidatorKey() decrypts the keystore key file of the node's validator.
+func readValidatorKey(file, passphraseFile string) (keys.Signer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	kf, err := keystore.ParseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, ok := os.LookupEnv(passphraseEnv)
+	if passphraseFile != "" {
+		b, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, ok = strings.TrimRight(string(b), "\r\n"), true
+	}
+	if !ok {
+		return nil, errors.New("the validator key needs -passphrase-file or $" + passphraseEnv)
+	}
+	return kf.Decrypt(passphrase)
+}
+
+// newConsensusEngine() returns the engine named by -consensus and the key the node produces
+// blocks with, which is nil unless the node is a proof of authority validator.
+func newConsensusEngine(name, validators, validatorKey, passphraseFile string) (blockchain.ConsensusEngine, keys.Signer, error) {
+	switch name {
+	case "pow":
+		return blockchain.NewProofOfWork(blockchain.MiningDifficulty), nil, nil
+	case "poa":
+		publicKeys, err := blockchain.ParseValidators(validators)
+		if err != nil {
+			return nil, nil, err
+		}
+		var signer keys.Signer
+		if validatorKey != "" {
+			if signer, err = readValidatorKey(validatorKey, passphraseFile); err != nil {
+				return nil, nil, err
+			}
+		}
+		engine, err := blockchain.NewProofOfAuthority(publicKeys, signer)
+		if err != nil {
+			return nil, nil, err
+		}
+		return engine, signer, nil
+	default:
+		return nil, nil, errors.New("unknown consensus engine " + name)
+	}
+}
+
 func main() {
 	port := flag.Uint("port", 3000, "port to listen on")
+	networkName := flag.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	dataDir := flag.String("datadir", "", "directory to keep the chain in (default in memory only)")
+	txIndex := flag.Bool("txindex", false, "index transactions by ID")
+	consensus := flag.String("consensus", "pow", "consensus engine: pow, or poa for a private network of validators")
+	validators := flag.String("validators", "", "comma separated [scheme:]hex public keys of the poa validators, in the order they produce blocks")
+	validatorKey := flag.String("validator-key", "", "keystore key file of this node's poa validator (default follow the chain only)")
+	passphraseFile := flag.String("passphrase-file", "", "file containing the passphrase of -validator-key (default $"+passphraseEnv+")")
 	flag.Parse()
 
-	log.Printf("Starting server on port %d", *port)
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	engine, signer, err := newConsensusEngine(*consensus, *validators, *validatorKey, *passphraseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// a validator collects the rewards of its blocks at the address of its key
+	var miningAddress string
+	if signer != nil {
+		miningAddress = address.FromPublicKey(signer.Public(), network)
+	}
+
+	log.Printf("Starting server on port %d with %s consensus", *port, engine.Name())
 
-	server := NewServer(uint16(*port))
+	server := NewServer(uint16(*port), network, *dataDir, *txIndex, engine, miningAddress)
 	server.Start()
 }
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/utils"
+	"github.com/Rha02/block-beard/src/vm"
+)
+
+// JSON-RPC 2.0 error codes; -32000 to -32099 are reserved for application errors.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeRejected       = -32000
+	codeNotFound       = -32001
+)
+
+// apiError is an error returned by a method, with its REST status and its JSON-RPC code.
+type apiError struct {
+	status  int
+	code    int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errInvalidParams(format string, a ...interface{}) *apiError {
+	return &apiError{http.StatusBadRequest, codeInvalidParams, fmt.Sprintf(format, a...)}
+}
+
+func errNotFound(format string, a ...interface{}) *apiError {
+	return &apiError{http.StatusNotFound, codeNotFound, fmt.Sprintf(format, a...)}
+}
+
+func errRejected(format string, a ...interface{}) *apiError {
+	return &apiError{http.StatusBadRequest, codeRejected, fmt.Sprintf(format, a...)}
+}
+
+// method is an API operation shared by the REST handlers and the JSON-RPC endpoint.
+type method func(ctx context.Context, params json.RawMessage) (interface{}, *apiError)
+
+// registerMethods() builds the method registry of the server.
+func (s *Server) registerMethods() map[string]method {
+	return map[string]method{
+		"getChainInfo":      s.getChainInfo,
+		"getBlockByHeight":  s.getBlockByHeight,
+		"getBlockByHash":    s.getBlockByHash,
+		"getBlocks":         s.getBlocks,
+		"getTransaction":    s.getTransaction,
+		"getChainTip":       s.getChainTip,
+		"getBalance":        s.getBalance,
+		"getAddress":        s.getAddress,
+		"getAddressHistory": s.getAddressHistory,
+		"sendTransaction":   s.sendTransaction,
+		"getMempool":        s.getMempool,
+		"getPeerInfo":       s.getPeerInfo,
+		"getMiningInfo":     s.getMiningInfo,
+		"getContract":       s.getContract,
+		"callContract":      s.callContract,
+		"getReceipt":        s.getReceipt,
+		"getTokens":         s.getTokens,
+		"getToken":          s.getToken,
+		"getTokenBalance":   s.getTokenBalance,
+	}
+}
+
+// parseParams() decodes params given by name, as an object, or by position, as an array
+// whose elements are matched to names. A method without names takes its single parameter as
+// the object itself or as the only element of an array.
+func parseParams(params json.RawMessage, out interface{}, names ...string) *apiError {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		params = []byte("{}")
+	}
+
+	if params[0] == '[' {
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil {
+			return errInvalidParams("invalid params: %v", err)
+		}
+		if len(names) == 0 {
+			if len(positional) != 1 {
+				return errInvalidParams("invalid params: expected 1 parameter")
+			}
+			params = positional[0]
+		} else {
+			if len(positional) > len(names) {
+				return errInvalidParams("invalid params: expected at most %d parameters", len(names))
+			}
+			named := make(map[string]json.RawMessage, len(positional))
+			for i, p := range positional {
+				named[names[i]] = p
+			}
+			params, _ = json.Marshal(named)
+		}
+	}
+
+	if err := json.Unmarshal(params, out); err != nil {
+		return errInvalidParams("invalid params: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) getChainInfo(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return s.GetBlockchain().Info(), nil
+}
+
+func (s *Server) getBlockByHeight(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Height *int `json:"height"`
+	}
+	if err := parseParams(params, &p, "height"); err != nil {
+		return nil, err
+	}
+	if p.Height == nil {
+		return nil, errInvalidParams("Invalid block height")
+	}
+
+	block, ok := s.GetBlockchain().BlockView(*p.Height)
+	if !ok {
+		return nil, errNotFound("Block not found")
+	}
+	return block, nil
+}
+
+func (s *Server) getBlockByHash(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Hash string `json:"hash"`
+	}
+	if err := parseParams(params, &p, "hash"); err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(p.Hash)
+	if err != nil || len(decoded) != 32 {
+		return nil, errInvalidParams("Invalid block hash")
+	}
+
+	var hash [32]byte
+	copy(hash[:], decoded)
+	block, ok := s.GetBlockchain().BlockViewByHash(hash)
+	if !ok {
+		return nil, errNotFound("Block not found")
+	}
+	return block, nil
+}
+
+func (s *Server) getBlocks(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Cursor string `json:"cursor"`
+		Limit  int    `json:"limit"`
+		Order  string `json:"order"`
+	}
+	if err := parseParams(params, &p, "cursor", "limit", "order"); err != nil {
+		return nil, err
+	}
+	if p.Order != "" && p.Order != "asc" && p.Order != "desc" {
+		return nil, errInvalidParams("Invalid order: expected asc or desc")
+	}
+
+	page, err := s.GetBlockchain().Blocks(p.Cursor, p.Limit, p.Order == "asc")
+	if err != nil {
+		return nil, errInvalidParams("Invalid cursor")
+	}
+	return page, nil
+}
+
+func (s *Server) getTransaction(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		ID string `json:"id"`
+	}
+	if err := parseParams(params, &p, "id"); err != nil {
+		return nil, err
+	}
+	if decoded, err := hex.DecodeString(p.ID); err != nil || len(decoded) != 32 {
+		return nil, errInvalidParams("Invalid transaction id")
+	}
+
+	t, ok := s.GetBlockchain().Transaction(p.ID)
+	if !ok {
+		return nil, errNotFound("Transaction not found")
+	}
+	return t, nil
+}
+
+func (s *Server) getChainTip(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return s.GetBlockchain().Tip(), nil
+}
+
+func (s *Server) getBalance(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Address          string `json:"address"`
+		MinConfirmations *int   `json:"min_confirmations"`
+	}
+	if err := parseParams(params, &p, "address", "min_confirmations"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+	minConfirmations := blockchain.DEFAULT_MIN_CONFIRMATIONS
+	if p.MinConfirmations != nil {
+		if *p.MinConfirmations < 0 {
+			return nil, errInvalidParams("Invalid min_confirmations")
+		}
+		minConfirmations = *p.MinConfirmations
+	}
+
+	return s.GetBlockchain().Balance(p.Address, minConfirmations), nil
+}
+
+func (s *Server) getAddress(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := parseParams(params, &p, "address"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+
+	return s.GetBlockchain().AddressSummary(p.Address), nil
+}
+
+func (s *Server) getAddressHistory(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Address string `json:"address"`
+		Cursor  string `json:"cursor"`
+		Limit   int    `json:"limit"`
+	}
+	if err := parseParams(params, &p, "address", "cursor", "limit"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+
+	page, err := s.GetBlockchain().AddressHistory(p.Address, p.Cursor, p.Limit)
+	if err != nil {
+		return nil, errInvalidParams("Invalid cursor")
+	}
+	return page, nil
+}
+
+// parseTransactionRequest() checks a transaction request and decodes it into a transaction.
+func (s *Server) parseTransactionRequest(t *blockchain.TransactionRequest) (*blockchain.Transaction, *apiError) {
+	if !t.IsValid() {
+		return nil, errInvalidParams("Invalid transaction request: missing fields")
+	}
+	// only the node mining a block pays out the mining reward
+	if *t.SenderAddress == blockchain.MINING_SENDER {
+		return nil, errInvalidParams("Invalid sender address: %s is reserved for mining rewards", blockchain.MINING_SENDER)
+	}
+	if *t.Amount < 0 {
+		return nil, errInvalidParams("Invalid amount: must not be negative")
+	}
+	if t.GetFee() < 0 {
+		return nil, errInvalidParams("Invalid fee: must not be negative")
+	}
+	if t.GetLocktime() < 0 {
+		return nil, errInvalidParams("Invalid lock time: must not be negative")
+	}
+	if err := address.Validate(*t.RecipientAddress, s.network); err != nil {
+		return nil, errInvalidParams("Invalid recipient address: %v", err)
+	}
+
+	if t.Token != "" && !blockchain.ValidTokenSymbol(t.Token) {
+		return nil, errInvalidParams("Invalid token symbol: %s", t.Token)
+	}
+
+	if t.GetGasLimit() > vm.MAX_GAS {
+		return nil, errInvalidParams("Invalid gas limit: must not exceed %d", vm.MAX_GAS)
+	}
+	input, err := blockchain.DecodeInput(t.Input)
+	if err != nil {
+		return nil, errInvalidParams("Invalid input: %v", err)
+	}
+
+	tx, apiErr := s.authorizeTransactionRequest(t)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	tx.SetNonce(t.GetNonce())
+	tx.SetLocktime(t.GetLocktime())
+	tx.SetContract(t.Code, input, t.GetGasLimit())
+	tx.SetToken(t.Token, t.TokenAction, t.Mintable)
+	return tx, nil
+}
+
+// authorizeTransactionRequest() decodes the request into a transaction carrying its signature,
+// its multisig signatures or its scripts.
+func (s *Server) authorizeTransactionRequest(t *blockchain.TransactionRequest) (*blockchain.Transaction, *apiError) {
+	if t.Multisig != nil {
+		signatures, err := blockchain.DecodeSignatures(t.Signatures)
+		if err != nil {
+			return nil, errInvalidParams("Invalid signatures: %v", err)
+		}
+		return blockchain.NewMultisigTransaction(*t.SenderAddress, *t.RecipientAddress, *t.Amount, t.GetFee(), t.Multisig, signatures), nil
+	}
+	if t.LockScript != nil {
+		return blockchain.NewScriptTransaction(*t.SenderAddress, *t.RecipientAddress, *t.Amount, t.GetFee(), t.LockScript, t.UnlockScript), nil
+	}
+
+	scheme := keys.DefaultScheme
+	if t.SignatureScheme != nil {
+		var err error
+		if scheme, err = keys.ParseScheme(*t.SignatureScheme); err != nil {
+			return nil, errInvalidParams("Invalid signature scheme: %v", err)
+		}
+	}
+	publicKey, err := keys.PublicKeyFromString(scheme, *t.SenderPublicKey)
+	if err != nil {
+		return nil, errInvalidParams("Invalid sender public key: %v", err)
+	}
+	signature, err := hex.DecodeString(*t.Signature)
+	if err != nil {
+		return nil, errInvalidParams("Invalid signature: %v", err)
+	}
+	return blockchain.NewSignedTransaction(*t.SenderAddress, *t.RecipientAddress, *t.Amount, t.GetFee(), publicKey, signature), nil
+}
+
+// submitTransaction() adds the transaction of the request to the pool and returns its ID. Transactions
+// relayed by a peer are not relayed again.
+func (s *Server) submitTransaction(t *blockchain.TransactionRequest, relayed bool) (string, *apiError) {
+	tx, apiErr := s.parseTransactionRequest(t)
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	bc := s.GetBlockchain()
+	var ok bool
+	if relayed {
+		ok = bc.PoolTransaction(tx)
+	} else {
+		ok = bc.BroadcastTransaction(tx)
+	}
+	if !ok {
+		return "", errRejected("Transaction failed")
+	}
+	return tx.ID(), nil
+}
+
+func (s *Server) sendTransaction(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var t blockchain.TransactionRequest
+	if err := parseParams(params, &t); err != nil {
+		return nil, err
+	}
+	id, apiErr := s.submitTransaction(&t, false)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return &blockchain.SubmitResponse{Message: "Transaction successful", ID: id}, nil
+}
+
+func (s *Server) getMempool(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return &blockchain.TransactionsResponse{Transactions: s.GetBlockchain().PendingTransactions()}, nil
+}
+
+func (s *Server) getPeerInfo(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return &blockchain.PeersResponse{Peers: s.GetBlockchain().GetNeighbors()}, nil
+}
+
+func (s *Server) getMiningInfo(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return s.GetBlockchain().MiningInfo(), nil
+}
+
+func (s *Server) getContract(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := parseParams(params, &p, "address"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+
+	c, ok := s.GetBlockchain().Contract(p.Address)
+	if !ok {
+		return nil, errNotFound("Contract not found")
+	}
+	return c, nil
+}
+
+// callContract() runs a contract without changing its state, for contracts that answer queries.
+func (s *Server) callContract(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p blockchain.CallRequest
+	if err := parseParams(params, &p, "address", "caller", "input", "gas_limit"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+	if p.Caller != "" {
+		if err := address.Validate(p.Caller, s.network); err != nil {
+			return nil, errInvalidParams("Invalid caller address: %v", err)
+		}
+	}
+	input, err := blockchain.DecodeInput(p.Input)
+	if err != nil || len(input) > vm.MAX_ARGS {
+		return nil, errInvalidParams("Invalid input")
+	}
+
+	res, err := s.GetBlockchain().CallContract(p.Address, p.Caller, input, p.GasLimit)
+	if err == blockchain.ErrNoContract {
+		return nil, errNotFound("Contract not found")
+	}
+	call := &blockchain.CallResponse{Success: err == nil, GasUsed: res.GasUsed}
+	if err != nil {
+		call.Error = err.Error()
+	} else {
+		call.Return = hex.EncodeToString(res.Return)
+	}
+	return call, nil
+}
+
+func (s *Server) getReceipt(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		ID string `json:"id"`
+	}
+	if err := parseParams(params, &p, "id"); err != nil {
+		return nil, err
+	}
+
+	r, ok := s.GetBlockchain().Receipt(p.ID)
+	if !ok {
+		return nil, errNotFound("Receipt not found")
+	}
+	return r, nil
+}
+
+func (s *Server) getTokens(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	return &blockchain.TokensResponse{Tokens: s.GetBlockchain().Tokens()}, nil
+}
+
+func (s *Server) getToken(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := parseParams(params, &p, "symbol"); err != nil {
+		return nil, err
+	}
+	if !blockchain.ValidTokenSymbol(p.Symbol) {
+		return nil, errInvalidParams("Invalid token symbol: %s", p.Symbol)
+	}
+
+	tk, ok := s.GetBlockchain().Token(p.Symbol)
+	if !ok {
+		return nil, errNotFound("Token not found")
+	}
+	return tk, nil
+}
+
+func (s *Server) getTokenBalance(ctx context.Context, params json.RawMessage) (interface{}, *apiError) {
+	var p struct {
+		Address string `json:"address"`
+		Token   string `json:"token"`
+	}
+	if err := parseParams(params, &p, "address", "token"); err != nil {
+		return nil, err
+	}
+	if err := address.Validate(p.Address, s.network); err != nil {
+		return nil, errInvalidParams("Invalid blockchain address: %v", err)
+	}
+	if !blockchain.ValidTokenSymbol(p.Token) {
+		return nil, errInvalidParams("Invalid token symbol: %s", p.Token)
+	}
+
+	return s.GetBlockchain().TokenBalance(p.Address, p.Token), nil
+}
+
+// serveMethod() runs a registered method for a REST handler and writes its result with the status,
+// or its error as a JSON status message.
+func (s *Server) serveMethod(w http.ResponseWriter, r *http.Request, name string, params json.RawMessage, status int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	result, apiErr := s.methods[name](r.Context(), params)
+	if apiErr != nil {
+		w.WriteHeader(apiErr.status)
+		w.Write(utils.JsonStatus(apiErr.message))
+		return
+	}
+
+	m, err := json.Marshal(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(utils.JsonStatus("Error encoding response"))
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(m)
+}
+
+// namedParams() encodes REST query values as the named params of a method.
+func namedParams(params map[string]interface{}) json.RawMessage {
+	m, _ := json.Marshal(params)
+	return m
+}
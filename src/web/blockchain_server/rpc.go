@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// RPC_MAX_BODY_BYTES bounds the size of a JSON-RPC request or batch.
+const RPC_MAX_BODY_BYTES = 1 << 20
+
+// rpcRequest is a JSON-RPC 2.0 request; a request without an id is a notification.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func newRPCError(id json.RawMessage, code int, message string) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", Error: &rpcError{code, message}, ID: id}
+}
+
+// RPCHandler serves JSON-RPC 2.0 calls, single or batched, to the methods of the REST API.
+func (s *Server) RPCHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, RPC_MAX_BODY_BYTES))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	var res interface{}
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			res = newRPCError(nil, codeParseError, "Parse error")
+		} else if len(batch) == 0 {
+			res = newRPCError(nil, codeInvalidRequest, "Invalid Request")
+		} else {
+			responses := make([]*rpcResponse, 0, len(batch))
+			for _, raw := range batch {
+				if response := s.call(r, raw); response != nil {
+					responses = append(responses, response)
+				}
+			}
+			if len(responses) > 0 {
+				res = responses
+			}
+		}
+	} else if response := s.call(r, body); response != nil {
+		res = response
+	}
+
+	// a request made only of notifications gets no response
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	m, _ := json.Marshal(res)
+	w.Write(m)
+}
+
+// call() runs a single JSON-RPC request and returns its response, or nil for a notification.
+func (s *Server) call(r *http.Request, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return newRPCError(nil, codeParseError, "Parse error")
+		}
+		return newRPCError(nil, codeInvalidRequest, "Invalid Request")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return newRPCError(req.ID, codeInvalidRequest, "Invalid Request")
+	}
+
+	m, ok := s.methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return newRPCError(req.ID, codeMethodNotFound, "Method not found")
+	}
+
+	result, apiErr := m(r.Context(), req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if apiErr != nil {
+		return newRPCError(req.ID, apiErr.code, apiErr.message)
+	}
+	return &rpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
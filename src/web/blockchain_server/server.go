@@ -3,9 +3,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"html/template"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/Rha02/block-beard/src/address"
 	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/keys"
 	"github.com/Rha02/block-beard/src/utils"
 	"github.com/Rha02/block-beard/src/wallet"
 )
@@ -13,11 +19,34 @@ import (
 var cache = make(map[string]*blockchain.Blockchain)
 
 type Server struct {
-	port uint16
+	port          uint16
+	network       *address.Network
+	dataDir       string
+	txIndex       bool
+	engine        blockchain.ConsensusEngine
+	miningAddress string
+	methods       map[string]method
+	explorer      map[string]*template.Template
 }
 
-func NewServer(port uint16) *Server {
-	return &Server{port}
+// NewServer() returns a pointer to a server; the chain is kept in dataDir unless it is empty,
+// and transactions are indexed by ID if txIndex is set. Blocks are produced and validated by the
+// engine and rewarded to miningAddress, or to a new address if it is empty.
+func NewServer(
+	port uint16, network *address.Network, dataDir string, txIndex bool,
+	engine blockchain.ConsensusEngine, miningAddress string,
+) *Server {
+	s := &Server{
+		port:          port,
+		network:       network,
+		dataDir:       dataDir,
+		txIndex:       txIndex,
+		engine:        engine,
+		miningAddress: miningAddress,
+	}
+	s.methods = s.registerMethods()
+	s.explorer = parseExplorerTemplates()
+	return s
 }
 
 func (s *Server) Port() uint16 {
@@ -31,15 +60,55 @@ func (s *Server) Start() {
 	http.HandleFunc("/mine", s.MineHandler)
 	http.HandleFunc("/mine/start", s.StartMineHandler)
 	http.HandleFunc("/amount", s.AmountHandler)
+	http.HandleFunc("/address", s.AddressHandler)
+	http.HandleFunc("/address/transactions", s.AddressHistoryHandler)
 	http.HandleFunc("/consensus", s.ConsensusHandler)
+	http.HandleFunc("/info", s.InfoHandler)
+	http.HandleFunc("/block", s.BlockHandler)
+	http.HandleFunc("/blocks", s.BlocksHandler)
+	http.HandleFunc("/transaction", s.TransactionHandler)
+	http.HandleFunc("/tip", s.TipHandler)
+	http.HandleFunc("/peers", s.PeersHandler)
+	http.HandleFunc("/mining", s.MiningInfoHandler)
+	http.HandleFunc("/contract", s.ContractHandler)
+	http.HandleFunc("/contract/call", s.ContractCallHandler)
+	http.HandleFunc("/receipt", s.ReceiptHandler)
+	http.HandleFunc("/tokens", s.TokensHandler)
+	http.HandleFunc("/token", s.TokenHandler)
+	http.HandleFunc("/token/balance", s.TokenBalanceHandler)
+	http.HandleFunc("/rpc", s.RPCHandler)
+	http.HandleFunc("/ws", s.WebSocketHandler)
+	http.HandleFunc("/explorer", s.ExplorerHandler)
+	http.HandleFunc("/explorer/", s.ExplorerHandler)
 	http.ListenAndServe(fmt.Sprintf(":%d", s.port), nil)
 }
 
 func (s *Server) GetBlockchain() *blockchain.Blockchain {
 	bc, ok := cache["blockchain"]
 	if !ok {
-		minerWallet := wallet.NewWallet()
-		bc = blockchain.NewBlockchain(minerWallet.GetAddress(), s.Port())
+		miningAddress := s.miningAddress
+		if miningAddress == "" {
+			miningAddress = wallet.NewWallet(s.network, keys.DefaultScheme).GetAddress()
+		}
+		bc = blockchain.NewBlockchain(miningAddress, s.Port(), s.network)
+		bc.SetConsensus(s.engine)
+		bc.SetPeerDialer(func(neighbor string) blockchain.PeerClient {
+			return client.New("http://" + neighbor)
+		})
+		if s.dataDir != "" {
+			store, err := blockchain.NewChainStore(s.dataDir)
+			if err != nil {
+				panic(err)
+			}
+			if err := bc.UseStore(store); err != nil {
+				panic(err)
+			}
+		}
+		if s.txIndex {
+			if err := bc.EnableTxIndex(); err != nil {
+				panic(err)
+			}
+		}
 		cache["blockchain"] = bc
 	}
 	return bc
@@ -59,35 +128,17 @@ func (s *Server) GetChainHandler(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) TransactionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
-		decoder := json.NewDecoder(r.Body)
-		var t blockchain.TransactionRequest
-		err := decoder.Decode(&t)
+		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			fmt.Println("Error decoding transaction request:", err)
-			return
-		}
-		if !t.IsValid() {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Println("Invalid transaction request: missing fields")
-			return
-		}
-		publicKey := utils.PublicKeyFromString(*t.SenderPublicKey)
-		signature := utils.SignatureFromString(*t.Signature)
-		bc := s.GetBlockchain()
-
-		w.Header().Set("Content-Type", "application/json")
-
-		if !bc.CreateTransaction(*t.SenderAddress, *t.RecipientAddress, *t.Amount, publicKey, signature) {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write(utils.JsonStatus("Transaction failed"))
+			fmt.Println("Error reading transaction request:", err)
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write(utils.JsonStatus("Transaction successful"))
+		s.serveMethod(w, r, "sendTransaction", body, http.StatusCreated)
 		return
 	}
 
+	// peers relay transactions with PUT, which adds them to the pool without relaying them again
 	if r.Method == http.MethodPut {
 		decoder := json.NewDecoder(r.Body)
 		var t blockchain.TransactionRequest
@@ -97,20 +148,11 @@ func (s *Server) TransactionsHandler(w http.ResponseWriter, r *http.Request) {
 			fmt.Println("Error decoding transaction request:", err)
 			return
 		}
-		if !t.IsValid() {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Println("Invalid transaction request: missing fields")
-			return
-		}
-		publicKey := utils.PublicKeyFromString(*t.SenderPublicKey)
-		signature := utils.SignatureFromString(*t.Signature)
-		bc := s.GetBlockchain()
-
 		w.Header().Set("Content-Type", "application/json")
 
-		if !bc.AddTransaction(*t.SenderAddress, *t.RecipientAddress, *t.Amount, publicKey, signature) {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write(utils.JsonStatus("Transaction failed"))
+		if _, apiErr := s.submitTransaction(&t, true); apiErr != nil {
+			w.WriteHeader(apiErr.status)
+			w.Write(utils.JsonStatus(apiErr.message))
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -127,15 +169,7 @@ func (s *Server) TransactionsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == http.MethodGet {
-		w.Header().Set("Content-Type", "application/json")
-		bc := s.GetBlockchain()
-		transactions := bc.GetTransactions()
-		m, _ := json.Marshal(struct {
-			Transactions []*blockchain.Transaction
-		}{
-			Transactions: transactions,
-		})
-		w.Write(m)
+		s.serveMethod(w, r, "getMempool", nil, http.StatusOK)
 		return
 	}
 
@@ -174,23 +208,27 @@ func (s *Server) StartMineHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(utils.JsonStatus("Mining successful"))
 }
 
+// AmountHandler returns the balance of the blockchain_address query parameter, counting funds
+// as confirmed from the min_confirmations query parameter, 1 by default.
 func (s *Server) AmountHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-
-	bcAddress := r.URL.Query().Get("blockchain_address")
-
-	bc := s.GetBlockchain()
-	amount := bc.GetBalance(bcAddress)
-
-	ar := &blockchain.AmountResponse{Amount: amount}
-	m, _ := ar.MarshalJSON()
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(m)
+	query := r.URL.Query()
+	params := map[string]interface{}{"address": query.Get("blockchain_address")}
+	if minConfirmations := query.Get("min_confirmations"); minConfirmations != "" {
+		n, err := strconv.Atoi(minConfirmations)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid min_confirmations"))
+			return
+		}
+		params["min_confirmations"] = n
+	}
+	s.serveMethod(w, r, "getBalance", namedParams(params), http.StatusOK)
 }
 
 func (s *Server) ConsensusHandler(w http.ResponseWriter, r *http.Request) {
@@ -206,3 +244,193 @@ func (s *Server) ConsensusHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(utils.JsonStatus("Consensus resolved"))
 }
+
+func (s *Server) InfoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getChainInfo", nil, http.StatusOK)
+}
+
+// BlockHandler returns the block with the hash query parameter, or else at the height query parameter.
+func (s *Server) BlockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if hash := query.Get("hash"); hash != "" {
+		s.serveMethod(w, r, "getBlockByHash", namedParams(map[string]interface{}{"hash": hash}), http.StatusOK)
+		return
+	}
+
+	height, err := strconv.Atoi(query.Get("height"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid block height"))
+		return
+	}
+	s.serveMethod(w, r, "getBlockByHeight", namedParams(map[string]interface{}{"height": height}), http.StatusOK)
+}
+
+// BlocksHandler returns a page of blocks, newest first unless the order query parameter is asc.
+// The next_cursor of a page is passed back as the cursor query parameter to get the following one.
+func (s *Server) BlocksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	params := map[string]interface{}{
+		"cursor": query.Get("cursor"),
+		"order":  query.Get("order"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid limit"))
+			return
+		}
+		params["limit"] = n
+	}
+	s.serveMethod(w, r, "getBlocks", namedParams(params), http.StatusOK)
+}
+
+// AddressHandler returns the balance and the received and sent totals of the address query parameter.
+func (s *Server) AddressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getAddress", namedParams(map[string]interface{}{"address": r.URL.Query().Get("address")}), http.StatusOK)
+}
+
+// AddressHistoryHandler returns a page of the confirmed transactions of the address query parameter,
+// newest first. The next_cursor of a page is passed back as the cursor query parameter to get the following one.
+func (s *Server) AddressHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	params := map[string]interface{}{
+		"address": query.Get("address"),
+		"cursor":  query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid limit"))
+			return
+		}
+		params["limit"] = n
+	}
+	s.serveMethod(w, r, "getAddressHistory", namedParams(params), http.StatusOK)
+}
+
+// TransactionHandler returns the pending or confirmed transaction with the id query parameter.
+func (s *Server) TransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getTransaction", namedParams(map[string]interface{}{"id": r.URL.Query().Get("id")}), http.StatusOK)
+}
+
+func (s *Server) TipHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getChainTip", nil, http.StatusOK)
+}
+
+func (s *Server) PeersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getPeerInfo", nil, http.StatusOK)
+}
+
+func (s *Server) MiningInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getMiningInfo", nil, http.StatusOK)
+}
+
+// ContractHandler returns the code and storage of the contract at the address query parameter.
+func (s *Server) ContractHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getContract", namedParams(map[string]interface{}{"address": r.URL.Query().Get("address")}), http.StatusOK)
+}
+
+// ContractCallHandler runs the contract of the posted call request without changing its state.
+func (s *Server) ContractCallHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Println("Error reading call request:", err)
+		return
+	}
+	s.serveMethod(w, r, "callContract", body, http.StatusOK)
+}
+
+// ReceiptHandler returns the outcome of the confirmed contract transaction with the id query parameter.
+func (s *Server) ReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getReceipt", namedParams(map[string]interface{}{"id": r.URL.Query().Get("id")}), http.StatusOK)
+}
+
+// TokensHandler returns the issued tokens.
+func (s *Server) TokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getTokens", nil, http.StatusOK)
+}
+
+// TokenHandler returns the issuer and supply of the token with the symbol query parameter.
+func (s *Server) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveMethod(w, r, "getToken", namedParams(map[string]interface{}{"symbol": r.URL.Query().Get("symbol")}), http.StatusOK)
+}
+
+// TokenBalanceHandler returns the balance of the address query parameter in the token query parameter.
+func (s *Server) TokenBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	params := map[string]interface{}{
+		"address": query.Get("address"),
+		"token":   query.Get("token"),
+	}
+	s.serveMethod(w, r, "getTokenBalance", namedParams(params), http.StatusOK)
+}
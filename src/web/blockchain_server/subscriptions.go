@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/websocket"
+)
+
+// Topics a WebSocket client can subscribe to.
+const (
+	topicNewHeads            = "newHeads"
+	topicPendingTransactions = "pendingTransactions"
+	topicReorgs              = "reorgs"
+	topicAddressActivity     = "addressActivity"
+)
+
+const (
+	WS_MAX_SUBSCRIPTIONS = 32
+	WS_PING_INTERVAL_SEC = 30
+	WS_WRITE_TIMEOUT_SEC = 10
+	// WS_READ_TIMEOUT_SEC is how long a client may stay silent; pings make it answer twice in that time.
+	WS_READ_TIMEOUT_SEC = 2 * WS_PING_INTERVAL_SEC
+	// WS_MAX_MESSAGE_SIZE bounds the requests of a client, which only subscribe and unsubscribe.
+	WS_MAX_MESSAGE_SIZE = 4 << 10
+)
+
+// subscription is a topic a client subscribed to; address is set for address activity.
+type subscription struct {
+	topic   string
+	address string
+}
+
+// wsSession is the state of a WebSocket client: its subscriptions and its queue of replies.
+type wsSession struct {
+	conn    *websocket.Conn
+	mux     sync.Mutex
+	subs    map[string]subscription
+	nextID  int
+	replies chan []byte
+}
+
+// pendingTransaction is the payload of pendingTransactions notifications.
+type pendingTransaction struct {
+	Action      string                  `json:"action"`
+	Transaction *blockchain.Transaction `json:"transaction"`
+}
+
+// addressActivity is the payload of addressActivity notifications.
+type addressActivity struct {
+	Address     string                  `json:"address"`
+	Status      string                  `json:"status"`
+	Height      *int                    `json:"height,omitempty"`
+	Transaction *blockchain.Transaction `json:"transaction"`
+}
+
+// WebSocketHandler lets clients subscribe to new blocks, pool changes, reorgs and address activity.
+// Clients must answer pings and may only connect from pages served by the node.
+// Requests and replies follow JSON-RPC 2.0: "subscribe" takes a topic, and an address for
+// addressActivity, and returns a subscription id that "unsubscribe" takes back.
+// Events are pushed as "subscription" notifications.
+func (s *Server) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		fmt.Println("Error upgrading to WebSocket:", err)
+		return
+	}
+	defer conn.Close()
+	conn.MaxMessageSize = WS_MAX_MESSAGE_SIZE
+	conn.ReadTimeout = WS_READ_TIMEOUT_SEC * time.Second
+
+	session := &wsSession{
+		conn:    conn,
+		subs:    make(map[string]subscription),
+		replies: make(chan []byte, WS_MAX_SUBSCRIPTIONS),
+	}
+	events := s.GetBlockchain().Events().Subscribe()
+	defer events.Unsubscribe()
+
+	done := make(chan struct{})
+	defer close(done)
+	go session.writeLoop(events, done)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		session.reply(s.handleSessionRequest(session, message))
+	}
+}
+
+// reply() queues a reply for the writer; a client that floods requests loses the replies that do not fit.
+func (ws *wsSession) reply(res *rpcResponse) {
+	m, _ := json.Marshal(res)
+	select {
+	case ws.replies <- m:
+	default:
+	}
+}
+
+func (s *Server) handleSessionRequest(ws *wsSession, message []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		return newRPCError(nil, codeParseError, "Parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return newRPCError(req.ID, codeInvalidRequest, "Invalid Request")
+	}
+
+	var params []string
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+			return newRPCError(req.ID, codeInvalidParams, "Invalid params: expected an array of strings")
+		}
+	}
+
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+
+	switch req.Method {
+	case "subscribe":
+		if len(params) == 0 {
+			return newRPCError(req.ID, codeInvalidParams, "Invalid params: missing topic")
+		}
+		sub := subscription{topic: params[0]}
+		switch sub.topic {
+		case topicNewHeads, topicPendingTransactions, topicReorgs:
+		case topicAddressActivity:
+			if len(params) < 2 {
+				return newRPCError(req.ID, codeInvalidParams, "Invalid params: missing address")
+			}
+			if err := address.Validate(params[1], s.network); err != nil {
+				return newRPCError(req.ID, codeInvalidParams, "Invalid address: "+err.Error())
+			}
+			sub.address = params[1]
+		default:
+			return newRPCError(req.ID, codeInvalidParams, "Unknown topic "+sub.topic)
+		}
+		if len(ws.subs) >= WS_MAX_SUBSCRIPTIONS {
+			return newRPCError(req.ID, codeRejected, "Too many subscriptions")
+		}
+
+		ws.nextID++
+		id := fmt.Sprintf("0x%x", ws.nextID)
+		ws.subs[id] = sub
+		return &rpcResponse{JSONRPC: "2.0", Result: id, ID: req.ID}
+
+	case "unsubscribe":
+		if len(params) == 0 {
+			return newRPCError(req.ID, codeInvalidParams, "Invalid params: missing subscription id")
+		}
+		_, ok := ws.subs[params[0]]
+		delete(ws.subs, params[0])
+		return &rpcResponse{JSONRPC: "2.0", Result: ok, ID: req.ID}
+	}
+	return newRPCError(req.ID, codeMethodNotFound, "Method not found")
+}
+
+// wsNotification is an event payload to deliver for a subscription.
+type wsNotification struct {
+	id      string
+	topic   string
+	payload interface{}
+}
+
+// notifications() returns the payloads of the event for every matching subscription.
+func (ws *wsSession) notifications(e blockchain.Event) []wsNotification {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+
+	var res []wsNotification
+	for id, sub := range ws.subs {
+		switch {
+		case sub.topic == topicNewHeads && e.Kind == blockchain.EventNewBlock:
+			res = append(res, wsNotification{id, sub.topic, blockchain.NewBlockView(e.Block, e.Height, e.Height, true)})
+		case sub.topic == topicPendingTransactions && e.Kind == blockchain.EventTransactionAdded:
+			res = append(res, wsNotification{id, sub.topic, &pendingTransaction{"added", e.Transaction}})
+		case sub.topic == topicPendingTransactions && e.Kind == blockchain.EventTransactionRemoved:
+			res = append(res, wsNotification{id, sub.topic, &pendingTransaction{"removed", e.Transaction}})
+		case sub.topic == topicReorgs && e.Kind == blockchain.EventReorg:
+			res = append(res, wsNotification{id, sub.topic, e.Reorg})
+		case sub.topic == topicAddressActivity && e.Kind == blockchain.EventTransactionAdded:
+			if involves(e.Transaction, sub.address) {
+				res = append(res, wsNotification{id, sub.topic, &addressActivity{sub.address, "pending", nil, e.Transaction}})
+			}
+		case sub.topic == topicAddressActivity && e.Kind == blockchain.EventNewBlock:
+			for _, t := range e.Block.GetTransactions() {
+				if involves(t, sub.address) {
+					height := e.Height
+					res = append(res, wsNotification{id, sub.topic, &addressActivity{sub.address, "confirmed", &height, t}})
+				}
+			}
+		}
+	}
+	return res
+}
+
+func involves(t *blockchain.Transaction, bcAddress string) bool {
+	return t.GetSenderAddress() == bcAddress || t.GetRecipientAddress() == bcAddress
+}
+
+// writeLoop() sends replies, notifications and pings until the connection is done.
+// If the client falls so far behind that the event bus dropped it, the connection is closed.
+func (ws *wsSession) writeLoop(events *blockchain.Subscription, done <-chan struct{}) {
+	ping := time.NewTicker(WS_PING_INTERVAL_SEC * time.Second)
+	defer ping.Stop()
+
+	write := func(m []byte) bool {
+		ws.conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT_SEC * time.Second))
+		if err := ws.conn.WriteText(m); err != nil {
+			ws.conn.Close()
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case m := <-ws.replies:
+			if !write(m) {
+				return
+			}
+		case <-ping.C:
+			ws.conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT_SEC * time.Second))
+			if err := ws.conn.Ping(); err != nil {
+				ws.conn.Close()
+				return
+			}
+		case e, ok := <-events.Events():
+			if !ok {
+				if events.Overflowed() {
+					ws.conn.WriteClose(websocket.CloseTryAgainLater, "client fell behind")
+				}
+				ws.conn.Close()
+				return
+			}
+			for _, n := range ws.notifications(e) {
+				if !write(notification(n)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func notification(n wsNotification) []byte {
+	m, _ := json.Marshal(struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{
+		JSONRPC: "2.0",
+		Method:  "subscription",
+		Params: struct {
+			Subscription string      `json:"subscription"`
+			Topic        string      `json:"topic"`
+			Result       interface{} `json:"result"`
+		}{n.id, n.topic, n.payload},
+	})
+	return m
+}
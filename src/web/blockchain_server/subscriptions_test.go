@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/keys"
+)
+
+func testAddress(t *testing.T) string {
+	t.Helper()
+	k, err := keys.GenerateKey(keys.P256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address.FromPublicKey(k.Public(), address.TestNet)
+}
+
+func newTestSession() *wsSession {
+	return &wsSession{subs: make(map[string]subscription), replies: make(chan []byte, WS_MAX_SUBSCRIPTIONS)}
+}
+
+// request() sends a JSON-RPC request with the params to the session.
+func request(s *Server, ws *wsSession, method string, params ...string) *rpcResponse {
+	p, _ := json.Marshal(params)
+	m, _ := json.Marshal(&rpcRequest{JSONRPC: "2.0", Method: method, Params: p, ID: json.RawMessage("1")})
+	return s.handleSessionRequest(ws, m)
+}
+
+func TestSubscribe(t *testing.T) {
+	s := &Server{network: address.TestNet}
+	ws := newTestSession()
+	watched := testAddress(t)
+
+	for _, tt := range []struct {
+		name   string
+		params []string
+		code   int
+	}{
+		{"new heads", []string{topicNewHeads}, 0},
+		{"pending transactions", []string{topicPendingTransactions}, 0},
+		{"reorgs", []string{topicReorgs}, 0},
+		{"the activity of an address", []string{topicAddressActivity, watched}, 0},
+		{"no topic", nil, codeInvalidParams},
+		{"an unknown topic", []string{"blocks"}, codeInvalidParams},
+		{"activity without an address", []string{topicAddressActivity}, codeInvalidParams},
+		{"the activity of an invalid address", []string{topicAddressActivity, "not an address"}, codeInvalidParams},
+		{"the activity of an address of another network", []string{topicAddressActivity, address.Encode(address.MainNet.PubKeyHash, make([]byte, address.HashLength))}, codeInvalidParams},
+	} {
+		res := request(s, ws, "subscribe", tt.params...)
+		switch {
+		case tt.code == 0 && res.Error != nil:
+			t.Errorf("subscribe to %s: %s", tt.name, res.Error.Message)
+		case tt.code != 0 && (res.Error == nil || res.Error.Code != tt.code):
+			t.Errorf("subscribe to %s = %+v, want error %d", tt.name, res, tt.code)
+		}
+	}
+	if len(ws.subs) != 4 {
+		t.Fatalf("%d subscriptions, want 4", len(ws.subs))
+	}
+	if sub := ws.subs["0x4"]; sub.topic != topicAddressActivity || sub.address != watched {
+		t.Errorf("subscription 0x4 = %+v, want the activity of %s", sub, watched)
+	}
+
+	if res := request(s, ws, "unsubscribe", "0x1"); res.Result != true {
+		t.Errorf("unsubscribe of a subscription = %v, want true", res.Result)
+	}
+	if res := request(s, ws, "unsubscribe", "0x1"); res.Result != false {
+		t.Errorf("second unsubscribe of a subscription = %v, want false", res.Result)
+	}
+	if res := request(s, ws, "unsubscribe"); res.Error == nil || res.Error.Code != codeInvalidParams {
+		t.Errorf("unsubscribe without an id = %+v, want error %d", res, codeInvalidParams)
+	}
+	if _, ok := ws.subs["0x1"]; ok || len(ws.subs) != 3 {
+		t.Errorf("subscriptions after unsubscribing 0x1 = %v", ws.subs)
+	}
+
+	// ids are not reused once a subscription ends
+	if res := request(s, ws, "subscribe", topicNewHeads); res.Result != "0x5" {
+		t.Errorf("id of a new subscription = %v, want 0x5", res.Result)
+	}
+	for len(ws.subs) < WS_MAX_SUBSCRIPTIONS {
+		request(s, ws, "subscribe", topicReorgs)
+	}
+	if res := request(s, ws, "subscribe", topicReorgs); res.Error == nil || res.Error.Code != codeRejected {
+		t.Errorf("subscription over the limit = %+v, want error %d", res, codeRejected)
+	}
+
+	for name, m := range map[string]string{
+		"a message that is not json":   "subscribe",
+		"a request of another version": `{"jsonrpc":"1.0","method":"subscribe","params":["newHeads"],"id":1}`,
+		"params that are not strings":  `{"jsonrpc":"2.0","method":"subscribe","params":[1],"id":1}`,
+		"an unknown method":            `{"jsonrpc":"2.0","method":"eth_subscribe","params":["newHeads"],"id":1}`,
+	} {
+		if res := s.handleSessionRequest(ws, []byte(m)); res.Error == nil {
+			t.Errorf("request of %s = %+v, want an error", name, res)
+		}
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := &Server{network: address.TestNet}
+	ws := newTestSession()
+	watched, other := testAddress(t), testAddress(t)
+	for _, params := range [][]string{{topicNewHeads}, {topicPendingTransactions}, {topicAddressActivity, watched}} {
+		if res := request(s, ws, "subscribe", params...); res.Error != nil {
+			t.Fatal(res.Error.Message)
+		}
+	}
+
+	incoming := blockchain.NewTransaction(other, watched, 1)
+	unrelated := blockchain.NewTransaction(other, other, 1)
+	block := blockchain.NewBlock(0, [32]byte{}, []*blockchain.Transaction{unrelated, incoming})
+	// topics() returns the subscriptions notified of the event, in order
+	topics := func(e blockchain.Event) []string {
+		var res []string
+		for _, n := range ws.notifications(e) {
+			res = append(res, n.id+" "+n.topic)
+		}
+		sort.Strings(res)
+		return res
+	}
+	for _, tt := range []struct {
+		name  string
+		event blockchain.Event
+		want  []string
+	}{
+		{"a new block", blockchain.Event{Kind: blockchain.EventNewBlock, Block: block, Height: 1}, []string{"0x1 newHeads", "0x3 addressActivity"}},
+		{"a pooled payment to the address", blockchain.Event{Kind: blockchain.EventTransactionAdded, Transaction: incoming}, []string{"0x2 pendingTransactions", "0x3 addressActivity"}},
+		{"an unrelated pooled payment", blockchain.Event{Kind: blockchain.EventTransactionAdded, Transaction: unrelated}, []string{"0x2 pendingTransactions"}},
+		{"a payment leaving the pool", blockchain.Event{Kind: blockchain.EventTransactionRemoved, Transaction: incoming}, []string{"0x2 pendingTransactions"}},
+		{"a reorg", blockchain.Event{Kind: blockchain.EventReorg}, nil},
+	} {
+		if got := topics(tt.event); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("notifications of %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	request(s, ws, "unsubscribe", "0x3")
+	if got := topics(blockchain.Event{Kind: blockchain.EventTransactionAdded, Transaction: incoming}); fmt.Sprint(got) != "[0x2 pendingTransactions]" {
+		t.Errorf("notifications after unsubscribing from address activity = %v", got)
+	}
+}
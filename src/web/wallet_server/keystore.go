@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/keystore"
+	"github.com/Rha02/block-beard/src/utils"
+)
+
+// keyResponse is the public view of a key file, without its encrypted key material.
+type keyResponse struct {
+	ID        string      `json:"id"`
+	Address   string      `json:"address"`
+	Scheme    keys.Scheme `json:"signature_scheme"`
+	PublicKey string      `json:"public_key"`
+	CreatedAt time.Time   `json:"created_at"`
+	Unlocked  bool        `json:"unlocked"`
+}
+
+func (s *Server) newKeyResponse(kf *keystore.KeyFile) *keyResponse {
+	return &keyResponse{
+		ID:        kf.ID,
+		Address:   kf.Address,
+		Scheme:    kf.Scheme,
+		PublicKey: kf.PublicKey,
+		CreatedAt: kf.CreatedAt,
+		Unlocked:  s.keystore.IsUnlocked(kf.ID),
+	}
+}
+
+// keystoreErrorStatus() maps keystore errors to HTTP status codes.
+func keystoreErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, keystore.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, keystore.ErrDecrypt), errors.Is(err, keystore.ErrLocked):
+		return http.StatusForbidden
+	case errors.Is(err, keystore.ErrExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet {
+		kfs, err := s.keystore.List()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(utils.JsonStatus("Error listing keys"))
+			return
+		}
+		res := make([]*keyResponse, 0, len(kfs))
+		for _, kf := range kfs {
+			res = append(res, s.newKeyResponse(kf))
+		}
+		m, _ := json.Marshal(struct {
+			Keys []*keyResponse `json:"keys"`
+		}{
+			Keys: res,
+		})
+		w.Write(m)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req struct {
+			Passphrase *string `json:"passphrase"`
+			Scheme     string  `json:"signature_scheme"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Passphrase == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid request: missing passphrase"))
+			return
+		}
+		scheme, err := keys.ParseScheme(req.Scheme)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus(err.Error()))
+			return
+		}
+
+		kf, err := s.keystore.Create(scheme, *req.Passphrase)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(utils.JsonStatus("Error creating key"))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		m, _ := json.Marshal(s.newKeyResponse(kf))
+		w.Write(m)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+func (s *Server) ImportKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+
+	var req struct {
+		KeyFile    json.RawMessage `json:"key_file"`
+		PrivateKey *string         `json:"private_key"`
+		Scheme     string          `json:"signature_scheme"`
+		Passphrase *string         `json:"passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid import request"))
+		return
+	}
+
+	var kf *keystore.KeyFile
+	var err error
+	switch {
+	case len(req.KeyFile) > 0:
+		kf, err = s.keystore.ImportFile(req.KeyFile)
+	case req.PrivateKey != nil && req.Passphrase != nil:
+		var scheme keys.Scheme
+		var signer keys.Signer
+		if scheme, err = keys.ParseScheme(req.Scheme); err == nil {
+			if signer, err = keys.PrivateKeyFromString(scheme, *req.PrivateKey); err == nil {
+				kf, err = s.keystore.Import(signer, *req.Passphrase)
+			}
+		}
+	default:
+		err = errors.New("either key_file or private_key and passphrase are required")
+	}
+	if err != nil {
+		w.WriteHeader(keystoreErrorStatus(err))
+		w.Write(utils.JsonStatus("Error importing key: " + err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	m, _ := json.Marshal(s.newKeyResponse(kf))
+	w.Write(m)
+}
+
+func (s *Server) ExportKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+
+	data, err := s.keystore.Export(r.URL.Query().Get("id"))
+	if err != nil {
+		w.WriteHeader(keystoreErrorStatus(err))
+		w.Write(utils.JsonStatus(err.Error()))
+		return
+	}
+	w.Write(data)
+}
+
+func (s *Server) UnlockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+
+	var req struct {
+		ID         *string `json:"id"`
+		Passphrase *string `json:"passphrase"`
+		TimeoutSec int     `json:"timeout_sec"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == nil || req.Passphrase == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid request: missing id or passphrase"))
+		return
+	}
+
+	timeout := time.Duration(req.TimeoutSec) * time.Second
+	if err := s.keystore.Unlock(*req.ID, *req.Passphrase, timeout); err != nil {
+		w.WriteHeader(keystoreErrorStatus(err))
+		w.Write(utils.JsonStatus(err.Error()))
+		return
+	}
+	w.Write(utils.JsonStatus("Key unlocked"))
+}
+
+func (s *Server) LockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+
+	var req struct {
+		ID *string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid request: missing id"))
+		return
+	}
+
+	s.keystore.Lock(*req.ID)
+	w.Write(utils.JsonStatus("Key locked"))
+}
+
+func (s *Server) ChangePassphraseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+
+	var req struct {
+		ID            *string `json:"id"`
+		OldPassphrase *string `json:"old_passphrase"`
+		NewPassphrase *string `json:"new_passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == nil || req.OldPassphrase == nil || req.NewPassphrase == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid request: missing fields"))
+		return
+	}
+
+	if err := s.keystore.ChangePassphrase(*req.ID, *req.OldPassphrase, *req.NewPassphrase); err != nil {
+		w.WriteHeader(keystoreErrorStatus(err))
+		w.Write(utils.JsonStatus(err.Error()))
+		return
+	}
+	w.Write(utils.JsonStatus("Passphrase changed"))
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"log"
-	"os"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/keystore"
 )
 
 func init() {
@@ -13,10 +15,21 @@ func init() {
 func main() {
 	port := flag.Uint("port", 8080, "port to listen on")
 	gateway := flag.String("gateway", "http://localhost:3000", "address of the blockchain server")
+	networkName := flag.String("network", address.MainNet.Name, "network whose address prefixes to use")
+	keystoreDir := flag.String("keystore", "keystore", "directory holding encrypted key files")
+	allowRawKeys := flag.Bool("allow-raw-keys", false, "accept raw private keys in POST /transaction")
 	flag.Parse()
 
-	println(os.Getwd())
+	network, err := address.ParseNetwork(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ks, err := keystore.New(*keystoreDir, network)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	server := NewServer(uint16(*port), *gateway)
+	server := NewServer(uint16(*port), *gateway, network, ks, *allowRawKeys)
 	server.Start()
 }
@@ -1,28 +1,36 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"html/template"
+	"math"
 	"net/http"
-	"path"
 	"strconv"
-	"text/template"
 
+	"github.com/Rha02/block-beard/src/address"
 	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/client"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/keystore"
 	"github.com/Rha02/block-beard/src/utils"
 	"github.com/Rha02/block-beard/src/wallet"
 )
 
-const tempDir = "./wallet_server/templates"
-
 type Server struct {
-	port    uint16
-	gateway string
+	port         uint16
+	gateway      string
+	network      *address.Network
+	keystore     *keystore.Keystore
+	allowRawKeys bool
+	node         *client.Client
+	index        *template.Template
 }
 
-func NewServer(port uint16, gateway string) *Server {
-	return &Server{port, gateway}
+func NewServer(
+	port uint16, gateway string, network *address.Network, ks *keystore.Keystore, allowRawKeys bool,
+) *Server {
+	return &Server{port, gateway, network, ks, allowRawKeys, client.New(gateway), parseIndexTemplate()}
 }
 
 func (s *Server) Port() uint16 {
@@ -33,34 +41,63 @@ func (s *Server) Gateway() string {
 	return s.gateway
 }
 
-func (s *Server) Index(rw http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		rw.WriteHeader(http.StatusMethodNotAllowed)
-		return
+// broadcast() forwards a signed transaction to the blockchain server and reports the outcome to the client.
+func (s *Server) broadcast(rw http.ResponseWriter, r *http.Request, st *wallet.SignedTransaction) {
+	scheme := string(st.SignatureScheme)
+	tr := blockchain.TransactionRequest{
+		SenderPublicKey:  &st.SenderPublicKey,
+		SenderAddress:    &st.SenderAddress,
+		RecipientAddress: &st.RecipientAddress,
+		Amount:           &st.Amount,
+		Fee:              &st.Fee,
+		Nonce:            &st.Nonce,
+		Signature:        &st.Signature,
+		SignatureScheme:  &scheme,
 	}
 
-	t := template.Must(template.ParseFiles(path.Join(tempDir, "index.html")))
-	t.Execute(rw, nil)
-}
+	id, err := s.node.SendTransaction(r.Context(), &tr)
+	if err == nil {
+		m, _ := json.Marshal(&blockchain.SubmitResponse{Message: "Transaction posted to blockchain", ID: id})
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write(m)
+		println("Transaction posted to blockchain: " + id)
+		return
+	}
 
-func (s *Server) Wallet(rw http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		rw.WriteHeader(http.StatusMethodNotAllowed)
+	println("Error posting transaction to blockchain: " + err.Error())
+	rw.WriteHeader(http.StatusInternalServerError)
+	if client.StatusCode(err) != 0 {
+		rw.Write(utils.JsonStatus("Blockchain rejected the transaction: " + err.Error()))
 		return
 	}
+	rw.Write(utils.JsonStatus("Error posting transaction to blockchain"))
+}
 
-	rw.Header().Add("Content-Type", "application/json")
-	myWallet := wallet.NewWallet()
-	walletJSON, _ := myWallet.MarshalJSON()
-	rw.Write(walletJSON)
+// parseAmount() converts a decimal amount string to a positive amount.
+func parseAmount(s string) (float32, error) {
+	amount, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("amount must be a finite number")
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount must be positive")
+	}
+	return float32(amount), nil
 }
 
+// PostTransactionHandler signs a transaction on the server with a keystore key or, when
+// the server was started with raw keys allowed, with a private key sent by the client.
 func (s *Server) PostTransactionHandler(rw http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		rw.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	rw.Header().Add("Content-Type", "application/json")
+
 	decoder := json.NewDecoder(r.Body)
 	var t wallet.TransactionRequest
 	err := decoder.Decode(&t)
@@ -78,49 +115,176 @@ func (s *Server) PostTransactionHandler(rw http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	publicKey := utils.PublicKeyFromString(*t.SenderPublicKey)
-	privateKey := utils.PrivateKeyFromString(*t.SenderPrivateKey, publicKey)
-	amount, err := strconv.ParseFloat(*t.Amount, 32)
+	if err := address.Validate(*t.RecipientAddress, s.network); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		println("Error: invalid recipient address")
+		return
+	}
+
+	var privateKey keys.Signer
+	if t.KeyID != nil {
+		if privateKey, err = s.keystore.Signer(*t.KeyID); err != nil {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+			println("Error: key is locked")
+			return
+		}
+	} else {
+		if !s.allowRawKeys {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write(utils.JsonStatus("Raw private keys are disabled: sign with a keystore key or use /transaction/build and /transaction/broadcast"))
+			return
+		}
+
+		scheme := keys.DefaultScheme
+		if t.SignatureScheme != nil {
+			if scheme, err = keys.ParseScheme(*t.SignatureScheme); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+				println("Error: invalid signature scheme")
+				return
+			}
+		}
+		privateKey, err = keys.PrivateKeyFromString(scheme, *t.SenderPrivateKey)
+		if err != nil || keys.ToString(privateKey.Public()) != *t.SenderPublicKey {
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write(utils.JsonStatus("Invalid transaction: invalid private key"))
+			println("Error: invalid private key")
+			return
+		}
+	}
+
+	amount, err := parseAmount(*t.Amount)
 	if err != nil {
 		rw.WriteHeader(http.StatusBadRequest)
 		rw.Write(utils.JsonStatus("Invalid transaction: invalid amount"))
 		println("Error: invalid amount")
 		return
 	}
-	amount32 := float32(amount)
+	var fee float32
+	if t.Fee != nil {
+		if fee, err = wallet.ParseFee(*t.Fee); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+			println("Error: invalid fee")
+			return
+		}
+	}
 
-	rw.Header().Add("Content-Type", "application/json")
+	nonce, err := s.node.Balance(r.Context(), address.FromPublicKey(privateKey.Public(), s.network), 0)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write(utils.JsonStatus("Error getting the nonce of the sender from blockchain"))
+		return
+	}
+
+	ut := wallet.NewUnsignedTransaction(privateKey.Public(), s.network, *t.RecipientAddress, amount, fee, nonce.Nonce)
+	st, err := ut.Sign(privateKey)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write(utils.JsonStatus("Error signing transaction: " + err.Error()))
+		return
+	}
 
-	transaction := wallet.NewTransaction(privateKey, publicKey, *t.SenderAddress, *t.RecipientAddress, amount32)
-	signature := transaction.GenerateSignature()
-	signatureStr := signature.ToString()
+	s.broadcast(rw, r, st)
+}
 
-	tr := blockchain.TransactionRequest{
-		SenderPublicKey:  t.SenderPublicKey,
-		SenderAddress:    t.SenderAddress,
-		RecipientAddress: t.RecipientAddress,
-		Amount:           &amount32,
-		Signature:        &signatureStr,
+// BuildTransactionHandler returns an unsigned transaction and the payload the sender must sign.
+func (s *Server) BuildTransactionHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
-	m, _ := json.Marshal(tr)
-	buf := bytes.NewBuffer(m)
+	rw.Header().Add("Content-Type", "application/json")
 
-	res, err := http.Post(s.Gateway()+"/transactions", "application/json", buf)
+	var req struct {
+		SenderPublicKey  *string `json:"sender_public_key"`
+		SignatureScheme  string  `json:"signature_scheme"`
+		RecipientAddress *string `json:"recipient_address"`
+		Amount           *string `json:"amount"`
+		Fee              string  `json:"fee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SenderPublicKey == nil || req.RecipientAddress == nil || req.Amount == nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: missing fields"))
+		return
+	}
+
+	scheme, err := keys.ParseScheme(req.SignatureScheme)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+	publicKey, err := keys.PublicKeyFromString(scheme, *req.SenderPublicKey)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+	if err := address.Validate(*req.RecipientAddress, s.network); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+	amount, err := parseAmount(*req.Amount)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: invalid amount"))
+		return
+	}
+	fee, err := wallet.ParseFee(req.Fee)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+
+	nonce, err := s.node.Balance(r.Context(), address.FromPublicKey(publicKey, s.network), 0)
 	if err != nil {
 		rw.WriteHeader(http.StatusInternalServerError)
-		rw.Write(utils.JsonStatus("Error posting transaction to blockchain"))
-		println("Error posting transaction to blockchain")
+		rw.Write(utils.JsonStatus("Error getting the nonce of the sender from blockchain"))
 		return
 	}
-	if res.StatusCode == http.StatusCreated {
-		rw.WriteHeader(http.StatusCreated)
-		rw.Write(utils.JsonStatus("Transaction posted to blockchain"))
-		println("Transaction posted to blockchain")
+
+	m, _ := json.Marshal(wallet.NewUnsignedTransaction(publicKey, s.network, *req.RecipientAddress, amount, fee, nonce.Nonce))
+	rw.Write(m)
+}
+
+// BroadcastTransactionHandler accepts a transaction signed outside the server and forwards it to the blockchain.
+func (s *Server) BroadcastTransactionHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	println("Error posting transaction to blockchain: " + res.Status)
-	rw.WriteHeader(http.StatusInternalServerError)
+
+	rw.Header().Add("Content-Type", "application/json")
+
+	var st wallet.SignedTransaction
+	if err := json.NewDecoder(r.Body).Decode(&st); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction"))
+		return
+	}
+	if err := address.Validate(st.RecipientAddress, s.network); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+	if st.Fee < 0 {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: fee must not be negative"))
+		return
+	}
+	if err := st.Verify(s.network); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write(utils.JsonStatus("Invalid transaction: " + err.Error()))
+		return
+	}
+
+	s.broadcast(rw, r, &st)
 }
 
 func (s *Server) WalletAmountHandler(w http.ResponseWriter, r *http.Request) {
@@ -131,45 +295,77 @@ func (s *Server) WalletAmountHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Add("Content-Type", "application/json")
 	bcAddress := r.URL.Query().Get("blockchain_address")
-	endpoint := fmt.Sprintf("%s/amount?blockchain_address=%s", s.Gateway(), bcAddress)
-
-	res, err := http.Get(endpoint)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(utils.JsonStatus("Error getting amount from blockchain"))
+	if err := address.Validate(bcAddress, s.network); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid blockchain address: " + err.Error()))
 		return
 	}
 
-	if res.StatusCode == http.StatusOK {
-		decoder := json.NewDecoder(res.Body)
-		var amount blockchain.AmountResponse
-		err := decoder.Decode(&amount)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(utils.JsonStatus("Error decoding amount from blockchain"))
+	minConfirmations := blockchain.DEFAULT_MIN_CONFIRMATIONS
+	if v := r.URL.Query().Get("min_confirmations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid min_confirmations"))
 			return
 		}
+		minConfirmations = n
+	}
 
-		w.WriteHeader(http.StatusOK)
-		m, _ := json.Marshal(struct {
-			Message string  `json:"message"`
-			Amount  float32 `json:"amount"`
-		}{
-			Message: "Amount retrieved from blockchain",
-			Amount:  amount.Amount,
-		})
-		w.Write(m)
-
+	amount, err := s.node.Balance(r.Context(), bcAddress, minConfirmations)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(utils.JsonStatus("Error getting amount from blockchain"))
 		return
 	}
-	w.WriteHeader(http.StatusInternalServerError)
-	w.Write(utils.JsonStatus("Error getting amount from blockchain"))
+	summary, err := s.node.Address(r.Context(), bcAddress)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(utils.JsonStatus("Error getting amount from blockchain"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	m, _ := json.Marshal(struct {
+		Message             string  `json:"message"`
+		Amount              float32 `json:"amount"`
+		Confirmed           float32 `json:"confirmed"`
+		UnconfirmedIncoming float32 `json:"unconfirmed_incoming"`
+		UnconfirmedOutgoing float32 `json:"unconfirmed_outgoing"`
+		Spendable           float32 `json:"spendable"`
+		MinConfirmations    int     `json:"min_confirmations"`
+		Received            float32 `json:"received"`
+		Sent                float32 `json:"sent"`
+		TransactionCount    int     `json:"transaction_count"`
+	}{
+		Message:             "Amount retrieved from blockchain",
+		Amount:              amount.Amount,
+		Confirmed:           amount.Confirmed,
+		UnconfirmedIncoming: amount.UnconfirmedIncoming,
+		UnconfirmedOutgoing: amount.UnconfirmedOutgoing,
+		Spendable:           amount.Spendable,
+		MinConfirmations:    amount.MinConfirmations,
+		Received:            summary.Received,
+		Sent:                summary.Sent,
+		TransactionCount:    summary.TransactionCount,
+	})
+	w.Write(m)
 }
 
 func (s *Server) Start() {
 	http.HandleFunc("/", s.Index)
-	http.HandleFunc("/wallet", s.Wallet)
+	http.HandleFunc("/wallet/address", s.AddressHandler)
 	http.HandleFunc("/wallet/amount", s.WalletAmountHandler)
+	http.HandleFunc("/wallet/transactions", s.WalletTransactionsHandler)
+	http.HandleFunc("/wallet/qr", s.QRCodeHandler)
 	http.HandleFunc("/transaction", s.PostTransactionHandler)
+	http.HandleFunc("/transaction/build", s.BuildTransactionHandler)
+	http.HandleFunc("/transaction/broadcast", s.BroadcastTransactionHandler)
+	http.HandleFunc("/keystore/keys", s.KeysHandler)
+	http.HandleFunc("/keystore/import", s.ImportKeyHandler)
+	http.HandleFunc("/keystore/export", s.ExportKeyHandler)
+	http.HandleFunc("/keystore/unlock", s.UnlockKeyHandler)
+	http.HandleFunc("/keystore/lock", s.LockKeyHandler)
+	http.HandleFunc("/keystore/passphrase", s.ChangePassphraseHandler)
 	http.ListenAndServe(fmt.Sprintf(":%d", s.port), nil)
 }
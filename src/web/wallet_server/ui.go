@@ -0,0 +1,209 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/Rha02/block-beard/src/address"
+	"github.com/Rha02/block-beard/src/blockchain"
+	"github.com/Rha02/block-beard/src/keys"
+	"github.com/Rha02/block-beard/src/qrcode"
+	"github.com/Rha02/block-beard/src/utils"
+	"github.com/Rha02/block-beard/src/wallet"
+)
+
+//go:embed templates
+var templates embed.FS
+
+const (
+	QR_CODE_DEFAULT_SCALE = 6
+	QR_CODE_MAX_SCALE     = 20
+)
+
+// parseIndexTemplate() parses the embedded wallet page.
+func parseIndexTemplate() *template.Template {
+	return template.Must(template.ParseFS(templates, "templates/index.html"))
+}
+
+// Index serves the wallet page.
+func (s *Server) Index(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != "/" {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// keys and HD wallets are generated and derived in the page, which needs the word list and
+	// derivation parameters of the wallet package
+	err := s.index.Execute(rw, struct {
+		Network          string
+		Schemes          []keys.Scheme
+		Fees             []wallet.FeeLevel
+		DefaultFee       string
+		MinConfirmations int
+		Words            []string
+		MnemonicBits     int
+		CoinType         uint32
+		GapLimit         int
+	}{
+		Network:          s.network.Name,
+		Schemes:          []keys.Scheme{keys.P256, keys.Secp256k1},
+		Fees:             wallet.FeeLevels,
+		DefaultFee:       "normal",
+		MinConfirmations: blockchain.DEFAULT_MIN_CONFIRMATIONS,
+		Words:            wallet.WordList(),
+		MnemonicBits:     wallet.DefaultMnemonicBits,
+		CoinType:         wallet.CoinType(s.network),
+		GapLimit:         wallet.DefaultGapLimit,
+	})
+	if err != nil {
+		println("Error rendering wallet page: " + err.Error())
+	}
+}
+
+// AddressHandler returns the address of a public key generated in the page, which keeps the
+// private key to itself.
+func (s *Server) AddressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	query := r.URL.Query()
+	scheme, err := keys.ParseScheme(query.Get("signature_scheme"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus(err.Error()))
+		return
+	}
+	publicKey, err := keys.PublicKeyFromString(scheme, query.Get("public_key"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid public key: " + err.Error()))
+		return
+	}
+
+	m, _ := json.Marshal(struct {
+		Address         string      `json:"address"`
+		PublicKey       string      `json:"public_key"`
+		SignatureScheme keys.Scheme `json:"signature_scheme"`
+	}{
+		Address:         address.FromPublicKey(publicKey, s.network),
+		PublicKey:       keys.ToString(publicKey),
+		SignatureScheme: scheme,
+	})
+	w.Write(m)
+}
+
+// QRCodeHandler returns a PNG QR code of a blockchain address.
+func (s *Server) QRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bcAddress := r.URL.Query().Get("blockchain_address")
+	if err := address.Validate(bcAddress, s.network); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid blockchain address: " + err.Error()))
+		return
+	}
+
+	scale := QR_CODE_DEFAULT_SCALE
+	if v := r.URL.Query().Get("scale"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > QR_CODE_MAX_SCALE {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid scale"))
+			return
+		}
+		scale = n
+	}
+
+	code, err := qrcode.Encode([]byte(bcAddress), qrcode.Medium)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	png, err := code.PNG(scale)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(png)
+}
+
+// WalletTransactionsHandler returns the pending transactions of an address and a page of its
+// confirmed ones, newest first.
+func (s *Server) WalletTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	query := r.URL.Query()
+	bcAddress := query.Get("blockchain_address")
+	if err := address.Validate(bcAddress, s.network); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(utils.JsonStatus("Invalid blockchain address: " + err.Error()))
+		return
+	}
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(utils.JsonStatus("Invalid limit"))
+			return
+		}
+		limit = n
+	}
+
+	history, err := s.node.AddressHistory(r.Context(), bcAddress, query.Get("cursor"), limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(utils.JsonStatus("Error getting transactions from blockchain"))
+		return
+	}
+	pool, err := s.node.Mempool(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(utils.JsonStatus("Error getting transactions from blockchain"))
+		return
+	}
+
+	pending := []*blockchain.TransactionView{}
+	for i := len(pool) - 1; i >= 0; i-- {
+		if pool[i].SenderAddress == bcAddress || pool[i].RecipientAddress == bcAddress {
+			pending = append(pending, pool[i])
+		}
+	}
+
+	m, _ := json.Marshal(struct {
+		Address      string                        `json:"address"`
+		Pending      []*blockchain.TransactionView `json:"pending"`
+		Transactions []*blockchain.TransactionView `json:"transactions"`
+		NextCursor   string                        `json:"next_cursor,omitempty"`
+	}{
+		Address:      bcAddress,
+		Pending:      pending,
+		Transactions: history.Transactions,
+		NextCursor:   history.NextCursor,
+	})
+	w.Write(m)
+}
@@ -0,0 +1,346 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes of the frames defined by RFC 6455.
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xa
+)
+
+// Status codes sent in close frames.
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	ClosePolicy        = 1008
+	CloseTooBig        = 1009
+	CloseTryAgainLater = 1013
+)
+
+// DefaultMaxMessageSize bounds the size of a message read from a client.
+const DefaultMaxMessageSize = 64 << 10
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrNotWebSocket = errors.New("websocket: not a websocket handshake")
+	ErrBadOrigin    = errors.New("websocket: request from another origin")
+	ErrProtocol     = errors.New("websocket: protocol error")
+	ErrTooBig       = errors.New("websocket: message too big")
+	ErrClosed       = errors.New("websocket: connection closed")
+)
+
+// CloseError is returned by ReadMessage() when the peer closes the connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: closed by peer: " + e.Reason
+}
+
+// Conn is a server side WebSocket connection (RFC 6455).
+// ReadMessage() must be called from a single goroutine; writes may come from any goroutine.
+type Conn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	writeMux sync.Mutex
+	closed   bool
+	// MaxMessageSize bounds the size of a frame, and of a message reassembled from fragments.
+	MaxMessageSize int64
+	// ReadTimeout bounds the wait for each frame from the client, unless it is zero. A client
+	// answering the pings of the server with pongs is never silent for longer than it is pinged.
+	ReadTimeout time.Duration
+}
+
+// acceptKey() computes the Sec-WebSocket-Accept value for a client key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains() reports whether a comma separated header contains the token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin() reports whether the request comes from a page served by the host it is sent to.
+// Browsers always send the origin of the page, so a request without one is not from a browser,
+// and no page of another site can use the cookies or the network position of its user.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade() completes the opening handshake of a WebSocket request and takes over its connection.
+// Requests from pages of another origin are refused. On failure it has already answered the
+// request with an error status.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin WebSocket request", http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, ErrNotWebSocket
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, reader: rw.Reader, MaxMessageSize: DefaultMaxMessageSize}, nil
+}
+
+// readFrame() reads a single frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.ReadTimeout > 0 {
+		if err = c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+			return
+		}
+	}
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	if header[0]&0x70 != 0 {
+		// no extension was negotiated, so the reserved bits must be clear
+		err = ErrProtocol
+		return
+	}
+	masked := header[1]&0x80 != 0
+	if !masked {
+		// RFC 6455 5.1: a server must close the connection on an unmasked client frame
+		err = ErrProtocol
+		return
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= OpClose && (length > 125 || !fin) {
+		// control frames are short and never fragmented
+		err = ErrProtocol
+		return
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		err = ErrTooBig
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// ReadMessage() returns the next text or binary message, reassembling fragments and
+// answering pings. It returns a *CloseError once the peer closes the connection.
+func (c *Conn) ReadMessage() (opcode byte, message []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			switch err {
+			case ErrProtocol:
+				c.WriteClose(CloseProtocolError, "protocol error")
+			case ErrTooBig:
+				c.WriteClose(CloseTooBig, "message too big")
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			code := CloseNormal
+			reason := ""
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+				reason = string(payload[2:])
+			}
+			c.WriteClose(code, "")
+			return 0, nil, &CloseError{code, reason}
+		case OpText, OpBinary:
+			if message != nil {
+				c.WriteClose(CloseProtocolError, "expected continuation frame")
+				return 0, nil, ErrProtocol
+			}
+			opcode = op
+			message = payload
+		case OpContinuation:
+			if message == nil {
+				c.WriteClose(CloseProtocolError, "unexpected continuation frame")
+				return 0, nil, ErrProtocol
+			}
+			if int64(len(message)+len(payload)) > c.MaxMessageSize {
+				c.WriteClose(CloseTooBig, "message too big")
+				return 0, nil, ErrTooBig
+			}
+			message = append(message, payload...)
+		default:
+			c.WriteClose(CloseProtocolError, "unknown opcode")
+			return 0, nil, ErrProtocol
+		}
+
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// writeFrame() writes a single unmasked, unfragmented frame.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, byte(n>>8), byte(n))
+	default:
+		header[1] = 127
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		header = append(header, ext[:]...)
+	}
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	if opcode == OpClose {
+		c.closed = true
+	}
+	return nil
+}
+
+// WriteMessage() sends a text or binary message.
+func (c *Conn) WriteMessage(opcode byte, message []byte) error {
+	return c.writeFrame(opcode, message)
+}
+
+// WriteText() sends a text message.
+func (c *Conn) WriteText(message []byte) error {
+	return c.writeFrame(OpText, message)
+}
+
+// Ping() sends a ping the client answers with a pong.
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// WriteClose() starts the closing handshake; nothing can be written afterwards.
+func (c *Conn) WriteClose(code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return c.writeFrame(OpClose, append(payload, reason...))
+}
+
+// SetReadDeadline() sets the deadline of the next reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline() sets the deadline of the next writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close() closes the underlying connection without a closing handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,329 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey and its accept value are the example of RFC 6455 section 1.3.
+const (
+	testKey    = "dGhlIHNhbXBsZSBub25jZQ=="
+	testAccept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+// newEchoServer() returns a server echoing the messages of every connection, configured by setup,
+// and a channel receiving the error that ends each connection.
+func newEchoServer(t *testing.T, setup func(c *Conn)) (*httptest.Server, <-chan error) {
+	t.Helper()
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if setup != nil {
+			setup(c)
+		}
+		for {
+			opcode, message, err := c.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := c.WriteMessage(opcode, message); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, errs
+}
+
+// testClient is the client side of a connection, which masks its frames.
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// handshake() sends an opening handshake with the headers to the server and returns its response.
+func handshake(t *testing.T, srv *httptest.Server, method string, header map[string]string) (*testClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := method + " / HTTP/1.1\r\nHost: " + srv.Listener.Addr().String() + "\r\n"
+	for name, value := range header {
+		if value != "" {
+			req += name + ": " + value + "\r\n"
+		}
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testClient{t, conn, reader}, res
+}
+
+func handshakeHeader() map[string]string {
+	return map[string]string{
+		"Connection":            "keep-alive, Upgrade",
+		"Upgrade":               "websocket",
+		"Sec-WebSocket-Version": "13",
+		"Sec-WebSocket-Key":     testKey,
+	}
+}
+
+// dial() opens a connection to the server.
+func dial(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	c, res := handshake(t, srv, http.MethodGet, handshakeHeader())
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want %d", res.StatusCode, http.StatusSwitchingProtocols)
+	}
+	return c
+}
+
+// write() sends a frame, masked unless masked is false.
+func (c *testClient) write(fin bool, opcode byte, payload []byte, masked bool) {
+	c.t.Helper()
+	header := []byte{opcode, byte(len(payload))}
+	if fin {
+		header[0] |= 0x80
+	}
+	if len(payload) > 125 {
+		header[1] = 126
+		header = append(header, byte(len(payload)>>8), byte(len(payload)))
+	}
+	frame := append([]byte{}, payload...)
+	if masked {
+		header[1] |= 0x80
+		mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+		header = append(header, mask...)
+		for i := range frame {
+			frame[i] ^= mask[i%4]
+		}
+	}
+	if _, err := c.conn.Write(append(header, frame...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read() returns the next frame from the server, which must be final and unmasked.
+func (c *testClient) read() (byte, []byte) {
+	c.t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		c.t.Fatalf("frame header %x is not final and unmasked", header)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			c.t.Fatal(err)
+		}
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// expectClose() reads a close frame with the status code.
+func (c *testClient) expectClose(code int) {
+	c.t.Helper()
+	opcode, payload := c.read()
+	if opcode != OpClose || len(payload) < 2 {
+		c.t.Fatalf("frame %x %q, want a close frame", opcode, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		c.t.Errorf("close code = %d (%s), want %d", got, payload[2:], code)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	if got := acceptKey(testKey); got != testAccept {
+		t.Errorf("acceptKey(%s) = %s, want %s", testKey, got, testAccept)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv, _ := newEchoServer(t, nil)
+	with := func(name, value string) map[string]string {
+		h := handshakeHeader()
+		h[name] = value
+		return h
+	}
+	for _, tt := range []struct {
+		name   string
+		method string
+		header map[string]string
+		want   int
+	}{
+		{"a handshake", http.MethodGet, handshakeHeader(), http.StatusSwitchingProtocols},
+		{"a handshake from a page of the host", http.MethodGet, with("Origin", "http://"+srv.Listener.Addr().String()), http.StatusSwitchingProtocols},
+		{"a handshake from a page of another site", http.MethodGet, with("Origin", "https://example.com"), http.StatusForbidden},
+		{"a handshake from a page of another port", http.MethodGet, with("Origin", "http://127.0.0.1:1"), http.StatusForbidden},
+		{"a handshake from an opaque origin", http.MethodGet, with("Origin", "null"), http.StatusForbidden},
+		{"a post", http.MethodPost, handshakeHeader(), http.StatusBadRequest},
+		{"a request without a key", http.MethodGet, with("Sec-WebSocket-Key", ""), http.StatusBadRequest},
+		{"a request of another version", http.MethodGet, with("Sec-WebSocket-Version", "8"), http.StatusBadRequest},
+		{"a request without an upgrade", http.MethodGet, with("Upgrade", ""), http.StatusBadRequest},
+		{"a request keeping the connection", http.MethodGet, with("Connection", "keep-alive"), http.StatusBadRequest},
+	} {
+		c, res := handshake(t, srv, tt.method, tt.header)
+		if res.StatusCode != tt.want {
+			t.Errorf("status of %s = %d, want %d", tt.name, res.StatusCode, tt.want)
+			continue
+		}
+		if tt.want != http.StatusSwitchingProtocols {
+			continue
+		}
+		if got := res.Header.Get("Sec-WebSocket-Accept"); got != testAccept {
+			t.Errorf("Sec-WebSocket-Accept of %s = %s, want %s", tt.name, got, testAccept)
+		}
+		c.write(true, OpText, []byte("hello"), true)
+		if _, message := c.read(); string(message) != "hello" {
+			t.Errorf("echo after %s = %q", tt.name, message)
+		}
+	}
+}
+
+func TestMasking(t *testing.T) {
+	srv, errs := newEchoServer(t, nil)
+	c := dial(t, srv)
+	long := strings.Repeat("a masked message spanning an extended length ", 8)
+	for _, message := range []string{"", "hello", long} {
+		c.write(true, OpBinary, []byte(message), true)
+		if opcode, echo := c.read(); opcode != OpBinary || string(echo) != message {
+			t.Errorf("echo of %q = %x %q", message, opcode, echo)
+		}
+	}
+
+	c.write(true, OpText, []byte("hello"), false)
+	c.expectClose(CloseProtocolError)
+	if err := <-errs; !errors.Is(err, ErrProtocol) {
+		t.Errorf("ReadMessage() of an unmasked frame = %v, want %v", err, ErrProtocol)
+	}
+}
+
+func TestFragmentation(t *testing.T) {
+	srv, errs := newEchoServer(t, nil)
+	c := dial(t, srv)
+	c.write(false, OpText, []byte("hel"), true)
+	c.write(true, OpPing, []byte("ping"), true)
+	if opcode, payload := c.read(); opcode != OpPong || string(payload) != "ping" {
+		t.Errorf("answer to a ping between fragments = %x %q, want a pong", opcode, payload)
+	}
+	c.write(false, OpContinuation, []byte("lo"), true)
+	c.write(true, OpContinuation, []byte(" world"), true)
+	if opcode, message := c.read(); opcode != OpText || string(message) != "hello world" {
+		t.Errorf("reassembled message = %x %q", opcode, message)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		frames func(c *testClient)
+	}{
+		{"a continuation without a first fragment", func(c *testClient) {
+			c.write(true, OpContinuation, []byte("lo"), true)
+		}},
+		{"a message interrupting a fragmented one", func(c *testClient) {
+			c.write(false, OpText, []byte("hel"), true)
+			c.write(true, OpText, []byte("lo"), true)
+		}},
+		{"a fragmented ping", func(c *testClient) {
+			c.write(false, OpPing, []byte("pi"), true)
+		}},
+		{"a reserved opcode", func(c *testClient) {
+			c.write(true, 0x3, []byte("hello"), true)
+		}},
+	} {
+		c := dial(t, srv)
+		tt.frames(c)
+		c.expectClose(CloseProtocolError)
+		if err := <-errs; !errors.Is(err, ErrProtocol) {
+			t.Errorf("ReadMessage() of %s = %v, want %v", tt.name, err, ErrProtocol)
+		}
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	srv, errs := newEchoServer(t, func(c *Conn) { c.MaxMessageSize = 8 })
+	c := dial(t, srv)
+	c.write(true, OpText, []byte("12345678"), true)
+	if _, message := c.read(); string(message) != "12345678" {
+		t.Errorf("echo of a message of the largest size = %q", message)
+	}
+
+	for name, frames := range map[string]func(c *testClient){
+		"a frame": func(c *testClient) {
+			c.write(true, OpText, []byte("123456789"), true)
+		},
+		"fragments": func(c *testClient) {
+			c.write(false, OpText, []byte("12345"), true)
+			c.write(true, OpContinuation, []byte("6789"), true)
+		},
+	} {
+		c := dial(t, srv)
+		frames(c)
+		c.expectClose(CloseTooBig)
+		if err := <-errs; !errors.Is(err, ErrTooBig) {
+			t.Errorf("ReadMessage() of %s over the limit = %v, want %v", name, err, ErrTooBig)
+		}
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	srv, errs := newEchoServer(t, func(c *Conn) { c.ReadTimeout = timeout })
+
+	// a client answering within the timeout stays connected
+	c := dial(t, srv)
+	for i := 0; i < 4; i++ {
+		time.Sleep(timeout / 2)
+		c.write(true, OpPong, nil, true)
+	}
+	c.write(true, OpText, []byte("still here"), true)
+	if _, message := c.read(); string(message) != "still here" {
+		t.Errorf("echo after answering within the timeout = %q", message)
+	}
+
+	// a silent client is dropped
+	start := time.Now()
+	select {
+	case err := <-errs:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("ReadMessage() of a silent client = %v, want a timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > 4*timeout {
+			t.Errorf("silent client dropped after %v, want about %v", elapsed, timeout)
+		}
+	case <-time.After(10 * timeout):
+		t.Fatal("silent client not dropped")
+	}
+}